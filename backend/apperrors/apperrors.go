@@ -3,7 +3,9 @@ package apperrors
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // craft our own enum types since go doesnt have those
@@ -18,12 +20,15 @@ const (
 	Conflict      Type = "CONFLICT"      // Already exists (eg, create account with existent username) - 409
 	Internal      Type = "INTERNAL"      // Server (500) and fallback errors
 	NotFound      Type = "NOTFOUND"      // For not finding resource
+	TooMany       Type = "TOOMANY"       // Rate limited or locked out - 429
 )
 
 // our custom error type
 type Error struct {
 	Type    Type   `json:"type"`
 	Message string `json:"message"`
+	// for TooMany errors, how long until the client may try again
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *Error) Error() string {
@@ -45,6 +50,8 @@ func (e *Error) Status() int {
 		return http.StatusNotFound
 	case Forbidden:
 		return http.StatusForbidden
+	case TooMany:
+		return http.StatusTooManyRequests
 	default:
 		return http.StatusInternalServerError
 	}
@@ -59,6 +66,23 @@ func Status(err error) int {
 	return http.StatusInternalServerError
 }
 
+/**
+ * Try cast error as Error and return the value for its Retry-After
+ * header (whole seconds), or "" if it doesn't have one
+ */
+func RetryAfterHeader(err error) string {
+	var e *Error
+	if !errors.As(err, &e) || e.RetryAfter <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d", retrySeconds(e.RetryAfter))
+}
+
+// `d` rounded up to whole seconds, so clients don't retry too early
+func retrySeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
 /*
  * Error factories
  */
@@ -95,6 +119,14 @@ func NewConflict(name string, value string) *Error {
 	}
 }
 
+// NewVersionConflict to create a 409 error for a write based on an outdated version of a resource
+func NewVersionConflict(name string, value string) *Error {
+	return &Error{
+		Type:    Conflict,
+		Message: fmt.Sprintf("resource: %v with value: %v was modified by another request", name, value),
+	}
+}
+
 // NewInternal for 500 errors and unknown errors
 func NewInternal() *Error {
 	return &Error{
@@ -110,3 +142,12 @@ func NewNotFound(name string, value string) *Error {
 		Message: fmt.Sprintf("resource: %v with value: %v not found", name, value),
 	}
 }
+
+// NewTooManyRequests to create a 429 error, for when the client can retry after `retryAfter`
+func NewTooManyRequests(retryAfter time.Duration) *Error {
+	return &Error{
+		Type:       TooMany,
+		Message:    fmt.Sprintf("Too many requests, please try again in %d seconds", retrySeconds(retryAfter)),
+		RetryAfter: retryAfter,
+	}
+}
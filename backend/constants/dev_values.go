@@ -3,7 +3,11 @@ package constants
 import (
 	"github.com/joho/godotenv"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func LoadEnv() error {
@@ -30,6 +34,31 @@ func getEnvWithDefault(key string, defaultVal string) string {
 	return envVal
 }
 
+func getIntEnvWithDefault(key string, defaultVal int) int {
+	envVal := os.Getenv(key)
+	if envVal == "" {
+		return defaultVal
+	}
+	val, err := strconv.Atoi(envVal)
+	if err != nil {
+		log.Fatalf("Env var %s must be an integer, got %q\n", key, envVal)
+	}
+	return val
+}
+
+// durations are in time.ParseDuration format, e.g. "30s" or "5m"
+func getDurationEnvWithDefault(key string, defaultVal time.Duration) time.Duration {
+	envVal := os.Getenv(key)
+	if envVal == "" {
+		return defaultVal
+	}
+	val, err := time.ParseDuration(envVal)
+	if err != nil {
+		log.Fatalf("Env var %s must be a duration (e.g. 30s), got %q\n", key, envVal)
+	}
+	return val
+}
+
 func GetDevMode() bool {
 	return getEnvWithDefault("DEV_MODE", "false") == "true"
 }
@@ -46,6 +75,48 @@ func GetOtelServiceName() string {
 	return getEnvWithDefault("OTEL_SERVICE_NAME", "net-go-server")
 }
 
+// minimum log level on startup (debug, info, warn or error); can be changed at runtime
+func GetLogLevel() string {
+	if GetDevMode() {
+		return getEnvWithDefault("LOG_LEVEL", "debug")
+	}
+	return getEnvWithDefault("LOG_LEVEL", "info")
+}
+
+// repeated log messages are logged this many times per second before sampling kicks in; 0 disables sampling
+func GetLogSamplingInitial() int {
+	return getIntEnvWithDefault("LOG_SAMPLING_INITIAL", 100)
+}
+
+// once sampling kicks in, every nth repeat of a message is logged
+func GetLogSamplingThereafter() int {
+	return getIntEnvWithDefault("LOG_SAMPLING_THEREAFTER", 100)
+}
+
+// address (e.g. 127.0.0.1:9090) of the internal listener for changing log config at runtime; off when empty
+func GetLogAdminAddr() string {
+	return getEnvWithDefault("LOG_ADMIN_ADDR", "")
+}
+
+// address (e.g. 127.0.0.1:9091) of the internal listener serving prometheus /metrics; off when empty
+func GetMetricsAddr() string {
+	return getEnvWithDefault("METRICS_ADDR", "")
+}
+
+// one of "mysql", "postgres" or "sqlite"
+func GetDatabaseDriver() string {
+	return getEnvWithDefault("DB_DRIVER", "mysql")
+}
+
+func GetDatabasePort() string {
+	return os.Getenv("DB_PORT")
+}
+
+// file path of the sqlite database; unused by other drivers
+func GetDatabasePath() string {
+	return getEnvWithDefault("DB_PATH", "net-go.db")
+}
+
 func GetDatabaseUserUsername() string {
 	return getEnvOrPanic("DB_USER")
 }
@@ -54,10 +125,206 @@ func GetDatabaseUserPassword() string {
 	return getEnvOrPanic("DB_PASS")
 }
 
+// falls back to MARIADB_DATABASE, which the mariadb container also reads
 func GetDatabaseName() string {
+	if name := os.Getenv("DB_NAME"); name != "" {
+		return name
+	}
 	return getEnvOrPanic("MARIADB_DATABASE")
 }
 
 func GetDatabaseHost() string {
 	return getEnvOrPanic("DB_HOST")
 }
+
+// postgres sslmode (disable, require, verify-ca or verify-full); only dev mode defaults to plaintext
+func GetDatabaseSSLMode() string {
+	if GetDevMode() {
+		return getEnvWithDefault("DB_SSLMODE", "disable")
+	}
+	return getEnvWithDefault("DB_SSLMODE", "require")
+}
+
+// max connections in the db pool (in use + idle); 0 means unlimited
+func GetDatabaseMaxOpenConns() int {
+	return getIntEnvWithDefault("DB_MAX_OPEN_CONNS", 20)
+}
+
+// max idle connections kept open in the db pool
+func GetDatabaseMaxIdleConns() int {
+	return getIntEnvWithDefault("DB_MAX_IDLE_CONNS", 5)
+}
+
+// connections are recycled after this long; keep it below the server's wait_timeout
+func GetDatabaseConnMaxLifetime() time.Duration {
+	return getDurationEnvWithDefault("DB_CONN_MAX_LIFETIME", 30*time.Minute)
+}
+
+// idle connections are closed after this long
+func GetDatabaseConnMaxIdleTime() time.Duration {
+	return getDurationEnvWithDefault("DB_CONN_MAX_IDLE_TIME", 5*time.Minute)
+}
+
+// how many times to try connecting to the db on startup before giving up
+func GetDatabaseConnectAttempts() int {
+	return getIntEnvWithDefault("DB_CONNECT_ATTEMPTS", 8)
+}
+
+/**
+ * how long the server keeps serving after SIGTERM while reporting not
+ * ready, so load balancers stop sending it traffic before it shuts down
+ */
+func GetShutdownDrainDelay() time.Duration {
+	return getDurationEnvWithDefault("SHUTDOWN_DRAIN_DELAY", 5*time.Second)
+}
+
+// signup/signin requests allowed per minute from one IP
+func GetAuthRateLimitPerIP() int {
+	return getIntEnvWithDefault("AUTH_RATE_LIMIT_PER_IP", 30)
+}
+
+// signup/signin requests allowed per minute for one username
+func GetAuthRateLimitPerUsername() int {
+	return getIntEnvWithDefault("AUTH_RATE_LIMIT_PER_USERNAME", 10)
+}
+
+// failed signins in a row before a username is locked out
+func GetSigninLockoutThreshold() int {
+	return getIntEnvWithDefault("SIGNIN_LOCKOUT_THRESHOLD", 5)
+}
+
+// first lockout; each further failure doubles it, up to SIGNIN_LOCKOUT_MAX
+func GetSigninLockoutBase() time.Duration {
+	return getDurationEnvWithDefault("SIGNIN_LOCKOUT_BASE", 30*time.Second)
+}
+
+func GetSigninLockoutMax() time.Duration {
+	return getDurationEnvWithDefault("SIGNIN_LOCKOUT_MAX", time.Hour)
+}
+
+/**
+ * comma separated IPs/CIDRs of the proxies trusted to set X-Forwarded-For,
+ * which the per IP rate limit goes by; when unset, no proxy is trusted
+ * and the client IP is the address of the connection
+ */
+func GetTrustedProxies() []string {
+	proxies := getEnvWithDefault("TRUSTED_PROXIES", "")
+	if proxies == "" {
+		return nil
+	}
+	var trusted []string
+	for _, proxy := range strings.Split(proxies, ",") {
+		trusted = append(trusted, strings.TrimSpace(proxy))
+	}
+	return trusted
+}
+
+/**
+ * comma separated secrets (32+ chars each) signing the auth cookie. The
+ * first signs new cookies and all of them verify, so a key is rotated by
+ * adding the new one in front and dropping the old one once its cookies
+ * have been re-signed. Required outside of dev mode.
+ */
+func GetAuthCookieKeys() []string {
+	keys := getEnvWithDefault("AUTH_COOKIE_KEYS", "")
+	if keys == "" {
+		return nil
+	}
+	var parsed []string
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			parsed = append(parsed, key)
+		}
+	}
+	return parsed
+}
+
+// whether to still accept auth cookies from before they were signed (re-signing them)
+func GetAuthCookieAllowUnsigned() bool {
+	return getEnvWithDefault("AUTH_COOKIE_ALLOW_UNSIGNED", "true") == "true"
+}
+
+// https only cookies; off by default in dev mode, which serves plain http
+func GetAuthCookieSecure() bool {
+	defaultVal := "true"
+	if GetDevMode() {
+		defaultVal = "false"
+	}
+	return getEnvWithDefault("AUTH_COOKIE_SECURE", defaultVal) == "true"
+}
+
+// SameSite attribute of the auth cookies: lax, strict or none (which requires secure)
+func GetAuthCookieSameSite() http.SameSite {
+	envVal := getEnvWithDefault("AUTH_COOKIE_SAMESITE", "lax")
+	switch strings.ToLower(envVal) {
+	case "lax":
+		return http.SameSiteLaxMode
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	log.Fatalf("Env var AUTH_COOKIE_SAMESITE must be lax, strict or none, got %q\n", envVal)
+	return http.SameSiteDefaultMode
+}
+
+// base url the app is served at, for links in emails
+func GetPublicURL() string {
+	if GetDevMode() {
+		return getEnvWithDefault("PUBLIC_URL", "http://localhost:"+GetPort())
+	}
+	return getEnvWithDefault("PUBLIC_URL", "https://"+GetDomain())
+}
+
+// how emails are sent: "log" (just logged, without the body), "file" (written
+// to MAILER_DIR) or "smtp" (relayed through SMTP_ADDR); only smtp is allowed
+// outside of dev and demo mode
+func GetMailer() string {
+	return getEnvWithDefault("MAILER", "log")
+}
+
+func GetMailerDir() string {
+	return getEnvWithDefault("MAILER_DIR", "mail")
+}
+
+// host:port of the SMTP server
+func GetSMTPAddr() string {
+	return os.Getenv("SMTP_ADDR")
+}
+
+// optional; leave unset for servers that don't need auth
+func GetSMTPUsername() string {
+	return os.Getenv("SMTP_USERNAME")
+}
+
+func GetSMTPPassword() string {
+	return os.Getenv("SMTP_PASSWORD")
+}
+
+// sender of the app's emails
+func GetMailFrom() string {
+	return getEnvWithDefault("MAIL_FROM", "net-go <noreply@"+GetDomain()+">")
+}
+
+// issuer URL of the OpenID Connect provider users can sign in with; unset disables it
+func GetOIDCIssuer() string {
+	return os.Getenv("OIDC_ISSUER")
+}
+
+func GetOIDCClientID() string {
+	return os.Getenv("OIDC_CLIENT_ID")
+}
+
+func GetOIDCClientSecret() string {
+	return os.Getenv("OIDC_CLIENT_SECRET")
+}
+
+// our callback, as registered with the provider
+func GetOIDCRedirectURL() string {
+	return getEnvWithDefault("OIDC_REDIRECT_URL", GetPublicURL()+"/api/accounts/oidc/callback")
+}
+
+// space separated scopes to ask the provider for
+func GetOIDCScopes() []string {
+	return strings.Fields(getEnvWithDefault("OIDC_SCOPES", "openid profile email"))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/logger"
 )
@@ -29,7 +30,7 @@ type invalidArgument struct {
 func BindData(c *gin.Context, req any) bool {
 	// attempt bind json data to struct
 	if err := c.ShouldBindJSON(req); err != nil {
-		logger.Debug("Error binding data: %+v\n", err)
+		logger.DebugCtx(c, "Error binding data", zap.Error(err))
 
 		if errs, ok := err.(validator.ValidationErrors); ok {
 			var invalidArgs []invalidArgument
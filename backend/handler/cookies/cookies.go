@@ -1,6 +1,10 @@
 package cookies
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"net-go/server/backend/constants"
@@ -12,18 +16,65 @@ import (
 const AuthCookieKey = "ngo_auth"
 const ViewerDataCookieKey = "ngo_viewer_data"
 
+const authCookieSep = "::"
+
+// signs cookies when AUTH_COOKIE_KEYS isn't set, which is only allowed in dev mode (and tests)
+const devAuthCookieKey = "net-go-dev-only-auth-cookie-key!"
+
+var ErrBadAuthCookieSignature = errors.New("auth cookie signature is invalid")
+
+// the keys signing auth cookies, newest (the one to sign with) first
+func authCookieKeys() []string {
+	if keys := constants.GetAuthCookieKeys(); len(keys) > 0 {
+		return keys
+	}
+	return []string{devAuthCookieKey}
+}
+
+// base64 HMAC-SHA256 of `payload` under `key`
+func sign(payload string, key string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// `userId::sessionToken::signature`
 func createAuthCookie(userId uint, sessionToken string) string {
-	return strconv.FormatUint(uint64(userId), 10) + "::" + sessionToken
+	payload := strconv.FormatUint(uint64(userId), 10) + authCookieSep + sessionToken
+	return payload + authCookieSep + sign(payload, authCookieKeys()[0])
+}
+
+// whether `signature` signs `payload` under any of the current keys
+func verifyAuthCookie(payload string, signature string) bool {
+	for _, key := range authCookieKeys() {
+		if hmac.Equal([]byte(signature), []byte(sign(payload, key))) {
+			return true
+		}
+	}
+	return false
 }
 
+/**
+ * The user id and session token in a signed auth cookie. Cookies from
+ * before signing (just `userId::sessionToken`) are still accepted
+ * while AUTH_COOKIE_ALLOW_UNSIGNED is on; see IsSignedAuthCookie.
+ */
 func DeconstructAuthCookie(cookie string) (uint64, string, error) {
 	// set defaults for err returns
 	var id uint64 = 0
 	sessToken := ""
 
-	parts := strings.Split(cookie, "::")
-	if len(parts) != 2 {
-		return id, sessToken, fmt.Errorf("Auth cookie was not a valid. Expected 2 parts, got %d", len(parts))
+	parts := strings.Split(cookie, authCookieSep)
+	switch {
+	case len(parts) == 3:
+		payload := parts[0] + authCookieSep + parts[1]
+		if !verifyAuthCookie(payload, parts[2]) {
+			return id, sessToken, ErrBadAuthCookieSignature
+		}
+	case len(parts) == 2 && constants.GetAuthCookieAllowUnsigned():
+		// legacy unsigned cookie
+	default:
+		return id, sessToken, fmt.Errorf("Auth cookie was not a valid. Expected 3 parts, got %d", len(parts))
 	}
 
 	id, err := strconv.ParseUint(parts[0], 10, 64)
@@ -35,55 +86,49 @@ func DeconstructAuthCookie(cookie string) (uint64, string, error) {
 	return id, sessToken, nil
 }
 
-/**
- * Save an auth cookie to validate `user` to the gin context response.
- */
-func SetAuthCookiesInResponse(user model.User, c *gin.Context) {
-	oneMonthSeconds := 2592000
-	// actual auth cookie
+// false for cookies from before auth cookies were signed, which should be re-issued
+func IsSignedAuthCookie(cookie string) bool {
+	return strings.Count(cookie, authCookieSep) == 2
+}
+
+// set a cookie on the whole site, with the configured Secure and SameSite attributes
+func setCookie(c *gin.Context, name string, value string, maxAge int, httpOnly bool) {
+	c.SetSameSite(constants.GetAuthCookieSameSite())
 	c.SetCookie(
-		AuthCookieKey,
-		createAuthCookie(user.ID, user.SessionToken),
-		oneMonthSeconds,
+		name,
+		value,
+		maxAge,
 		"/",
 		constants.GetDomain(),
-		false,
-		true, // httpOnly
+		constants.GetAuthCookieSecure(),
+		httpOnly,
 	)
+}
+
+/**
+ * Save an auth cookie to validate `user` to the gin context response,
+ * with `sessionToken` identifying the session it signs them in to.
+ */
+func SetAuthCookiesInResponse(user model.User, sessionToken string, c *gin.Context) {
+	oneMonthSeconds := 2592000
+	// actual auth cookie
+	setCookie(c, AuthCookieKey, createAuthCookie(user.ID, sessionToken), oneMonthSeconds, true)
 	// viewer data cookie for client side to check whether or not to make auth test requests
-	c.SetCookie(
+	setCookie(
+		c,
 		ViewerDataCookieKey,
 		fmt.Sprintf("{\"id\":%d,\"username\":\"%s\"}", user.ID, user.Username),
 		oneMonthSeconds,
-		"/",
-		constants.GetDomain(),
 		false,
-		false, // httpOnly
 	)
 }
 
 func DeleteAuthCookiesInResponse(c *gin.Context) {
 	deleteNow := -1
 	// actual auth cookie
-	c.SetCookie(
-		AuthCookieKey,
-		"",
-		deleteNow,
-		"/",
-		constants.GetDomain(),
-		false,
-		true, // httpOnly
-	)
+	setCookie(c, AuthCookieKey, "", deleteNow, true)
 	// flag cookie for client side to check whether or not to make auth test requests
-	c.SetCookie(
-		ViewerDataCookieKey,
-		"",
-		deleteNow,
-		"/",
-		constants.GetDomain(),
-		false,
-		false, // httpOnly
-	)
+	setCookie(c, ViewerDataCookieKey, "", deleteNow, false)
 }
 
 /**
@@ -0,0 +1,171 @@
+package cookies
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/model"
+)
+
+const testKey = "0123456789abcdef0123456789abcdef"
+const oldTestKey = "fedcba9876543210fedcba9876543210"
+
+func TestAuthCookie(t *testing.T) {
+	t.Run("Signed cookies round trip", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_KEYS", testKey)
+
+		cookie := createAuthCookie(12, "token")
+		id, token, err := DeconstructAuthCookie(cookie)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(12), id)
+		assert.Equal(t, "token", token)
+		assert.True(t, IsSignedAuthCookie(cookie))
+	})
+
+	t.Run("Tampered cookies are rejected", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_KEYS", testKey)
+		cookie := createAuthCookie(12, "token")
+
+		_, _, err := DeconstructAuthCookie("13" + strings.TrimPrefix(cookie, "12"))
+
+		assert.ErrorIs(t, err, ErrBadAuthCookieSignature)
+	})
+
+	t.Run("Cookies signed by a rotated out key still verify until it's dropped", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_KEYS", oldTestKey)
+		cookie := createAuthCookie(12, "token")
+
+		t.Setenv("AUTH_COOKIE_KEYS", testKey+","+oldTestKey)
+		_, _, err := DeconstructAuthCookie(cookie)
+		assert.NoError(t, err)
+		// new cookies are signed with the new key
+		assert.NotEqual(t, cookie, createAuthCookie(12, "token"))
+
+		t.Setenv("AUTH_COOKIE_KEYS", testKey)
+		_, _, err = DeconstructAuthCookie(cookie)
+		assert.ErrorIs(t, err, ErrBadAuthCookieSignature)
+	})
+
+	t.Run("Unsigned cookies are accepted only while allowed", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_KEYS", testKey)
+
+		id, token, err := DeconstructAuthCookie("12::token")
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(12), id)
+		assert.Equal(t, "token", token)
+		assert.False(t, IsSignedAuthCookie("12::token"))
+
+		t.Setenv("AUTH_COOKIE_ALLOW_UNSIGNED", "false")
+		_, _, err = DeconstructAuthCookie("12::token")
+		assert.Error(t, err)
+	})
+}
+
+func TestSetAuthCookiesInResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Sets the configured cookie attributes", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_SECURE", "true")
+		t.Setenv("AUTH_COOKIE_SAMESITE", "strict")
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		user := model.User{Username: "tim"}
+		user.ID = 12
+		SetAuthCookiesInResponse(user, "token", c)
+
+		cookies := rr.Result().Cookies()
+		assert.Len(t, cookies, 2)
+		for _, cookie := range cookies {
+			assert.True(t, cookie.Secure, cookie.Name)
+			assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite, cookie.Name)
+		}
+		assert.Equal(t, AuthCookieKey, cookies[0].Name)
+		assert.True(t, cookies[0].HttpOnly)
+		// gin url escapes cookie values
+		value, err := url.QueryUnescape(cookies[0].Value)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(value, "12::token::"))
+	})
+
+	t.Run("Secure is off by default in dev mode", func(t *testing.T) {
+		t.Setenv("DEV_MODE", "true")
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+
+		DeleteAuthCookiesInResponse(c)
+
+		for _, cookie := range rr.Result().Cookies() {
+			assert.False(t, cookie.Secure, cookie.Name)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite, cookie.Name)
+		}
+	})
+}
+
+// context for a request carrying the cookies set in `rr`
+func contextWithCookies(rr *httptest.ResponseRecorder) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range rr.Result().Cookies() {
+		c.Request.AddCookie(cookie)
+	}
+	return c
+}
+
+func TestSignedCookie(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	type payload struct {
+		State string
+	}
+
+	t.Run("Values round trip", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_KEYS", testKey)
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		assert.NoError(t, SetSignedCookie(c, "signed", payload{State: "abc"}, time.Minute))
+
+		var got payload
+		assert.NoError(t, GetSignedCookie(contextWithCookies(rr), "signed", &got))
+		assert.Equal(t, "abc", got.State)
+		// and only under their own name
+		renamed, _ := gin.CreateTestContext(httptest.NewRecorder())
+		renamed.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		renamed.Request.AddCookie(&http.Cookie{Name: "other", Value: rr.Result().Cookies()[0].Value})
+		assert.ErrorIs(t, GetSignedCookie(renamed, "other", &got), ErrBadSignedCookie)
+	})
+
+	t.Run("Tampered and expired cookies are rejected", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_KEYS", testKey)
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		assert.NoError(t, SetSignedCookie(c, "signed", payload{State: "abc"}, time.Minute))
+		cookie := rr.Result().Cookies()[0]
+
+		tampered, _ := gin.CreateTestContext(httptest.NewRecorder())
+		tampered.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		tampered.Request.AddCookie(&http.Cookie{Name: "signed", Value: "x" + cookie.Value})
+		var got payload
+		assert.ErrorIs(t, GetSignedCookie(tampered, "signed", &got), ErrBadSignedCookie)
+
+		rr = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(rr)
+		assert.NoError(t, SetSignedCookie(c, "signed", payload{State: "abc"}, -time.Minute))
+		assert.ErrorIs(t, GetSignedCookie(contextWithCookies(rr), "signed", &got), ErrBadSignedCookie)
+	})
+
+	t.Run("They're never SameSite strict", func(t *testing.T) {
+		t.Setenv("AUTH_COOKIE_SAMESITE", "strict")
+		rr := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rr)
+		assert.NoError(t, SetSignedCookie(c, "signed", payload{}, time.Minute))
+
+		assert.Equal(t, http.SameSiteLaxMode, rr.Result().Cookies()[0].SameSite)
+	})
+}
@@ -0,0 +1,42 @@
+package cookies
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"github.com/gin-gonic/gin"
+)
+
+const CSRFCookieKey = "ngo_csrf"
+
+// header mutating requests echo the CSRF cookie's token back in
+const CSRFHeader = "X-CSRF-Token"
+
+/**
+ * The request's CSRF token, or a new one set in the response cookie if
+ * it has none yet. Clients send it back in the X-CSRF-Token header,
+ * which other sites can't do since they can't read it.
+ */
+func GetOrSetCSRFToken(c *gin.Context) (string, error) {
+	if token, err := c.Cookie(CSRFCookieKey); err == nil && token != "" {
+		return token, nil
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	// session cookie; a new token is simply issued once it's gone
+	setCookie(c, CSRFCookieKey, token, 0, true)
+	return token, nil
+}
+
+// whether `token` (from the header) matches the request's CSRF cookie
+func ValidCSRFToken(c *gin.Context, token string) bool {
+	cookieToken, err := c.Cookie(CSRFCookieKey)
+	if err != nil || cookieToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookieToken), []byte(token)) == 1
+}
@@ -0,0 +1,95 @@
+package cookies
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"net-go/server/backend/constants"
+)
+
+// the sign in with an identity provider in progress (see identity.AuthRequest)
+const OIDCStateCookieKey = "ngo_oidc_state"
+
+// who came back from an identity provider without an account yet, until they pick a username
+const OIDCSignupCookieKey = "ngo_oidc_signup"
+
+var ErrBadSignedCookie = errors.New("cookie is missing, expired or its signature is invalid")
+
+// what's stored in a signed cookie, before signing
+type signedCookie struct {
+	Value     json.RawMessage `json:"v"`
+	ExpiresAt int64           `json:"exp"`
+}
+
+/**
+ * Stores `value` JSON encoded in cookie `name`, signed by the auth cookie
+ * key so it comes back unchanged; it's readable by the client though, so
+ * never put secrets in it that the client shouldn't have. It expires
+ * after `ttl`, whatever the browser does.
+ */
+func SetSignedCookie(c *gin.Context, name string, value any, ttl time.Duration) error {
+	encodedValue, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(signedCookie{
+		Value:     encodedValue,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(encoded)
+	setSignedCookie(c, name, payload+"."+sign(name+"="+payload, authCookieKeys()[0]), int(ttl.Seconds()))
+	return nil
+}
+
+// decodes signed cookie `name` into `value`; ErrBadSignedCookie unless it's there, intact and unexpired
+func GetSignedCookie(c *gin.Context, name string, value any) error {
+	cookie, err := c.Cookie(name)
+	if err != nil {
+		return ErrBadSignedCookie
+	}
+	payload, signature, found := strings.Cut(cookie, ".")
+	if !found || !verifyAuthCookie(name+"="+payload, signature) {
+		return ErrBadSignedCookie
+	}
+	encoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrBadSignedCookie
+	}
+	var decoded signedCookie
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return ErrBadSignedCookie
+	}
+	if time.Now().Unix() >= decoded.ExpiresAt {
+		return ErrBadSignedCookie
+	}
+	if err := json.Unmarshal(decoded.Value, value); err != nil {
+		return ErrBadSignedCookie
+	}
+	return nil
+}
+
+func DeleteSignedCookie(c *gin.Context, name string) {
+	setSignedCookie(c, name, "", -1)
+}
+
+/**
+ * Like setCookie, but never SameSite=Strict: these cookies have to come
+ * along when an identity provider redirects back to us, which is a
+ * navigation from another site.
+ */
+func setSignedCookie(c *gin.Context, name string, value string, maxAge int) {
+	sameSite := constants.GetAuthCookieSameSite()
+	if sameSite == http.SameSiteStrictMode {
+		sameSite = http.SameSiteLaxMode
+	}
+	c.SetSameSite(sameSite)
+	c.SetCookie(name, value, maxAge, "/", constants.GetDomain(), constants.GetAuthCookieSecure(), true)
+}
@@ -1,6 +1,9 @@
 package provider
 
 import (
+	"net-go/server/backend/health"
+	"net-go/server/backend/identity"
+	"net-go/server/backend/ratelimit"
 	"net-go/server/backend/services"
 	"net-go/server/backend/subscriptions"
 
@@ -9,20 +12,21 @@ import (
 
 // servicer provider
 type Provider struct {
-	R             *gin.Engine
-	UserService   services.IUserService
-	GameService   services.IGameService
-	Subscriptions subscriptions.GameSubscriptions
-}
-
-func (p *Provider) MigrateAll() error {
-	var err error
-	if err = p.UserService.MigrateAll(); err != nil {
-		return err
-	}
-	if err = p.GameService.MigrateAll(); err != nil {
-		return err
-	}
-
-	return nil
+	R                *gin.Engine
+	UserService      services.IUserService
+	SessionService   services.ISessionService
+	TwoFactorService services.ITwoFactorService
+	IdentityService  services.IIdentityService
+	APITokenService  services.IAPITokenService
+	GameService      services.IGameService
+	Subscriptions    *subscriptions.Hub
+	Readiness        *health.Readiness
+	// external identity provider to sign in with; nil when none is configured
+	IdentityProvider identity.Provider
+	// throttle signup/signin by client IP and by username; nil disables either
+	AuthIPLimiter       ratelimit.Limiter
+	AuthUsernameLimiter ratelimit.Limiter
+	// IPs/CIDRs of the proxies whose X-Forwarded-For is believed; when
+	// empty, the client IP is always the address of the connection
+	TrustedProxies []string
 }
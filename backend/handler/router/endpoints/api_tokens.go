@@ -0,0 +1,135 @@
+package endpoints
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/binding"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type createAPITokenReq struct {
+	// to tell tokens apart, e.g. the bot using it
+	Name   string   `json:"name" binding:"required,gte=1,lte=100"`
+	Scopes []string `json:"scopes" binding:"required,gte=1"`
+}
+
+type apiTokenUri struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// an API token, as shown to its user; never include the token hash here!
+type apiTokenInfo struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+func newAPITokenInfo(token model.APIToken) apiTokenInfo {
+	return apiTokenInfo{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     token.ScopeList(),
+		CreatedAt:  token.CreatedAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+/**
+ * POST /accounts/tokens
+ * Responds with the new token, which is only ever shown this once.
+ */
+func (rhandler RouteHandler) CreateAPIToken(c *gin.Context) {
+	var req createAPITokenReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	token, stored, err := rhandler.Provider.APITokenService.Create(c, user.ID, req.Name, req.Scopes)
+	if err != nil {
+		logger.DebugCtx(c, "Failed to create API token", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":    token,
+		"apiToken": newAPITokenInfo(*stored),
+	})
+}
+
+// GET /accounts/tokens
+func (rhandler RouteHandler) ListAPITokens(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	tokens, err := rhandler.Provider.APITokenService.List(c, user.ID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	infos := make([]apiTokenInfo, 0, len(tokens))
+	for _, token := range tokens {
+		infos = append(infos, newAPITokenInfo(token))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tokens": infos,
+	})
+}
+
+// DELETE /accounts/tokens/:id
+func (rhandler RouteHandler) RevokeAPIToken(c *gin.Context) {
+	var uriParams apiTokenUri
+	if err := c.ShouldBindUri(&uriParams); err != nil {
+		logger.WarnCtx(c, "Failed to parse API token URI params", zap.Error(err))
+		badReqErr := apperrors.NewBadRequest("Invalid URI parameter for ID")
+		c.JSON(badReqErr.Status(), gin.H{
+			"error": badReqErr.Error(),
+		})
+		return
+	}
+
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// only ever deletes tokens of `user`, so others' ids are just a 404
+	if err := rhandler.Provider.APITokenService.Revoke(c, user.ID, uriParams.ID); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
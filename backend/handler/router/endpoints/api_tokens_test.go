@@ -0,0 +1,118 @@
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/model"
+	"net-go/server/backend/services/mocks"
+)
+
+func buildAPITokenRouter(mockAPITokenService *mocks.MockAPITokenService, ctxUser *model.User) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:               router,
+		APITokenService: mockAPITokenService,
+	}
+	rhandler := NewRouteHandler(p)
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+	authed.GET("/api/accounts/tokens", rhandler.ListAPITokens)
+	authed.POST("/api/accounts/tokens", rhandler.CreateAPIToken)
+	authed.DELETE("/api/accounts/tokens/:id", rhandler.RevokeAPIToken)
+	return router
+}
+
+func TestAPITokensIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := &model.User{Username: "tim"}
+	user.ID = 4
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("Creates a token, showing it once", func(t *testing.T) {
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("Create", mock.AnythingOfType("*gin.Context"), uint(4), "bot", []string{"games:read", "games:play"}).
+			Return("ngo_pat_abc", &model.APIToken{ID: 3, UserID: 4, Name: "bot", TokenHash: "hash", Scopes: "games:read games:play", CreatedAt: createdAt}, nil)
+
+		router := buildAPITokenRouter(mockAPITokenService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/tokens", gin.H{
+			"name":   "bot",
+			"scopes": []string{"games:read", "games:play"},
+		}))
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.JSONEq(t, `{
+			"token": "ngo_pat_abc",
+			"apiToken": {"id":3,"name":"bot","scopes":["games:read","games:play"],"createdAt":"2024-01-02T03:04:05Z","lastUsedAt":null}
+		}`, rr.Body.String())
+	})
+
+	t.Run("Bad scopes", func(t *testing.T) {
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("Create", mock.AnythingOfType("*gin.Context"), uint(4), "bot", []string{"admin"}).
+			Return("", nil, apperrors.NewBadRequest(`Unknown scope "admin"`))
+
+		router := buildAPITokenRouter(mockAPITokenService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/tokens", gin.H{
+			"name":   "bot",
+			"scopes": []string{"admin"},
+		}))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/tokens", gin.H{
+			"name":   "bot",
+			"scopes": []string{},
+		}))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Lists tokens without their hashes", func(t *testing.T) {
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("List", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return([]model.APIToken{{ID: 3, UserID: 4, Name: "bot", TokenHash: "hash", Scopes: "games:read", CreatedAt: createdAt, LastUsedAt: &createdAt}}, nil)
+
+		router := buildAPITokenRouter(mockAPITokenService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/accounts/tokens", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"tokens":[{"id":3,"name":"bot","scopes":["games:read"],"createdAt":"2024-01-02T03:04:05Z","lastUsedAt":"2024-01-02T03:04:05Z"}]}`, rr.Body.String())
+	})
+
+	t.Run("Revokes tokens", func(t *testing.T) {
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("Revoke", mock.AnythingOfType("*gin.Context"), uint(4), uint(3)).
+			Return(nil)
+		mockAPITokenService.
+			On("Revoke", mock.AnythingOfType("*gin.Context"), uint(4), uint(5)).
+			Return(apperrors.NewNotFound("API token", "5"))
+
+		router := buildAPITokenRouter(mockAPITokenService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/accounts/tokens/3", nil))
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/api/accounts/tokens/5", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
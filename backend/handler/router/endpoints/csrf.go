@@ -0,0 +1,34 @@
+package endpoints
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/cookies"
+	"net-go/server/backend/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+/**
+ * GET /csrf
+ * Token for the X-CSRF-Token header of mutating requests; see middleware.CSRF
+ */
+func (rhandler RouteHandler) CSRFToken(c *gin.Context) {
+	token, err := cookies.GetOrSetCSRFToken(c)
+	if err != nil {
+		logger.ErrorCtx(c, "Unable to generate CSRF token", zap.Error(err))
+		internalErr := apperrors.NewInternal()
+		c.JSON(internalErr.Status(), gin.H{
+			"error": internalErr.Error(),
+		})
+		return
+	}
+
+	// never cache; a cached token could outlive its cookie
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"csrfToken": token,
+		"header":    cookies.CSRFHeader,
+	})
+}
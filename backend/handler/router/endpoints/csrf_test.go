@@ -0,0 +1,59 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/handler/provider"
+)
+
+func buildCSRFRouter() *gin.Engine {
+	router := gin.Default()
+	rhandler := NewRouteHandler(provider.Provider{R: router})
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.GET("/api/csrf", rhandler.CSRFToken)
+	return router
+}
+
+func TestCSRFTokenIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Issues a token matching its cookie", func(t *testing.T) {
+		router := buildCSRFRouter()
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/api/csrf", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		var resp map[string]string
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.NotEmpty(t, resp["csrfToken"])
+		assert.Equal(t, "X-CSRF-Token", resp["header"])
+		cookies := rr.Result().Cookies()
+		assert.Len(t, cookies, 1)
+		assert.Equal(t, "ngo_csrf", cookies[0].Name)
+		assert.Equal(t, resp["csrfToken"], cookies[0].Value)
+	})
+
+	t.Run("Reuses the existing token", func(t *testing.T) {
+		router := buildCSRFRouter()
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/api/csrf", nil)
+		assert.NoError(t, err)
+		request.AddCookie(&http.Cookie{Name: "ngo_csrf", Value: "existing"})
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"csrfToken":"existing"`)
+		assert.Empty(t, rr.Header().Get("Set-Cookie"))
+	})
+}
@@ -0,0 +1,122 @@
+package endpoints
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/binding"
+	"net-go/server/backend/logger"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type emailReq struct {
+	Email string `json:"email" binding:"required,email,lte=254"`
+}
+
+type verifyEmailReq struct {
+	Token string `json:"token" binding:"required,lte=64"`
+}
+
+// the signed in user's own email; only ever a verified one
+type accountEmail struct {
+	Email      *string    `json:"email"`
+	VerifiedAt *time.Time `json:"verifiedAt"`
+}
+
+// GET /accounts/email
+func (rhandler RouteHandler) GetEmail(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, accountEmail{
+		Email:      user.Email,
+		VerifiedAt: user.EmailVerifiedAt,
+	})
+}
+
+/**
+ * POST /accounts/email
+ * Mails a verification link to the address; it only replaces the current
+ * one once that link is used.
+ */
+func (rhandler RouteHandler) RequestEmailVerification(c *gin.Context) {
+	var req emailReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := rhandler.Provider.UserService.RequestEmailVerification(c, user.ID, req.Email); err != nil {
+		logger.DebugCtx(c, "Failed to request email verification", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "A verification link is on its way",
+	})
+}
+
+// DELETE /accounts/email
+func (rhandler RouteHandler) RemoveEmail(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := rhandler.Provider.UserService.RemoveEmail(c, user.ID); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+/**
+ * POST /accounts/email/verify
+ * Doesn't need the user to be signed in, so links work on any device.
+ */
+func (rhandler RouteHandler) VerifyEmail(c *gin.Context) {
+	var req verifyEmailReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	user, err := rhandler.Provider.UserService.VerifyEmail(c, req.Token)
+	if err != nil {
+		logger.DebugCtx(c, "Failed to verify email", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, accountEmail{
+		Email:      user.Email,
+		VerifiedAt: user.EmailVerifiedAt,
+	})
+}
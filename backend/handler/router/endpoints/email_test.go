@@ -0,0 +1,161 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/model"
+	"net-go/server/backend/services/mocks"
+)
+
+func buildEmailRouter(mockUserService *mocks.MockUserService, ctxUser *model.User) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:           router,
+		UserService: mockUserService,
+	}
+	rhandler := NewRouteHandler(p)
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.POST("/api/accounts/email/verify", rhandler.VerifyEmail)
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+	authed.POST("/api/accounts/email", rhandler.RequestEmailVerification)
+	authed.GET("/api/accounts/email", rhandler.GetEmail)
+	authed.DELETE("/api/accounts/email", rhandler.RemoveEmail)
+	return router
+}
+
+func TestEmailIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := &model.User{Username: "tim"}
+	user.ID = 4
+
+	t.Run("Gets the user's email", func(t *testing.T) {
+		email, verifiedAt := "tim@example.com", time.Now()
+		withEmail := &model.User{Username: "tim", Email: &email, EmailVerifiedAt: &verifiedAt}
+
+		router := buildEmailRouter(new(mocks.MockUserService), withEmail)
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/api/accounts/email", nil)
+		router.ServeHTTP(rr, request)
+
+		var got accountEmail
+		assert.Equal(t, 200, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+		assert.Equal(t, "tim@example.com", *got.Email)
+		assert.NotNil(t, got.VerifiedAt)
+	})
+
+	t.Run("No email is null", func(t *testing.T) {
+		router := buildEmailRouter(new(mocks.MockUserService), user)
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/api/accounts/email", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		assert.JSONEq(t, `{"email":null,"verifiedAt":null}`, rr.Body.String())
+	})
+
+	t.Run("Requesting verification is accepted", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("RequestEmailVerification", mock.AnythingOfType("*gin.Context"), uint(4), "tim@example.com").
+			Return(nil)
+
+		router := buildEmailRouter(mockUserService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/email", gin.H{
+			"email": "tim@example.com",
+		}))
+
+		assert.Equal(t, 202, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Email used by someone else", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("RequestEmailVerification", mock.AnythingOfType("*gin.Context"), uint(4), "tom@example.com").
+			Return(apperrors.NewConflict("Email", "tom@example.com"))
+
+		router := buildEmailRouter(mockUserService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/email", gin.H{
+			"email": "tom@example.com",
+		}))
+
+		assert.Equal(t, 409, rr.Code)
+	})
+
+	t.Run("Not an email", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := buildEmailRouter(mockUserService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/email", gin.H{
+			"email": "tim",
+		}))
+
+		assert.Equal(t, 400, rr.Code)
+		mockUserService.AssertNotCalled(t, "RequestEmailVerification")
+	})
+
+	t.Run("Removes the email", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("RemoveEmail", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(nil)
+
+		router := buildEmailRouter(mockUserService, user)
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodDelete, "/api/accounts/email", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 204, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Verifies with a token", func(t *testing.T) {
+		email, verifiedAt := "tim@example.com", time.Now()
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("VerifyEmail", mock.AnythingOfType("*gin.Context"), "token").
+			Return(&model.User{Username: "tim", Email: &email, EmailVerifiedAt: &verifiedAt}, nil)
+
+		router := buildEmailRouter(mockUserService, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/email/verify", gin.H{
+			"token": "token",
+		}))
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"email":"tim@example.com"`)
+	})
+
+	t.Run("Bad token", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("VerifyEmail", mock.AnythingOfType("*gin.Context"), "made-up").
+			Return(nil, apperrors.NewBadRequest("Email verification link is invalid or has expired"))
+
+		router := buildEmailRouter(mockUserService, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/email/verify", gin.H{
+			"token": "made-up",
+		}))
+
+		assert.Equal(t, 400, rr.Code)
+	})
+}
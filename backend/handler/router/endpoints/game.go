@@ -3,15 +3,16 @@ package endpoints
 import (
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/handler/binding"
+	"net-go/server/backend/instrumentation"
 	"net-go/server/backend/logger"
 	"net-go/server/backend/model"
 	"net-go/server/backend/model/types"
-	"net-go/server/backend/subscriptions"
 	"net/http"
-	"reflect"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 type gameUri struct {
@@ -26,17 +27,68 @@ type updateGameRequest struct {
 	Game ElmGame `json:"game" binding:"required"`
 }
 
+type listGameSummariesQuery struct {
+	Status        model.GameStatusFilter `form:"status" binding:"omitempty,oneof=active finished"`
+	Turn          model.GameTurnFilter   `form:"turn" binding:"omitempty,oneof=mine theirs"`
+	Opponent      string                 `form:"opponent" binding:"omitempty,lte=30"` // opponent username
+	BoardSize     types.BoardSize        `form:"boardSize" binding:"omitempty,oneof=9 12 19"`
+	CreatedAfter  time.Time              `form:"createdAfter"`  // RFC3339
+	CreatedBefore time.Time              `form:"createdBefore"` // RFC3339
+	Sort          model.GameSortOrder    `form:"sort" binding:"omitempty,oneof=created_desc created_asc updated_desc updated_asc"`
+	Cursor        string                 `form:"cursor"`
+	Limit         int                    `form:"limit,default=20" binding:"min=1,max=100"`
+}
+
+// lightweight version of ElmGame without the board or move history
+type gameSummaryResponse struct {
+	ID              string            `json:"id"`
+	BoardSize       types.BoardSize   `json:"boardSize"`
+	IsOver          bool              `json:"isOver"`
+	Score           types.Score       `json:"score"`
+	PlayerColor     types.ColorChoice `json:"playerColor"`
+	WhitePlayerName string            `json:"whitePlayerName"`
+	BlackPlayerName string            `json:"blackPlayerName"`
+	MoveCount       uint              `json:"moveCount"`
+	IsActiveTurn    bool              `json:"isActiveTurn"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+}
+
+func newGameSummaryResponse(g model.GameSummary, authedUser model.User) gameSummaryResponse {
+	resp := gameSummaryResponse{
+		ID:              strconv.FormatUint(uint64(g.ID), 10),
+		BoardSize:       g.BoardSize,
+		IsOver:          g.IsOver,
+		Score:           g.Score,
+		PlayerColor:     types.Black,
+		WhitePlayerName: g.WhitePlayer.Username,
+		BlackPlayerName: g.BlackPlayer.Username,
+		MoveCount:       g.MoveCount,
+		IsActiveTurn:    g.ActivePlayerId == authedUser.ID,
+		CreatedAt:       g.CreatedAt,
+		UpdatedAt:       g.UpdatedAt,
+	}
+	if g.WhitePlayerId == authedUser.ID {
+		resp.PlayerColor = types.White
+	}
+	return resp
+}
+
 func parseGameIdUriParam(c *gin.Context) (*gameUri, error) {
 	// bind uri params
 	var uriParams gameUri
 	if err := c.ShouldBindUri(&uriParams); err != nil {
-		logger.Warn("Failed to parse game URI params: %v", err)
+		logger.WarnCtx(c, "Failed to parse game URI params", zap.Error(err))
 		badReqErr := apperrors.NewBadRequest("Invalid URI parameter for game ID")
 		c.JSON(badReqErr.Status(), gin.H{
 			"error": badReqErr.Error(),
 		})
 		return nil, err
 	}
+	// tag the request's logs with the game
+	c.Request = c.Request.WithContext(
+		logger.WithFields(c.Request.Context(), zap.Uint("game_id", uriParams.ID)),
+	)
 	return &uriParams, nil
 }
 
@@ -60,6 +112,8 @@ type ElmGame struct {
 	WhitePlayerName string            `json:"whitePlayerName" binding:"required"`
 	BlackPlayerName string            `json:"blackPlayerName" binding:"required"`
 	ID              string            `json:"id,omitempty"`
+	// version of the game this state was based on; required for updates
+	Version uint `json:"version"`
 }
 
 /**
@@ -115,6 +169,7 @@ func (r *ElmGame) fromGame(g model.Game, authedUser model.User) {
 	r.IsOver = g.IsOver
 	r.Score = g.Score
 	r.ID = strconv.FormatUint(uint64(g.ID), 10)
+	r.Version = g.Version
 	r.BlackPlayerName = g.BlackPlayer.Username
 	r.WhitePlayerName = g.WhitePlayer.Username
 	if g.WhitePlayerId == authedUser.ID {
@@ -133,7 +188,7 @@ func (rhandler RouteHandler) GetGame(c *gin.Context) {
 	// make sure we got authed user
 	user, err := getUserFromCtx(c)
 	if err != nil {
-		logger.Debug("Expected to have authed user from middleware, but found none")
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -148,7 +203,7 @@ func (rhandler RouteHandler) GetGame(c *gin.Context) {
 
 	game, err := rhandler.Provider.GameService.Get(c, uriParams.ID)
 	if err != nil {
-		logger.Debug("Error fetching game with id %d: %v", uriParams.ID, err)
+		logger.DebugCtx(c, "Error fetching game", zap.Error(err))
 		notFoundErr := apperrors.NewNotFound("Game", strconv.FormatUint(uint64(uriParams.ID), 10))
 		c.JSON(notFoundErr.Status(), gin.H{
 			"error": notFoundErr.Error(),
@@ -169,7 +224,7 @@ func (rhandler RouteHandler) GetGameLongPoll(c *gin.Context) {
 	// make sure we got authed user
 	user, err := getUserFromCtx(c)
 	if err != nil {
-		logger.Debug("Expected to have authed user from middleware, but found none")
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -182,20 +237,24 @@ func (rhandler RouteHandler) GetGameLongPoll(c *gin.Context) {
 		return
 	}
 
-	rhandler.Provider.Subscriptions[uriParams.ID] = make(subscriptions.GameListener)
-	// await an update message
-	game := <-rhandler.Provider.Subscriptions[uriParams.ID]
+	listener, unsubscribe := rhandler.Provider.Subscriptions.Subscribe(uriParams.ID)
+	defer unsubscribe()
 
-	if reflect.ValueOf(game).IsZero() {
-		// subscriber channel closed before game was sent
-		c.JSON(http.StatusInternalServerError, gin.H{})
+	// await an update message
+	var game model.Game
+	select {
+	case update, ok := <-listener:
+		if !ok {
+			// subscriber channel closed before game was sent
+			c.JSON(http.StatusInternalServerError, gin.H{})
+			return
+		}
+		game = update
+	case <-c.Request.Context().Done():
+		// client gave up waiting
 		return
 	}
 
-	// cleanup
-	close(rhandler.Provider.Subscriptions[uriParams.ID])
-	delete(rhandler.Provider.Subscriptions, uriParams.ID)
-
 	var respGame ElmGame
 	respGame.fromGame(game, *user)
 	c.JSON(http.StatusOK, gin.H{
@@ -208,7 +267,7 @@ func (rhandler RouteHandler) ListGamesByUser(c *gin.Context) {
 	// make sure we got authed user
 	user, err := getUserFromCtx(c)
 	if err != nil {
-		logger.Debug("Expected to have authed user from middleware, but found none")
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -217,7 +276,7 @@ func (rhandler RouteHandler) ListGamesByUser(c *gin.Context) {
 
 	games, err := rhandler.Provider.GameService.ListByUser(c, user.ID)
 	if err != nil {
-		logger.Error("Error fetching games for user with id %d: %v", user.ID, err)
+		logger.ErrorCtx(c, "Error fetching games for user", zap.Error(err))
 		internal := apperrors.NewInternal()
 		c.JSON(internal.Status(), gin.H{
 			"error": internal.Error(),
@@ -238,12 +297,126 @@ func (rhandler RouteHandler) ListGamesByUser(c *gin.Context) {
 	})
 }
 
+// GET /summaries
+func (rhandler RouteHandler) ListGameSummaries(c *gin.Context) {
+	// make sure we got authed user
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var query listGameSummariesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.DebugCtx(c, "Failed to parse game list query params", zap.Error(err))
+		badReqErr := apperrors.NewBadRequest("Invalid query parameters for game listing")
+		c.JSON(badReqErr.Status(), gin.H{
+			"error": badReqErr.Error(),
+		})
+		return
+	}
+
+	opts := model.GameListOptions{
+		UserId:        user.ID,
+		Status:        query.Status,
+		Turn:          query.Turn,
+		BoardSize:     query.BoardSize,
+		CreatedAfter:  query.CreatedAfter,
+		CreatedBefore: query.CreatedBefore,
+		Sort:          query.Sort,
+		Limit:         query.Limit,
+	}
+
+	if query.Cursor != "" {
+		cursor, err := model.DecodeGameCursor(query.Cursor)
+		if err != nil {
+			logger.DebugCtx(c, "Failed to decode game list cursor", zap.Error(err))
+			badReqErr := apperrors.NewBadRequest("Invalid cursor")
+			c.JSON(badReqErr.Status(), gin.H{
+				"error": badReqErr.Error(),
+			})
+			return
+		}
+		opts.Cursor = cursor
+	}
+
+	if query.Opponent != "" {
+		opponent, err := rhandler.Provider.UserService.FindByUsername(c, query.Opponent)
+		if err != nil {
+			// no such opponent means no games against them either
+			c.JSON(http.StatusOK, gin.H{
+				"games":      []gameSummaryResponse{},
+				"nextCursor": nil,
+			})
+			return
+		}
+		opts.OpponentId = opponent.ID
+	}
+
+	page, err := rhandler.Provider.GameService.ListSummaries(c, opts)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	resp := make([]gameSummaryResponse, len(page.Games))
+	for i, summary := range page.Games {
+		resp[i] = newGameSummaryResponse(summary, *user)
+	}
+
+	var nextCursor *string
+	if page.NextCursor != nil {
+		encoded := page.NextCursor.Encode()
+		nextCursor = &encoded
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"games":      resp,
+		"nextCursor": nextCursor,
+	})
+}
+
+// GET /pending
+func (rhandler RouteHandler) ListPendingGames(c *gin.Context) {
+	// make sure we got authed user
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	games, err := rhandler.Provider.GameService.ListPending(c, user.ID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	resp := make([]gameSummaryResponse, len(games))
+	for i, summary := range games {
+		resp[i] = newGameSummaryResponse(summary, *user)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"games": resp,
+	})
+}
+
 // POST /
 func (rhandler RouteHandler) CreateGame(c *gin.Context) {
 	// make sure we got authed user
 	user, err := getUserFromCtx(c)
 	if err != nil {
-		logger.Debug("Expected to have authed user from middleware, but found none")
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -259,7 +432,7 @@ func (rhandler RouteHandler) CreateGame(c *gin.Context) {
 	// transform input into game model
 	game, err := req.Game.toGame(user)
 	if err != nil {
-		logger.Error("Failed to construct game model from request data")
+		logger.ErrorCtx(c, "Failed to construct game model from request data")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -275,7 +448,7 @@ func (rhandler RouteHandler) CreateGame(c *gin.Context) {
 		game.WhitePlayerId = user.ID
 		opponentUsername = req.Game.BlackPlayerName
 	} else {
-		logger.Error("Requesting user not a member of the proposed game to create")
+		logger.ErrorCtx(c, "Requesting user not a member of the proposed game to create")
 		err := apperrors.NewForbidden()
 		c.JSON(err.Status(), gin.H{
 			"error": err.Error(),
@@ -285,7 +458,7 @@ func (rhandler RouteHandler) CreateGame(c *gin.Context) {
 
 	opponentUser, err := rhandler.Provider.UserService.FindByUsername(c, opponentUsername)
 	if err != nil {
-		logger.Debug("Opponent user not found by username")
+		logger.DebugCtx(c, "Opponent user not found by username")
 		err := apperrors.NewNotFound("User", opponentUsername)
 		c.JSON(err.Status(), gin.H{
 			"error": err.Error(),
@@ -308,13 +481,15 @@ func (rhandler RouteHandler) CreateGame(c *gin.Context) {
 	}
 
 	if err := rhandler.Provider.GameService.Create(c, game); err != nil {
-		logger.Error("Error creating game: %v", err.Error())
+		logger.ErrorCtx(c, "Error creating game", zap.Error(err))
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
+	instrumentation.GamesCreated.Inc()
+
 	c.JSON(http.StatusCreated, gin.H{
 		"uid": game.ID,
 	})
@@ -331,7 +506,7 @@ func (rhandler RouteHandler) UpdateGame(c *gin.Context) {
 	// make sure we got authed user
 	user, err := getUserFromCtx(c)
 	if err != nil {
-		logger.Debug("Expected to have authed user from middleware, but found none")
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -347,7 +522,7 @@ func (rhandler RouteHandler) UpdateGame(c *gin.Context) {
 	// transform input into game model
 	newGameValues, err := req.Game.toGame(user)
 	if err != nil {
-		logger.Warn("Failed to construct game model from request data")
+		logger.WarnCtx(c, "Failed to construct game model from request data")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -357,7 +532,7 @@ func (rhandler RouteHandler) UpdateGame(c *gin.Context) {
 	// fetch current value from DB
 	currentGame, err := rhandler.Provider.GameService.Get(c, uriParams.ID)
 	if err != nil {
-		logger.Debug("Error fetching game with id %d: %v", uriParams.ID, err)
+		logger.DebugCtx(c, "Error fetching game", zap.Error(err))
 		notFoundErr := apperrors.NewNotFound("Game", strconv.FormatUint(uint64(uriParams.ID), 10))
 		c.JSON(notFoundErr.Status(), gin.H{
 			"error": notFoundErr.Error(),
@@ -374,17 +549,45 @@ func (rhandler RouteHandler) UpdateGame(c *gin.Context) {
 		return
 	}
 
+	if req.Game.Version == 0 {
+		badReqErr := apperrors.NewBadRequest("Expected game version is required")
+		c.JSON(badReqErr.Status(), gin.H{
+			"error": badReqErr.Error(),
+		})
+		return
+	}
+	if req.Game.Version != currentGame.Version {
+		// client is behind; give them the current state to retry from
+		conflictErr := apperrors.NewVersionConflict("Game", strconv.FormatUint(uint64(uriParams.ID), 10))
+		rhandler.respondWithGameConflict(c, conflictErr, *currentGame, *user)
+		return
+	}
+
+	wasOver := currentGame.IsOver
+	prevMoveCount := len(currentGame.History)
 	currentGame.UpdateLegalValues(*newGameValues)
 	if err := rhandler.Provider.GameService.Update(c, currentGame); err != nil {
+		if apperrors.Status(err) == http.StatusConflict {
+			// lost a race with a concurrent update
+			if latestGame, getErr := rhandler.Provider.GameService.Get(c, uriParams.ID); getErr == nil {
+				rhandler.respondWithGameConflict(c, err, *latestGame, *user)
+				return
+			}
+		}
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	if rhandler.Provider.Subscriptions[uriParams.ID] != nil {
-		rhandler.Provider.Subscriptions[uriParams.ID] <- *currentGame
+	// undos shorten the history, so only count additions as moves
+	if newMoves := len(currentGame.History) - prevMoveCount; newMoves > 0 {
+		instrumentation.Moves.Add(float64(newMoves))
+	}
+	if currentGame.IsOver && !wasOver {
+		instrumentation.GamesFinished.Inc()
 	}
+	rhandler.Provider.Subscriptions.Publish(uriParams.ID, *currentGame)
 
 	// return game in shape elm expects
 	var respGame ElmGame
@@ -394,6 +597,19 @@ func (rhandler RouteHandler) UpdateGame(c *gin.Context) {
 	})
 }
 
+/**
+ * Responds with a 409 `err` along with the current state of `game`,
+ * for when a client tried to update an outdated version of it.
+ */
+func (rhandler RouteHandler) respondWithGameConflict(c *gin.Context, err error, game model.Game, authedUser model.User) {
+	var respGame ElmGame
+	respGame.fromGame(game, authedUser)
+	c.JSON(apperrors.Status(err), gin.H{
+		"error": err.Error(),
+		"game":  respGame,
+	})
+}
+
 // DELETE /:id
 func (rhandler RouteHandler) DeleteGame(c *gin.Context) {
 	uriParams, err := parseGameIdUriParam(c)
@@ -405,7 +621,7 @@ func (rhandler RouteHandler) DeleteGame(c *gin.Context) {
 	// make sure we got authed user
 	user, err := getUserFromCtx(c)
 	if err != nil {
-		logger.Debug("Expected to have authed user from middleware, but found none")
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
@@ -415,7 +631,7 @@ func (rhandler RouteHandler) DeleteGame(c *gin.Context) {
 	// fetch current value from DB
 	currentGame, err := rhandler.Provider.GameService.Get(c, uriParams.ID)
 	if err != nil {
-		logger.Debug("Error fetching game with id %d: %v", uriParams.ID, err)
+		logger.DebugCtx(c, "Error fetching game", zap.Error(err))
 		notFoundErr := apperrors.NewNotFound("Game", strconv.FormatUint(uint64(uriParams.ID), 10))
 		c.JSON(notFoundErr.Status(), gin.H{
 			"error": notFoundErr.Error(),
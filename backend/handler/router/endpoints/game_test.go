@@ -11,13 +11,16 @@ import (
 	"testing"
 	"time"
 
+	"net-go/server/backend/apperrors"
 	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/instrumentation"
 	"net-go/server/backend/model"
 	"net-go/server/backend/model/types"
 	"net-go/server/backend/services/mocks"
 	"net-go/server/backend/subscriptions"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -60,7 +63,7 @@ func buildGameRouter(mockGameService *mocks.MockGameService, mockUserService *mo
 		R:             router,
 		GameService:   mockGameService,
 		UserService:   mockUserService,
-		Subscriptions: make(subscriptions.GameSubscriptions),
+		Subscriptions: subscriptions.NewHub(),
 	}
 	rhandler := NewRouteHandler(p)
 	if ctxUser != nil {
@@ -76,6 +79,8 @@ func buildGameRouter(mockGameService *mocks.MockGameService, mockUserService *mo
 	router.POST("/api/games/", rhandler.CreateGame)
 	router.POST("/api/games/:id", rhandler.UpdateGame)
 	router.GET("/api/games/", rhandler.ListGamesByUser)
+	router.GET("/api/games/summaries", rhandler.ListGameSummaries)
+	router.GET("/api/games/pending", rhandler.ListPendingGames)
 	router.DELETE("/api/games/:id", rhandler.DeleteGame)
 
 	return router
@@ -258,6 +263,7 @@ func TestCreateGameIntegration(t *testing.T) {
 		// do request
 		req, err := http.NewRequest(http.MethodPost, "/api/games/", bytes.NewBuffer(mockReqBody))
 		assert.NoError(t, err)
+		gamesCreated := testutil.ToFloat64(instrumentation.GamesCreated)
 
 		router.ServeHTTP(rr, req)
 
@@ -267,6 +273,7 @@ func TestCreateGameIntegration(t *testing.T) {
 		})
 		assert.Equal(t, 201, rr.Code)
 		assert.Equal(t, expectedResp, rr.Body.Bytes())
+		assert.Equal(t, gamesCreated+1, testutil.ToFloat64(instrumentation.GamesCreated))
 
 		mockGameService.AssertExpectations(t)
 	})
@@ -475,6 +482,7 @@ func TestUpdateGameIntegration(t *testing.T) {
 			Score:         types.Score{},
 			WhitePlayer:   user,
 			WhitePlayerId: user.ID,
+			Version:       1,
 		}
 		game.ID = 123
 		mockGameService := new(mocks.MockGameService)
@@ -508,6 +516,7 @@ func TestUpdateGameIntegration(t *testing.T) {
 				PlayerColor:     types.White,
 				BlackPlayerName: "sally",
 				WhitePlayerName: "tim",
+				Version:         1,
 			},
 		})
 		assert.NoError(t, err)
@@ -529,6 +538,75 @@ func TestUpdateGameIntegration(t *testing.T) {
 
 		mockGameService.AssertExpectations(t)
 	})
+	t.Run("records moves and finished games", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+			Password: "pwnd",
+		}
+		user.ID = 123
+		game := model.Game{
+			Board: types.Board{
+				Size: types.Full,
+				Map:  [][]types.Piece{},
+			},
+			History:       make([]types.Move, 0),
+			Score:         types.Score{},
+			WhitePlayer:   user,
+			WhitePlayerId: user.ID,
+			Version:       1,
+		}
+		game.ID = 123
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On(
+				"Get",
+				mock.AnythingOfType("*gin.Context"),
+				uint(123),
+			).
+			Return(&game, nil)
+		mockGameService.
+			On(
+				"Update",
+				mock.AnythingOfType("*gin.Context"),
+				mock.AnythingOfType("*model.Game"),
+			).
+			Return(nil)
+
+		// record responses
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, &user)
+
+		// create mock req; both players passed, ending the game
+		mockReqBody, err := json.Marshal(gin.H{
+			"game": ElmGame{
+				BoardSize:       types.Full,
+				Board:           make([]types.Piece, 0),
+				History:         []types.Move{{MoveType: types.Pass}, {MoveType: types.Pass}},
+				IsOver:          true,
+				Score:           types.Score{},
+				PlayerColor:     types.White,
+				BlackPlayerName: "sally",
+				WhitePlayerName: "tim",
+				Version:         1,
+			},
+		})
+		assert.NoError(t, err)
+
+		// do request
+		req, err := http.NewRequest(http.MethodPost, "/api/games/123", bytes.NewBuffer(mockReqBody))
+		assert.NoError(t, err)
+		moves := testutil.ToFloat64(instrumentation.Moves)
+		gamesFinished := testutil.ToFloat64(instrumentation.GamesFinished)
+
+		router.ServeHTTP(rr, req)
+
+		// validate
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, moves+2, testutil.ToFloat64(instrumentation.Moves))
+		assert.Equal(t, gamesFinished+1, testutil.ToFloat64(instrumentation.GamesFinished))
+
+		mockGameService.AssertExpectations(t)
+	})
 	t.Run("404 returned when game to update isn't found", func(t *testing.T) {
 		user := model.User{
 			Username: "tim",
@@ -624,6 +702,166 @@ func TestUpdateGameIntegration(t *testing.T) {
 		assert.Equal(t, 403, rr.Code)
 		mockGameService.AssertExpectations(t)
 	})
+	t.Run("bad request when expected version is missing", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		game := model.Game{
+			Board: types.Board{
+				Size: types.Full,
+				Map:  [][]types.Piece{},
+			},
+			WhitePlayerId: user.ID,
+			Version:       1,
+		}
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On("Get", mock.AnythingOfType("*gin.Context"), uint(123)).
+			Return(&game, nil)
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, &user)
+
+		mockReqBody, err := json.Marshal(gin.H{
+			"game": ElmGame{
+				BoardSize:       types.Full,
+				Board:           make([]types.Piece, 0),
+				History:         make([]types.Move, 0),
+				Score:           types.Score{},
+				PlayerColor:     types.White,
+				BlackPlayerName: "sally",
+				WhitePlayerName: "tim",
+			},
+		})
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/games/123", bytes.NewBuffer(mockReqBody))
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 400, rr.Code)
+		mockGameService.AssertNotCalled(t, "Update")
+	})
+	t.Run("conflict with current state when expected version is outdated", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		game := model.Game{
+			Board: types.Board{
+				Size: types.Full,
+				Map:  [][]types.Piece{},
+			},
+			History: []types.Move{
+				{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 4},
+			},
+			WhitePlayerId: user.ID,
+			Version:       2,
+		}
+		game.ID = 123
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On("Get", mock.AnythingOfType("*gin.Context"), uint(123)).
+			Return(&game, nil)
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, &user)
+
+		mockReqBody, err := json.Marshal(gin.H{
+			"game": ElmGame{
+				BoardSize:       types.Full,
+				Board:           make([]types.Piece, 0),
+				History:         make([]types.Move, 0),
+				Score:           types.Score{},
+				PlayerColor:     types.White,
+				BlackPlayerName: "sally",
+				WhitePlayerName: "tim",
+				Version:         1,
+			},
+		})
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/games/123", bytes.NewBuffer(mockReqBody))
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		var currentGame ElmGame
+		currentGame.fromGame(game, user)
+		expectedResp, err := json.Marshal(gin.H{
+			"error": apperrors.NewVersionConflict("Game", "123").Error(),
+			"game":  currentGame,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 409, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+		mockGameService.AssertNotCalled(t, "Update")
+	})
+	t.Run("conflict with latest state when a concurrent update wins", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		game := model.Game{
+			Board: types.Board{
+				Size: types.Full,
+				Map:  [][]types.Piece{},
+			},
+			WhitePlayerId: user.ID,
+			Version:       1,
+		}
+		game.ID = 123
+		latestGame := game
+		latestGame.Version = 2
+		latestGame.History = []types.Move{
+			{MoveType: types.Pass, Piece: types.BlackStone},
+		}
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On("Get", mock.AnythingOfType("*gin.Context"), uint(123)).
+			Return(&game, nil).
+			Once()
+		mockGameService.
+			On("Update", mock.AnythingOfType("*gin.Context"), mock.AnythingOfType("*model.Game")).
+			Return(apperrors.NewVersionConflict("Game", "123"))
+		mockGameService.
+			On("Get", mock.AnythingOfType("*gin.Context"), uint(123)).
+			Return(&latestGame, nil).
+			Once()
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, &user)
+
+		mockReqBody, err := json.Marshal(gin.H{
+			"game": ElmGame{
+				BoardSize:       types.Full,
+				Board:           make([]types.Piece, 0),
+				History:         make([]types.Move, 0),
+				Score:           types.Score{},
+				PlayerColor:     types.White,
+				BlackPlayerName: "sally",
+				WhitePlayerName: "tim",
+				Version:         1,
+			},
+		})
+		assert.NoError(t, err)
+
+		req, err := http.NewRequest(http.MethodPost, "/api/games/123", bytes.NewBuffer(mockReqBody))
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		var respBody struct {
+			Game ElmGame `json:"game"`
+		}
+		assert.Equal(t, 409, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &respBody))
+		assert.Equal(t, uint(2), respBody.Game.Version)
+		assert.Len(t, respBody.Game.History, 1)
+		mockGameService.AssertExpectations(t)
+	})
 	t.Run("incorrect URI fails as bad request", func(t *testing.T) {
 		mockGameService := new(mocks.MockGameService)
 		user := model.User{
@@ -801,6 +1039,239 @@ func TestListGameIntegration(t *testing.T) {
 	})
 }
 
+func TestListGameSummariesIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+			Password: "pwnd",
+		}
+		user.ID = 123
+		opponent := model.User{
+			Username: "tom",
+		}
+		opponent.ID = 456
+		summary := model.GameSummary{
+			BoardSize:      types.Full,
+			MoveCount:      3,
+			ActivePlayerId: user.ID,
+			BlackPlayerId:  opponent.ID,
+			WhitePlayerId:  user.ID,
+			BlackPlayer:    opponent,
+			WhitePlayer:    user,
+		}
+		summary.ID = 7
+		nextCursor := model.NewGameCursor(summary, model.CreatedDesc)
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On(
+				"ListSummaries",
+				mock.AnythingOfType("*gin.Context"),
+				model.GameListOptions{
+					UserId: user.ID,
+					Status: model.ActiveGames,
+					Limit:  1,
+				},
+			).
+			Return(&model.GameSummaryPage{
+				Games:      []model.GameSummary{summary},
+				NextCursor: &nextCursor,
+			}, nil)
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, &user)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/games/summaries?status=active&limit=1", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		var resp struct {
+			Games      []gameSummaryResponse `json:"games"`
+			NextCursor string                `json:"nextCursor"`
+		}
+		assert.Equal(t, 200, rr.Code)
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Len(t, resp.Games, 1)
+		assert.Equal(t, "7", resp.Games[0].ID)
+		assert.Equal(t, types.White, resp.Games[0].PlayerColor)
+		assert.Equal(t, "tom", resp.Games[0].BlackPlayerName)
+		assert.True(t, resp.Games[0].IsActiveTurn)
+		assert.Equal(t, nextCursor.Encode(), resp.NextCursor)
+		assert.NotContains(t, rr.Body.String(), "history")
+		assert.NotContains(t, rr.Body.String(), "pwnd")
+
+		mockGameService.AssertExpectations(t)
+	})
+	t.Run("cursor and opponent filters are passed to service", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		opponent := model.User{
+			Username: "tom",
+		}
+		opponent.ID = 456
+		cursor := model.GameCursor{
+			SortValue: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			ID:        99,
+		}
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("FindByUsername", mock.AnythingOfType("*gin.Context"), "tom").
+			Return(&opponent, nil)
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On(
+				"ListSummaries",
+				mock.AnythingOfType("*gin.Context"),
+				mock.MatchedBy(func(opts model.GameListOptions) bool {
+					return opts.OpponentId == opponent.ID &&
+						opts.Cursor != nil &&
+						opts.Cursor.ID == cursor.ID &&
+						opts.Cursor.SortValue.Equal(cursor.SortValue) &&
+						opts.BoardSize == types.Small &&
+						opts.Sort == model.UpdatedAsc &&
+						opts.Limit == 20
+				}),
+			).
+			Return(&model.GameSummaryPage{Games: []model.GameSummary{}}, nil)
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, mockUserService, &user)
+
+		url := "/api/games/summaries?opponent=tom&boardSize=9&sort=updated_asc&cursor=" + cursor.Encode()
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(gin.H{
+			"games":      []gameSummaryResponse{},
+			"nextCursor": nil,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+		mockGameService.AssertExpectations(t)
+	})
+	t.Run("invalid query params fail as bad request", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		mockGameService := new(mocks.MockGameService)
+
+		for _, query := range []string{"status=paused", "limit=1000", "boardSize=7", "sort=random", "cursor=not-a-cursor!", "createdAfter=yesterday"} {
+			rr := httptest.NewRecorder()
+			router := buildGameRouter(mockGameService, nil, &user)
+
+			req, err := http.NewRequest(http.MethodGet, "/api/games/summaries?"+query, nil)
+			assert.NoError(t, err)
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, 400, rr.Code, query)
+		}
+		mockGameService.AssertNotCalled(t, "ListSummaries")
+	})
+	t.Run("request is rejected when user is not set by middleware", func(t *testing.T) {
+		mockGameService := new(mocks.MockGameService)
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/games/summaries", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 401, rr.Code)
+		mockGameService.AssertNotCalled(t, "ListSummaries")
+	})
+}
+
+func TestListPendingGamesIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		opponent := model.User{
+			Username: "tom",
+		}
+		opponent.ID = 456
+		summary := model.GameSummary{
+			BoardSize:      types.Small,
+			MoveCount:      4,
+			ActivePlayerId: user.ID,
+			BlackPlayerId:  user.ID,
+			WhitePlayerId:  opponent.ID,
+			BlackPlayer:    user,
+			WhitePlayer:    opponent,
+		}
+		summary.ID = 9
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On("ListPending", mock.AnythingOfType("*gin.Context"), user.ID).
+			Return([]model.GameSummary{summary}, nil)
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, &user)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/games/pending", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(gin.H{
+			"games": []gameSummaryResponse{newGameSummaryResponse(summary, user)},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+		mockGameService.AssertExpectations(t)
+	})
+	t.Run("service error is returned", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On("ListPending", mock.AnythingOfType("*gin.Context"), user.ID).
+			Return(nil, apperrors.NewInternal())
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, &user)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/games/pending", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 500, rr.Code)
+		mockGameService.AssertExpectations(t)
+	})
+	t.Run("request is rejected when user is not set by middleware", func(t *testing.T) {
+		mockGameService := new(mocks.MockGameService)
+
+		rr := httptest.NewRecorder()
+		router := buildGameRouter(mockGameService, nil, nil)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/games/pending", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 401, rr.Code)
+		mockGameService.AssertNotCalled(t, "ListPending")
+	})
+}
+
 func TestDeleteGameIntegration(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -955,6 +1426,7 @@ func TestGetGameLongPollIntegration(t *testing.T) {
 			BlackPlayerId: user.ID,
 			WhitePlayer:   user2,
 			WhitePlayerId: user2.ID,
+			Version:       1,
 		}
 		mockGameService := new(mocks.MockGameService)
 		mockGameService.
@@ -1004,6 +1476,7 @@ func TestGetGameLongPollIntegration(t *testing.T) {
 				BlackPlayerName: "tim",
 				WhitePlayerName: "sally",
 				ID:              "0",
+				Version:         1,
 			},
 		})
 		assert.NoError(t, err)
@@ -0,0 +1,36 @@
+package endpoints
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GET /healthz
+// liveness; succeeds whenever the process is able to serve requests
+func (rhandler RouteHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GET /readyz
+// readiness; fails while a dependency is down or the server is shutting down
+func (rhandler RouteHandler) Readyz(c *gin.Context) {
+	report := rhandler.Provider.Readiness.Check(c.Request.Context())
+
+	if report.Ready {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ready",
+			"checks": report.Checks,
+		})
+		return
+	}
+
+	status := "unavailable"
+	if report.Draining {
+		status = "draining"
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"status": status,
+		"checks": report.Checks,
+	})
+}
@@ -0,0 +1,116 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildHealthRouter(readiness *health.Readiness) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:         router,
+		Readiness: readiness,
+	}
+	rhandler := NewRouteHandler(p)
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.GET("/healthz", rhandler.Healthz)
+	router.GET("/readyz", rhandler.Readyz)
+	return router
+}
+
+func TestHealthzIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router := buildHealthRouter(health.NewReadiness())
+
+		req, err := http.NewRequest(http.MethodGet, "/healthz", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(gin.H{"status": "ok"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+	})
+}
+
+func TestReadyzIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	dbCheck := func(err error) health.Check {
+		return health.Check{
+			Name: "database",
+			Run:  func(ctx context.Context) error { return err },
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router := buildHealthRouter(health.NewReadiness(dbCheck(nil)))
+
+		req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(gin.H{
+			"status": "ready",
+			"checks": map[string]string{"database": "ok"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+	})
+	t.Run("failing check", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		router := buildHealthRouter(health.NewReadiness(dbCheck(errors.New("connection refused"))))
+
+		req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(gin.H{
+			"status": "unavailable",
+			"checks": map[string]string{"database": "connection refused"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+	})
+	t.Run("draining", func(t *testing.T) {
+		readiness := health.NewReadiness(dbCheck(nil))
+		readiness.StartDraining()
+		rr := httptest.NewRecorder()
+		router := buildHealthRouter(readiness)
+
+		req, err := http.NewRequest(http.MethodGet, "/readyz", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(gin.H{
+			"status": "draining",
+			"checks": map[string]string{},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+	})
+}
@@ -0,0 +1,333 @@
+package endpoints
+
+import (
+	"crypto/subtle"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/binding"
+	"net-go/server/backend/handler/cookies"
+	"net-go/server/backend/identity"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// how long users have to sign in at the provider, and then to pick a username
+const oidcCookieTTL = 10 * time.Minute
+
+// a sign in at the identity provider in progress, kept in a signed cookie until the callback
+type oidcState struct {
+	Request identity.AuthRequest `json:"request"`
+	// the signed in user linking the identity, or 0 to sign in with it
+	LinkUserID uint `json:"linkUserId,omitempty"`
+}
+
+type oidcSignupReq struct {
+	Username string `json:"username" binding:"required,gte=1,lte=30"`
+}
+
+// a linked identity, as shown to its user
+type identityInfo struct {
+	ID        uint      `json:"id"`
+	Issuer    string    `json:"issuer"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type identityUri struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// GET /accounts/oidc/login
+func (rhandler RouteHandler) OIDCLogin(c *gin.Context) {
+	rhandler.redirectToIdentityProvider(c, 0)
+}
+
+// GET /accounts/oidc/link
+func (rhandler RouteHandler) OIDCLink(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	rhandler.redirectToIdentityProvider(c, user.ID)
+}
+
+// sends the user to sign in at the identity provider, to come back to OIDCCallback
+func (rhandler RouteHandler) redirectToIdentityProvider(c *gin.Context, linkUserId uint) {
+	provider, ok := rhandler.identityProvider(c)
+	if !ok {
+		return
+	}
+
+	req, err := identity.NewAuthRequest()
+	if err != nil {
+		logger.ErrorCtx(c, "Unable to start OIDC sign in", zap.Error(err))
+		internalErr := apperrors.NewInternal()
+		c.JSON(internalErr.Status(), gin.H{
+			"error": internalErr.Error(),
+		})
+		return
+	}
+	authURL, err := provider.AuthCodeURL(c, req)
+	if err != nil {
+		logger.ErrorCtx(c, "Unable to reach identity provider", zap.Error(err))
+		internalErr := apperrors.NewInternal()
+		c.JSON(internalErr.Status(), gin.H{
+			"error": internalErr.Error(),
+		})
+		return
+	}
+	state := oidcState{Request: *req, LinkUserID: linkUserId}
+	if err := cookies.SetSignedCookie(c, cookies.OIDCStateCookieKey, state, oidcCookieTTL); err != nil {
+		logger.ErrorCtx(c, "Unable to set OIDC state cookie", zap.Error(err))
+		internalErr := apperrors.NewInternal()
+		c.JSON(internalErr.Status(), gin.H{
+			"error": internalErr.Error(),
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+/**
+ * GET /accounts/oidc/callback
+ * Where the identity provider sends users back to. Identities linked to
+ * a user sign in as them (through the 2FA page, if they have it on);
+ * new ones go on to pick a username for their account, which is then
+ * created by OIDCSignup.
+ */
+func (rhandler RouteHandler) OIDCCallback(c *gin.Context) {
+	provider, ok := rhandler.identityProvider(c)
+	if !ok {
+		return
+	}
+
+	var state oidcState
+	err := cookies.GetSignedCookie(c, cookies.OIDCStateCookieKey, &state)
+	// each sign in at the provider is only good for one callback
+	cookies.DeleteSignedCookie(c, cookies.OIDCStateCookieKey)
+	if err != nil {
+		logger.DebugCtx(c, "OIDC callback without a sign in in progress", zap.Error(err))
+		rhandler.rejectOIDCCallback(c)
+		return
+	}
+	if providerErr := c.Query("error"); providerErr != "" {
+		logger.DebugCtx(c, "Identity provider refused sign in", zap.String("error", providerErr))
+		rhandler.rejectOIDCCallback(c)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(state.Request.State)) != 1 {
+		logger.DebugCtx(c, "OIDC callback state mismatch")
+		rhandler.rejectOIDCCallback(c)
+		return
+	}
+	claims, err := provider.Exchange(c, c.Query("code"), &state.Request)
+	if err != nil {
+		logger.WarnCtx(c, "Failed to exchange OIDC code", zap.Error(err))
+		rhandler.rejectOIDCCallback(c)
+		return
+	}
+
+	if state.LinkUserID != 0 {
+		if _, err := rhandler.Provider.IdentityService.Link(c, state.LinkUserID, claims); err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+
+	user, err := rhandler.Provider.IdentityService.FindUser(c, claims)
+	if err != nil && apperrors.Status(err) != http.StatusNotFound {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		// new here; hold on to who they are while they pick a username
+		if err := cookies.SetSignedCookie(c, cookies.OIDCSignupCookieKey, claims, oidcCookieTTL); err != nil {
+			logger.ErrorCtx(c, "Unable to set OIDC signup cookie", zap.Error(err))
+			internalErr := apperrors.NewInternal()
+			c.JSON(internalErr.Status(), gin.H{
+				"error": internalErr.Error(),
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, "/signup/oidc?username="+url.QueryEscape(suggestedUsername(claims)))
+		return
+	}
+
+	// the provider stands in for the password, not the second factor
+	if user.TwoFactorEnabled() {
+		ticket, err := rhandler.Provider.TwoFactorService.StartChallenge(c, user.ID)
+		if err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, "/signin/2fa?ticket="+url.QueryEscape(ticket))
+		return
+	}
+
+	if ok := rhandler.createSession(c, user); !ok {
+		return
+	}
+	c.Redirect(http.StatusFound, "/")
+}
+
+/**
+ * POST /accounts/oidc/signup
+ * Creates the account of a user back from the identity provider for
+ * the first time (see OIDCCallback), with the username they picked.
+ */
+func (rhandler RouteHandler) OIDCSignup(c *gin.Context) {
+	if _, ok := rhandler.identityProvider(c); !ok {
+		return
+	}
+	var req oidcSignupReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	var claims identity.Claims
+	if err := cookies.GetSignedCookie(c, cookies.OIDCSignupCookieKey, &claims); err != nil {
+		logger.DebugCtx(c, "OIDC signup without a sign in at the provider", zap.Error(err))
+		unauthErr := apperrors.NewUnauthorized()
+		c.JSON(unauthErr.Status(), gin.H{
+			"error": unauthErr.Error(),
+		})
+		return
+	}
+
+	user, err := rhandler.Provider.IdentityService.Signup(c, req.Username, &claims)
+	if err != nil {
+		logger.DebugCtx(c, "Failed to sign up user with identity", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	cookies.DeleteSignedCookie(c, cookies.OIDCSignupCookieKey)
+
+	if ok := rhandler.createSession(c, user); !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"uid":      user.ID,
+		"username": user.Username,
+	})
+}
+
+// GET /accounts/identities
+func (rhandler RouteHandler) ListIdentities(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	identities, err := rhandler.Provider.IdentityService.List(c, user.ID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	infos := make([]identityInfo, 0, len(identities))
+	for _, linked := range identities {
+		infos = append(infos, newIdentityInfo(linked))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"identities": infos,
+	})
+}
+
+// DELETE /accounts/identities/:id
+func (rhandler RouteHandler) UnlinkIdentity(c *gin.Context) {
+	var uriParams identityUri
+	if err := c.ShouldBindUri(&uriParams); err != nil {
+		logger.WarnCtx(c, "Failed to parse identity URI params", zap.Error(err))
+		badReqErr := apperrors.NewBadRequest("Invalid URI parameter for ID")
+		c.JSON(badReqErr.Status(), gin.H{
+			"error": badReqErr.Error(),
+		})
+		return
+	}
+
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// only ever deletes identities of `user`, so others' ids are just a 404
+	if err := rhandler.Provider.IdentityService.Unlink(c, user.ID, uriParams.ID); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// the configured identity provider; responds with a 404 if there's none
+func (rhandler RouteHandler) identityProvider(c *gin.Context) (identity.Provider, bool) {
+	if rhandler.Provider.IdentityProvider == nil {
+		notFoundErr := apperrors.NewNotFound("Identity provider", "oidc")
+		c.JSON(notFoundErr.Status(), gin.H{
+			"error": notFoundErr.Error(),
+		})
+		return nil, false
+	}
+	return rhandler.Provider.IdentityProvider, true
+}
+
+// a failed sign in at the provider; they have to start over
+func (rhandler RouteHandler) rejectOIDCCallback(c *gin.Context) {
+	unauthErr := apperrors.NewUnauthorized()
+	c.JSON(unauthErr.Status(), gin.H{
+		"error": unauthErr.Error(),
+	})
+}
+
+func newIdentityInfo(linked model.UserIdentity) identityInfo {
+	return identityInfo{
+		ID:        linked.ID,
+		Issuer:    linked.Issuer,
+		Email:     linked.Email,
+		CreatedAt: linked.CreatedAt,
+	}
+}
+
+// what to offer new users as their username, from what they go by at the provider
+func suggestedUsername(claims *identity.Claims) string {
+	username := claims.PreferredUsername
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if runes := []rune(username); len(runes) > 30 {
+		username = string(runes[:30])
+	}
+	return username
+}
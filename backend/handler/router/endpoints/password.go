@@ -0,0 +1,144 @@
+package endpoints
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/binding"
+	"net-go/server/backend/handler/cookies"
+	"net-go/server/backend/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type changePasswordReq struct {
+	CurrentPassword string `json:"currentPassword" binding:"required,lte=30"`
+	NewPassword     string `json:"newPassword" binding:"required,gte=8,lte=30"`
+}
+
+type passwordResetReq struct {
+	Username string `json:"username" binding:"required,gte=1,lte=30"`
+}
+
+type confirmPasswordResetReq struct {
+	Token       string `json:"token" binding:"required,lte=64"`
+	NewPassword string `json:"newPassword" binding:"required,gte=8,lte=30"`
+}
+
+/**
+ * POST /accounts/password
+ * Signs every other device out and revokes the user's API tokens, since
+ * the old password may be why they exist; this device gets a fresh session.
+ */
+func (rhandler RouteHandler) ChangePassword(c *gin.Context) {
+	var req changePasswordReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	err = rhandler.Provider.UserService.ChangePassword(c, user.ID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		logger.DebugCtx(c, "Failed to change password", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// rotate sessions: revoke them all (and API tokens), then sign this device back in
+	if err := rhandler.revokeAllAccess(c, user.ID); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	token, _, err := rhandler.Provider.SessionService.Create(c, user.ID, sessionClient(c))
+	if err != nil {
+		// the password did change; this device just has to sign in again
+		cookies.DeleteAuthCookiesInResponse(c)
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	cookies.SetAuthCookiesInResponse(*user, token, c)
+
+	logger.InfoCtx(c, "User changed password")
+	c.JSON(http.StatusOK, gin.H{
+		"uid":      user.ID,
+		"username": user.Username,
+	})
+}
+
+/**
+ * POST /accounts/password/reset
+ * Responds the same whether or not the user exists.
+ */
+func (rhandler RouteHandler) RequestPasswordReset(c *gin.Context) {
+	var req passwordResetReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	if err := rhandler.Provider.UserService.RequestPasswordReset(c, req.Username); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If that account exists, a password reset link is on its way",
+	})
+}
+
+/**
+ * POST /accounts/password/reset/confirm
+ * Signs the user out everywhere and revokes their API tokens; they sign
+ * in again with the new password.
+ */
+func (rhandler RouteHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req confirmPasswordResetReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	user, err := rhandler.Provider.UserService.ResetPassword(c, req.Token, req.NewPassword)
+	if err != nil {
+		logger.DebugCtx(c, "Failed to reset password", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := rhandler.revokeAllAccess(c, user.ID); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	cookies.DeleteAuthCookiesInResponse(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"uid":      user.ID,
+		"username": user.Username,
+	})
+}
+
+// revokes every session and API token of `userId`, all of which may have come from the old password
+func (rhandler RouteHandler) revokeAllAccess(c *gin.Context, userId uint) error {
+	if err := rhandler.Provider.SessionService.RevokeAll(c, userId); err != nil {
+		return err
+	}
+	return rhandler.Provider.APITokenService.RevokeAll(c, userId)
+}
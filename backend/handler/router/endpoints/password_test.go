@@ -0,0 +1,186 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/model"
+	"net-go/server/backend/services/mocks"
+)
+
+func buildPasswordRouter(mockUserService *mocks.MockUserService, mockSessionService *mocks.MockSessionService, mockAPITokenService *mocks.MockAPITokenService, ctxUser *model.User) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:               router,
+		UserService:     mockUserService,
+		SessionService:  mockSessionService,
+		APITokenService: mockAPITokenService,
+	}
+	rhandler := NewRouteHandler(p)
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.POST("/api/accounts/password/reset", rhandler.RequestPasswordReset)
+	router.POST("/api/accounts/password/reset/confirm", rhandler.ConfirmPasswordReset)
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+	authed.POST("/api/accounts/password", rhandler.ChangePassword)
+	return router
+}
+
+func jsonRequest(t *testing.T, method string, url string, body gin.H) *http.Request {
+	t.Helper()
+	reqBody, err := json.Marshal(body)
+	assert.NoError(t, err)
+	request, err := http.NewRequest(method, url, bytes.NewBuffer(reqBody))
+	assert.NoError(t, err)
+	request.Header.Set("Content-Type", "application/json")
+	return request
+}
+
+func TestChangePasswordIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := &model.User{Username: "tim"}
+	user.ID = 4
+
+	t.Run("Changes the password, rotates sessions and revokes API tokens", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("ChangePassword", mock.AnythingOfType("*gin.Context"), uint(4), "password1", "password2").
+			Return(nil)
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("RevokeAll", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(nil)
+		mockSessionService.
+			On("Create", mock.AnythingOfType("*gin.Context"), uint(4), mock.AnythingOfType("model.SessionClient")).
+			Return("new-token", &model.Session{ID: 9, UserID: 4}, nil)
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("RevokeAll", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(nil)
+
+		router := buildPasswordRouter(mockUserService, mockSessionService, mockAPITokenService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/password", gin.H{
+			"currentPassword": "password1",
+			"newPassword":     "password2",
+		}))
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=4%3A%3Anew-token")
+		mockUserService.AssertExpectations(t)
+		mockSessionService.AssertExpectations(t)
+		mockAPITokenService.AssertExpectations(t)
+	})
+
+	t.Run("Wrong current password leaves sessions alone", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("ChangePassword", mock.AnythingOfType("*gin.Context"), uint(4), "wrong", "password2").
+			Return(apperrors.NewBadRequest("Current password is incorrect"))
+		mockSessionService := new(mocks.MockSessionService)
+
+		router := buildPasswordRouter(mockUserService, mockSessionService, new(mocks.MockAPITokenService), user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/password", gin.H{
+			"currentPassword": "wrong",
+			"newPassword":     "password2",
+		}))
+
+		assert.Equal(t, 400, rr.Code)
+		mockSessionService.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("New password too short", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+
+		router := buildPasswordRouter(mockUserService, new(mocks.MockSessionService), new(mocks.MockAPITokenService), user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/password", gin.H{
+			"currentPassword": "password1",
+			"newPassword":     "short",
+		}))
+
+		assert.Equal(t, 400, rr.Code)
+		mockUserService.AssertNotCalled(t, "ChangePassword")
+	})
+}
+
+func TestPasswordResetIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Requesting a reset is accepted", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("RequestPasswordReset", mock.AnythingOfType("*gin.Context"), "tim").
+			Return(nil)
+
+		router := buildPasswordRouter(mockUserService, new(mocks.MockSessionService), new(mocks.MockAPITokenService), nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/password/reset", gin.H{
+			"username": "tim",
+		}))
+
+		assert.Equal(t, 202, rr.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Confirming a reset signs the user out everywhere and revokes API tokens", func(t *testing.T) {
+		user := &model.User{Username: "tim"}
+		user.ID = 4
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("ResetPassword", mock.AnythingOfType("*gin.Context"), "reset-token", "password2").
+			Return(user, nil)
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("RevokeAll", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(nil)
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("RevokeAll", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(nil)
+
+		router := buildPasswordRouter(mockUserService, mockSessionService, mockAPITokenService, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/password/reset/confirm", gin.H{
+			"token":       "reset-token",
+			"newPassword": "password2",
+		}))
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=;")
+		mockUserService.AssertExpectations(t)
+		mockSessionService.AssertExpectations(t)
+		mockAPITokenService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid reset token", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("ResetPassword", mock.AnythingOfType("*gin.Context"), "used-token", "password2").
+			Return(nil, apperrors.NewBadRequest("Password reset link is invalid or has expired"))
+		mockSessionService := new(mocks.MockSessionService)
+
+		router := buildPasswordRouter(mockUserService, mockSessionService, new(mocks.MockAPITokenService), nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/password/reset/confirm", gin.H{
+			"token":       "used-token",
+			"newPassword": "password2",
+		}))
+
+		assert.Equal(t, 400, rr.Code)
+		mockSessionService.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+	})
+}
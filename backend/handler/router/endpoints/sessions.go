@@ -0,0 +1,162 @@
+package endpoints
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/cookies"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type sessionUri struct {
+	ID uint `uri:"id" binding:"required"`
+}
+
+// a signed in device, as shown to its user; never include the token hash here!
+type sessionInfo struct {
+	ID         uint      `json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	// whether this is the session making the request
+	Current bool `json:"current"`
+}
+
+func newSessionInfo(session model.Session, currentId uint) sessionInfo {
+	return sessionInfo{
+		ID:         session.ID,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		Current:    session.ID == currentId,
+	}
+}
+
+// the device making the request, to label the session it signs in to
+func sessionClient(c *gin.Context) model.SessionClient {
+	return model.SessionClient{
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+}
+
+func getSessionFromCtx(c *gin.Context) (*model.Session, error) {
+	untypedSession, exists := c.Get("session")
+	if !exists {
+		return nil, apperrors.NewUnauthorized()
+	}
+	session := untypedSession.(*model.Session)
+	return session, nil
+}
+
+// GET /accounts/sessions
+func (rhandler RouteHandler) ListSessions(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	current, err := getSessionFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have session from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	sessions, err := rhandler.Provider.SessionService.List(c, user.ID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	infos := make([]sessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		infos = append(infos, newSessionInfo(session, current.ID))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": infos,
+	})
+}
+
+// DELETE /accounts/sessions/:id
+func (rhandler RouteHandler) RevokeSession(c *gin.Context) {
+	var uriParams sessionUri
+	if err := c.ShouldBindUri(&uriParams); err != nil {
+		logger.WarnCtx(c, "Failed to parse session URI params", zap.Error(err))
+		badReqErr := apperrors.NewBadRequest("Invalid URI parameter for ID")
+		c.JSON(badReqErr.Status(), gin.H{
+			"error": badReqErr.Error(),
+		})
+		return
+	}
+
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	current, err := getSessionFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have session from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// only ever deletes sessions of `user`, so others' ids are just a 404
+	if err := rhandler.Provider.SessionService.Revoke(c, user.ID, uriParams.ID); err != nil {
+		logger.DebugCtx(c, "Failed to revoke session", zap.Uint("session_id", uriParams.ID), zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// revoking this device's own session signs it out
+	if uriParams.ID == current.ID {
+		cookies.DeleteAuthCookiesInResponse(c)
+	}
+	logger.InfoCtx(c, "Revoked user session", zap.Uint("session_id", uriParams.ID))
+	c.JSON(http.StatusNoContent, gin.H{})
+}
+
+// DELETE /accounts/sessions
+func (rhandler RouteHandler) RevokeAllSessions(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := rhandler.Provider.SessionService.RevokeAll(c, user.ID); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// this device's session went with the rest
+	cookies.DeleteAuthCookiesInResponse(c)
+	logger.InfoCtx(c, "Revoked all user sessions")
+	c.JSON(http.StatusNoContent, gin.H{})
+}
@@ -0,0 +1,174 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/model"
+	"net-go/server/backend/services/mocks"
+)
+
+func buildSessionsRouter(mockSessionService *mocks.MockSessionService, ctxUser *model.User, ctxSession *model.Session) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:              router,
+		SessionService: mockSessionService,
+	}
+	rhandler := NewRouteHandler(p)
+	// stand in for the AuthUser middleware
+	router.Use(func(c *gin.Context) {
+		c.Set("user", ctxUser)
+		c.Set("session", ctxSession)
+	})
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.GET("/api/accounts/sessions", rhandler.ListSessions)
+	router.DELETE("/api/accounts/sessions", rhandler.RevokeAllSessions)
+	router.DELETE("/api/accounts/sessions/:id", rhandler.RevokeSession)
+	return router
+}
+
+func TestListSessionsIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Lists the user's sessions, flagging the current one", func(t *testing.T) {
+		user := &model.User{Username: "tim"}
+		user.ID = 4
+		seen := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		sessions := []model.Session{
+			{ID: 2, UserID: 4, TokenHash: "secret", LastSeenAt: seen, UserAgent: "firefox", IP: "10.0.0.1"},
+			{ID: 1, UserID: 4, TokenHash: "secret", LastSeenAt: seen.Add(-time.Hour), UserAgent: "curl"},
+		}
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("List", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(sessions, nil)
+
+		router := buildSessionsRouter(mockSessionService, user, &sessions[1])
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/api/accounts/sessions", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		assert.NotContains(t, rr.Body.String(), "secret")
+		var resp struct {
+			Sessions []sessionInfo `json:"sessions"`
+		}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Len(t, resp.Sessions, 2)
+		assert.Equal(t, "firefox", resp.Sessions[0].UserAgent)
+		assert.Equal(t, "10.0.0.1", resp.Sessions[0].IP)
+		assert.False(t, resp.Sessions[0].Current)
+		assert.True(t, resp.Sessions[1].Current)
+		mockSessionService.AssertExpectations(t)
+	})
+}
+
+func TestRevokeSessionIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := &model.User{Username: "tim"}
+	user.ID = 4
+	current := &model.Session{ID: 1, UserID: 4}
+
+	t.Run("Revokes another device's session", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Revoke", mock.AnythingOfType("*gin.Context"), uint(4), uint(2)).
+			Return(nil)
+
+		router := buildSessionsRouter(mockSessionService, user, current)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodDelete, "/api/accounts/sessions/2", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 204, rr.Code)
+		// this device stays signed in
+		assert.Empty(t, rr.Header().Get("Set-Cookie"))
+		mockSessionService.AssertExpectations(t)
+	})
+
+	t.Run("Revoking the current session signs this device out", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Revoke", mock.AnythingOfType("*gin.Context"), uint(4), uint(1)).
+			Return(nil)
+
+		router := buildSessionsRouter(mockSessionService, user, current)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodDelete, "/api/accounts/sessions/1", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 204, rr.Code)
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=;")
+	})
+
+	t.Run("Unknown session is a 404", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Revoke", mock.AnythingOfType("*gin.Context"), uint(4), uint(9)).
+			Return(apperrors.NewNotFound("Session", "9"))
+
+		router := buildSessionsRouter(mockSessionService, user, current)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodDelete, "/api/accounts/sessions/9", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 404, rr.Code)
+	})
+
+	t.Run("Invalid session id", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+
+		router := buildSessionsRouter(mockSessionService, user, current)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodDelete, "/api/accounts/sessions/abc", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 400, rr.Code)
+		mockSessionService.AssertNotCalled(t, "Revoke")
+	})
+}
+
+func TestRevokeAllSessionsIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Revokes every session and signs this device out", func(t *testing.T) {
+		user := &model.User{Username: "tim"}
+		user.ID = 4
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("RevokeAll", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(nil)
+
+		router := buildSessionsRouter(mockSessionService, user, &model.Session{ID: 1, UserID: 4})
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodDelete, "/api/accounts/sessions", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 204, rr.Code)
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=;")
+		mockSessionService.AssertExpectations(t)
+	})
+}
@@ -2,10 +2,13 @@ package endpoints
 
 import (
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/handler/binding"
 	"net-go/server/backend/handler/cookies"
+	"net-go/server/backend/instrumentation"
 	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
 	"net/http"
 )
 
@@ -23,28 +26,73 @@ func (rhandler RouteHandler) Signin(c *gin.Context) {
 
 	user, err := rhandler.Provider.UserService.Signin(c, req.Username, req.Password)
 	if err != nil {
-		logger.Debug("Failed to signin user: %v", err)
+		logger.DebugCtx(c, "Failed to signin user", zap.Error(err))
+		instrumentation.SigninFailures.WithLabelValues(signinFailureReason(err)).Inc()
+		if retryAfter := apperrors.RetryAfterHeader(err); retryAfter != "" {
+			c.Header("Retry-After", retryAfter)
+		}
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	// make sure we have an updated sess token
-	err = rhandler.Provider.UserService.UpdateSessionToken(c, user)
-	if err != nil {
-		logger.Warn("Failed to update user session: %v", err.Error())
-		c.JSON(apperrors.Status(err), gin.H{
-			"error": err.Error(),
+	// the password isn't enough for 2FA users; they get a ticket to trade
+	// for a session along with their code (see CompleteTwoFactorSignin)
+	if user.TwoFactorEnabled() {
+		ticket, err := rhandler.Provider.TwoFactorService.StartChallenge(c, user.ID)
+		if err != nil {
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"twoFactorRequired": true,
+			"ticket":            ticket,
 		})
 		return
 	}
 
-	// set auth cookie to preserve session
-	cookies.SetAuthCookiesInResponse(*user, c)
+	rhandler.startSession(c, user)
+}
+
+// signs `user` in on this device with a new session, and responds with who they are
+func (rhandler RouteHandler) startSession(c *gin.Context, user *model.User) {
+	if ok := rhandler.createSession(c, user); !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"uid":      user.ID,
 		"username": user.Username,
 	})
 }
+
+// signs `user` in on this device with a new session; responds with the error on failure
+func (rhandler RouteHandler) createSession(c *gin.Context, user *model.User) bool {
+	token, _, err := rhandler.Provider.SessionService.Create(c, user.ID, sessionClient(c))
+	if err != nil {
+		logger.WarnCtx(c, "Failed to create user session", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return false
+	}
+
+	// set auth cookie to preserve session
+	cookies.SetAuthCookiesInResponse(*user, token, c)
+	return true
+}
+
+// metric label for a failed signin; bad usernames and passwords are both a 404
+func signinFailureReason(err error) string {
+	switch apperrors.Status(err) {
+	case http.StatusNotFound:
+		return "invalid_credentials"
+	case http.StatusTooManyRequests:
+		return "locked_out"
+	default:
+		return "error"
+	}
+}
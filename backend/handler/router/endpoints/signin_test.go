@@ -3,26 +3,31 @@ package endpoints
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/instrumentation"
 	"net-go/server/backend/model"
 	"net-go/server/backend/services/mocks"
 )
 
-func buildSigninRouter(mockUserService *mocks.MockUserService) *gin.Engine {
+func buildSigninRouter(mockUserService *mocks.MockUserService, mockSessionService *mocks.MockSessionService) *gin.Engine {
 	router := gin.Default()
 
 	p := provider.Provider{
-		R:           router,
-		UserService: mockUserService,
+		R:              router,
+		UserService:    mockUserService,
+		SessionService: mockSessionService,
 	}
 	rhandler := NewRouteHandler(p)
 
@@ -49,7 +54,7 @@ func TestSigninIntegration(t *testing.T) {
 		// response recorder for saving http resps
 		rr := httptest.NewRecorder()
 
-		router := buildSigninRouter(mockUserService)
+		router := buildSigninRouter(mockUserService, new(mocks.MockSessionService))
 
 		// create json req w/ no password field
 		reqBody, err := json.Marshal(gin.H{
@@ -82,7 +87,7 @@ func TestSigninIntegration(t *testing.T) {
 		// response recorder for saving http resps
 		rr := httptest.NewRecorder()
 
-		router := buildSigninRouter(mockUserService)
+		router := buildSigninRouter(mockUserService, new(mocks.MockSessionService))
 
 		// create json req w/ no password field
 		reqBody, err := json.Marshal(gin.H{
@@ -116,7 +121,7 @@ func TestSigninIntegration(t *testing.T) {
 		// response recorder for saving http resps
 		rr := httptest.NewRecorder()
 
-		router := buildSigninRouter(mockUserService)
+		router := buildSigninRouter(mockUserService, new(mocks.MockSessionService))
 
 		// create json req w/ no password field
 		reqBody, err := json.Marshal(gin.H{
@@ -154,7 +159,7 @@ func TestSigninIntegration(t *testing.T) {
 		// a response recorder for getting written http response
 		rr := httptest.NewRecorder()
 
-		router := buildSigninRouter(mockUserService)
+		router := buildSigninRouter(mockUserService, new(mocks.MockSessionService))
 
 		reqBody, err := json.Marshal(gin.H{
 			"username": u.Username,
@@ -168,9 +173,48 @@ func TestSigninIntegration(t *testing.T) {
 
 		request.Header.Set("Content-Type", "application/json")
 
+		failures := testutil.ToFloat64(instrumentation.SigninFailures.WithLabelValues("error"))
+
 		router.ServeHTTP(rr, request)
 
 		assert.Equal(t, 409, rr.Code)
+		assert.Equal(t, failures+1, testutil.ToFloat64(instrumentation.SigninFailures.WithLabelValues("error")))
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("locked out", func(t *testing.T) {
+		u := &model.User{
+			Username: "bob",
+			Password: "avalidpassword",
+		}
+
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On(
+				"Signin",
+				mock.AnythingOfType("*gin.Context"),
+				u.Username,
+				u.Password,
+			).Return(nil, apperrors.NewTooManyRequests(90*time.Second))
+
+		rr := httptest.NewRecorder()
+		router := buildSigninRouter(mockUserService, new(mocks.MockSessionService))
+
+		reqBody, err := json.Marshal(gin.H{
+			"username": u.Username,
+			"password": u.Password,
+		})
+		assert.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, "/api/accounts/signin", bytes.NewBuffer(reqBody))
+		assert.NoError(t, err)
+		failures := testutil.ToFloat64(instrumentation.SigninFailures.WithLabelValues("locked_out"))
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "90", rr.Header().Get("Retry-After"))
+		assert.Equal(t, failures+1, testutil.ToFloat64(instrumentation.SigninFailures.WithLabelValues("locked_out")))
 		mockUserService.AssertExpectations(t)
 	})
 
@@ -190,17 +234,19 @@ func TestSigninIntegration(t *testing.T) {
 				u.Username,
 				u.Password,
 			).Return(u, nil)
-		mockUserService.
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
 			On(
-				"UpdateSessionToken",
+				"Create",
 				mock.AnythingOfType("*gin.Context"),
-				u,
-			).Return(nil)
+				uid,
+				mock.AnythingOfType("model.SessionClient"),
+			).Return("sess-token", &model.Session{ID: 1, UserID: uid}, nil)
 
 		// a response recorder for getting written http response
 		rr := httptest.NewRecorder()
 
-		router := buildSigninRouter(mockUserService)
+		router := buildSigninRouter(mockUserService, mockSessionService)
 
 		reqBody, err := json.Marshal(gin.H{
 			"username": u.Username,
@@ -226,7 +272,9 @@ func TestSigninIntegration(t *testing.T) {
 		// validate response data
 		assert.Equal(t, 200, rr.Code)
 		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), fmt.Sprintf("ngo_auth=%d%%3A%%3Asess-token", uid))
 
 		mockUserService.AssertExpectations(t)
+		mockSessionService.AssertExpectations(t)
 	})
 }
@@ -1,17 +1,88 @@
 package endpoints
 
 import (
+	"net-go/server/backend/apperrors"
 	"net-go/server/backend/handler/cookies"
 	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
+type signoutQuery struct {
+	// sign out every device of the user, not just this one
+	Everywhere bool `form:"everywhere"`
+}
+
+// POST /accounts/signout
 func (rhandler RouteHandler) Signout(c *gin.Context) {
+	var query signoutQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		badReqErr := apperrors.NewBadRequest("Invalid query parameter for everywhere")
+		c.JSON(badReqErr.Status(), gin.H{
+			"error": badReqErr.Error(),
+		})
+		return
+	}
+
+	if err := rhandler.revokeCookieSession(c, query.Everywhere); err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	cookies.DeleteAuthCookiesInResponse(c)
+	c.JSON(http.StatusOK, gin.H{
+		"signedOut":  true,
+		"everywhere": query.Everywhere,
+	})
+}
+
+/**
+ * Revokes the session in the request's auth cookie, or all of its user's
+ * sessions if `everywhere`. A missing or dead cookie has no session left
+ * to revoke, so it's not an error; signing out is always allowed.
+ */
+func (rhandler RouteHandler) revokeCookieSession(c *gin.Context, everywhere bool) error {
+	session := rhandler.sessionFromCookie(c)
+	if session == nil {
+		logger.DebugCtx(c, "Signout without a live session")
+		return nil
+	}
+
+	if everywhere {
+		if err := rhandler.Provider.SessionService.RevokeAll(c, session.UserID); err != nil {
+			return err
+		}
+		logger.InfoCtx(c, "User signed out everywhere", zap.Uint("user_id", session.UserID))
+		return nil
+	}
+
+	err := rhandler.Provider.SessionService.Revoke(c, session.UserID, session.ID)
+	// a concurrent signout may have got there first
+	if err != nil && apperrors.Status(err) != http.StatusNotFound {
+		return err
+	}
+	logger.InfoCtx(c, "User signed out", zap.Uint("user_id", session.UserID))
+	return nil
+}
 
-	// redirect to root route
-	c.Request.URL.Path = "/"
-	rhandler.Provider.R.HandleContext(c)
-	logger.Info("Manual user signout")
+// the live session the request's auth cookie is for, if any
+func (rhandler RouteHandler) sessionFromCookie(c *gin.Context) *model.Session {
+	cookie, err := cookies.GetAuthCookieFromRequest(c)
+	if err != nil {
+		return nil
+	}
+	userId, sessToken, err := cookies.DeconstructAuthCookie(cookie)
+	if err != nil {
+		return nil
+	}
+	session, err := rhandler.Provider.SessionService.Authenticate(c, uint(userId), sessToken)
+	if err != nil {
+		return nil
+	}
+	return session
 }
@@ -0,0 +1,141 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/cookies"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/model"
+	"net-go/server/backend/services/mocks"
+)
+
+func buildSignoutRouter(mockSessionService *mocks.MockSessionService) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:              router,
+		SessionService: mockSessionService,
+	}
+	rhandler := NewRouteHandler(p)
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.POST("/api/accounts/signout", rhandler.Signout)
+	return router
+}
+
+// a signed auth cookie, as set on signin
+func signedAuthCookie(user model.User, sessionToken string) *http.Cookie {
+	rr := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rr)
+	cookies.SetAuthCookiesInResponse(user, sessionToken, c)
+	return rr.Result().Cookies()[0]
+}
+
+func TestSignoutIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := model.User{Username: "tim"}
+	user.ID = 4
+	session := &model.Session{ID: 2, UserID: 4}
+
+	t.Run("Revokes the session and clears the cookies", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Authenticate", mock.AnythingOfType("*gin.Context"), uint(4), "token").
+			Return(session, nil)
+		mockSessionService.
+			On("Revoke", mock.AnythingOfType("*gin.Context"), uint(4), uint(2)).
+			Return(nil)
+
+		router := buildSignoutRouter(mockSessionService)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/api/accounts/signout", nil)
+		assert.NoError(t, err)
+		request.AddCookie(signedAuthCookie(user, "token"))
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		var resp map[string]bool
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.True(t, resp["signedOut"])
+		assert.False(t, resp["everywhere"])
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=;")
+		mockSessionService.AssertExpectations(t)
+		mockSessionService.AssertNotCalled(t, "RevokeAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Signs out everywhere", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Authenticate", mock.AnythingOfType("*gin.Context"), uint(4), "token").
+			Return(session, nil)
+		mockSessionService.
+			On("RevokeAll", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(nil)
+
+		router := buildSignoutRouter(mockSessionService)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/api/accounts/signout?everywhere=true", nil)
+		assert.NoError(t, err)
+		request.AddCookie(signedAuthCookie(user, "token"))
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"everywhere":true`)
+		mockSessionService.AssertExpectations(t)
+	})
+
+	t.Run("Dead or missing cookies are still signed out", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Authenticate", mock.AnythingOfType("*gin.Context"), uint(4), "token").
+			Return(nil, apperrors.NewUnauthorized())
+
+		router := buildSignoutRouter(mockSessionService)
+		for _, cookie := range []*http.Cookie{signedAuthCookie(user, "token"), nil} {
+			rr := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodPost, "/api/accounts/signout", nil)
+			assert.NoError(t, err)
+			if cookie != nil {
+				request.AddCookie(cookie)
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, 200, rr.Code)
+			assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=;")
+		}
+		mockSessionService.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failing to revoke keeps the cookies", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Authenticate", mock.AnythingOfType("*gin.Context"), uint(4), "token").
+			Return(session, nil)
+		mockSessionService.
+			On("Revoke", mock.AnythingOfType("*gin.Context"), uint(4), uint(2)).
+			Return(apperrors.NewInternal())
+
+		router := buildSignoutRouter(mockSessionService)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/api/accounts/signout", nil)
+		assert.NoError(t, err)
+		request.AddCookie(signedAuthCookie(user, "token"))
+
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 500, rr.Code)
+		assert.Empty(t, rr.Header().Get("Set-Cookie"))
+	})
+
+}
@@ -2,6 +2,7 @@ package endpoints
 
 import (
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/handler/binding"
 	"net-go/server/backend/handler/cookies"
@@ -25,24 +26,24 @@ func (rhandler RouteHandler) Signup(c *gin.Context) {
 
 	user, err := rhandler.Provider.UserService.Signup(c, req.Username, req.Password)
 	if err != nil {
-		logger.Warn("Failed to sign up user: %v", err.Error())
+		logger.WarnCtx(c, "Failed to sign up user", zap.Error(err))
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	// make sure we have an updated sess token
-	err = rhandler.Provider.UserService.UpdateSessionToken(c, user)
+	// sign this device in with a new session
+	token, _, err := rhandler.Provider.SessionService.Create(c, user.ID, sessionClient(c))
 	if err != nil {
-		logger.Error("Failed to generate user session: %v", err.Error())
+		logger.ErrorCtx(c, "Failed to create user session", zap.Error(err))
 		c.JSON(apperrors.Status(err), gin.H{
 			"error": err.Error(),
 		})
 		return
 	}
 
-	cookies.SetAuthCookiesInResponse(*user, c)
+	cookies.SetAuthCookiesInResponse(*user, token, c)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"uid":      user.ID,
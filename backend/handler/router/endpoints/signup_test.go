@@ -3,6 +3,7 @@ package endpoints
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
@@ -17,12 +18,13 @@ import (
 	"net-go/server/backend/services/mocks"
 )
 
-func buildSignupRouter(mockUserService *mocks.MockUserService) *gin.Engine {
+func buildSignupRouter(mockUserService *mocks.MockUserService, mockSessionService *mocks.MockSessionService) *gin.Engine {
 	router := gin.Default()
 
 	p := provider.Provider{
-		R:           router,
-		UserService: mockUserService,
+		R:              router,
+		UserService:    mockUserService,
+		SessionService: mockSessionService,
 	}
 	rhandler := NewRouteHandler(p)
 
@@ -49,7 +51,7 @@ func TestSignupIntegration(t *testing.T) {
 		// response recorder for saving http resps
 		rr := httptest.NewRecorder()
 
-		router := buildSignupRouter(mockUserService)
+		router := buildSignupRouter(mockUserService, new(mocks.MockSessionService))
 
 		// create json req w/ no password field
 		reqBody, err := json.Marshal(gin.H{
@@ -82,7 +84,7 @@ func TestSignupIntegration(t *testing.T) {
 		// response recorder for saving http resps
 		rr := httptest.NewRecorder()
 
-		router := buildSignupRouter(mockUserService)
+		router := buildSignupRouter(mockUserService, new(mocks.MockSessionService))
 
 		// create json req w/ no password field
 		reqBody, err := json.Marshal(gin.H{
@@ -116,7 +118,7 @@ func TestSignupIntegration(t *testing.T) {
 		// response recorder for saving http resps
 		rr := httptest.NewRecorder()
 
-		router := buildSignupRouter(mockUserService)
+		router := buildSignupRouter(mockUserService, new(mocks.MockSessionService))
 
 		// create json req w/ no password field
 		reqBody, err := json.Marshal(gin.H{
@@ -154,7 +156,7 @@ func TestSignupIntegration(t *testing.T) {
 		// a response recorder for getting written http response
 		rr := httptest.NewRecorder()
 
-		router := buildSignupRouter(mockUserService)
+		router := buildSignupRouter(mockUserService, new(mocks.MockSessionService))
 
 		reqBody, err := json.Marshal(gin.H{
 			"username": u.Username,
@@ -190,17 +192,19 @@ func TestSignupIntegration(t *testing.T) {
 				u.Username,
 				u.Password,
 			).Return(u, nil)
-		mockUserService.
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
 			On(
-				"UpdateSessionToken",
+				"Create",
 				mock.AnythingOfType("*gin.Context"),
-				u,
-			).Return(nil)
+				uid,
+				mock.AnythingOfType("model.SessionClient"),
+			).Return("sess-token", &model.Session{ID: 1, UserID: uid}, nil)
 
 		// a response recorder for getting written http response
 		rr := httptest.NewRecorder()
 
-		router := buildSignupRouter(mockUserService)
+		router := buildSignupRouter(mockUserService, mockSessionService)
 
 		reqBody, err := json.Marshal(gin.H{
 			"username": u.Username,
@@ -225,7 +229,9 @@ func TestSignupIntegration(t *testing.T) {
 
 		assert.Equal(t, 201, rr.Code)
 		assert.Equal(t, respBody, rr.Body.Bytes())
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), fmt.Sprintf("ngo_auth=%d%%3A%%3Asess-token", uid))
 
 		mockUserService.AssertExpectations(t)
+		mockSessionService.AssertExpectations(t)
 	})
 }
@@ -0,0 +1,165 @@
+package endpoints
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/binding"
+	"net-go/server/backend/logger"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// a TOTP code from the user's authenticator app, or one of their recovery codes
+type twoFactorCodeReq struct {
+	Code string `json:"code" binding:"required,lte=32"`
+}
+
+type twoFactorSigninReq struct {
+	Ticket string `json:"ticket" binding:"required,lte=64"`
+	Code   string `json:"code" binding:"required,lte=32"`
+}
+
+/**
+ * POST /accounts/signin/2fa
+ * Second step of signing in a user with 2FA on: trades the ticket from
+ * Signin and a code for a session.
+ */
+func (rhandler RouteHandler) CompleteTwoFactorSignin(c *gin.Context) {
+	var req twoFactorSigninReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	user, err := rhandler.Provider.TwoFactorService.CompleteChallenge(c, req.Ticket, req.Code)
+	if err != nil {
+		logger.DebugCtx(c, "Failed to complete 2FA signin", zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	rhandler.startSession(c, user)
+}
+
+// GET /accounts/2fa
+func (rhandler RouteHandler) GetTwoFactorStatus(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	status, err := rhandler.Provider.TwoFactorService.Status(c, user.ID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":           status.Enabled,
+		"recoveryCodesLeft": status.RecoveryCodesLeft,
+	})
+}
+
+/**
+ * POST /accounts/2fa
+ * Starts enrolling: responds with the secret to add to an authenticator
+ * app. 2FA is only on once a code from the app is confirmed.
+ */
+func (rhandler RouteHandler) BeginTwoFactorEnrollment(c *gin.Context) {
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	enrollment, err := rhandler.Provider.TwoFactorService.BeginEnrollment(c, user.ID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"secret": enrollment.Secret,
+		"uri":    enrollment.URI,
+	})
+}
+
+// POST /accounts/2fa/confirm
+func (rhandler RouteHandler) ConfirmTwoFactorEnrollment(c *gin.Context) {
+	rhandler.withTwoFactorCode(c, func(userId uint, code string) {
+		recoveryCodes, err := rhandler.Provider.TwoFactorService.ConfirmEnrollment(c, userId, code)
+		if err != nil {
+			logger.DebugCtx(c, "Failed to confirm 2FA enrollment", zap.Error(err))
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"recoveryCodes": recoveryCodes,
+		})
+	})
+}
+
+// POST /accounts/2fa/disable
+func (rhandler RouteHandler) DisableTwoFactor(c *gin.Context) {
+	rhandler.withTwoFactorCode(c, func(userId uint, code string) {
+		if err := rhandler.Provider.TwoFactorService.Disable(c, userId, code); err != nil {
+			logger.DebugCtx(c, "Failed to disable 2FA", zap.Error(err))
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// POST /accounts/2fa/recovery-codes
+func (rhandler RouteHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	rhandler.withTwoFactorCode(c, func(userId uint, code string) {
+		recoveryCodes, err := rhandler.Provider.TwoFactorService.RegenerateRecoveryCodes(c, userId, code)
+		if err != nil {
+			logger.DebugCtx(c, "Failed to regenerate recovery codes", zap.Error(err))
+			c.JSON(apperrors.Status(err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"recoveryCodes": recoveryCodes,
+		})
+	})
+}
+
+// binds a twoFactorCodeReq and gets the authed user, then calls `handle` with them
+func (rhandler RouteHandler) withTwoFactorCode(c *gin.Context, handle func(userId uint, code string)) {
+	var req twoFactorCodeReq
+	if ok := binding.BindData(c, &req); !ok {
+		return // BindData handles server response on fail
+	}
+
+	user, err := getUserFromCtx(c)
+	if err != nil {
+		logger.DebugCtx(c, "Expected to have authed user from middleware, but found none")
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	handle(user.ID, req.Code)
+}
@@ -0,0 +1,197 @@
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/model"
+	"net-go/server/backend/services/mocks"
+)
+
+func buildTwoFactorRouter(mockUserService *mocks.MockUserService, mockSessionService *mocks.MockSessionService, mockTwoFactorService *mocks.MockTwoFactorService, ctxUser *model.User) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:                router,
+		UserService:      mockUserService,
+		SessionService:   mockSessionService,
+		TwoFactorService: mockTwoFactorService,
+	}
+	rhandler := NewRouteHandler(p)
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.POST("/api/accounts/signin", rhandler.Signin)
+	router.POST("/api/accounts/signin/2fa", rhandler.CompleteTwoFactorSignin)
+	authed := router.Group("", func(c *gin.Context) {
+		c.Set("user", ctxUser)
+	})
+	authed.GET("/api/accounts/2fa", rhandler.GetTwoFactorStatus)
+	authed.POST("/api/accounts/2fa", rhandler.BeginTwoFactorEnrollment)
+	authed.POST("/api/accounts/2fa/confirm", rhandler.ConfirmTwoFactorEnrollment)
+	authed.POST("/api/accounts/2fa/disable", rhandler.DisableTwoFactor)
+	authed.POST("/api/accounts/2fa/recovery-codes", rhandler.RegenerateRecoveryCodes)
+	return router
+}
+
+// user "tim" (id 4) with 2FA on
+func newTwoFactorUser() *model.User {
+	secret, enabledAt := "SECRET", time.Now()
+	user := &model.User{Username: "tim", TOTPSecret: &secret, TOTPEnabledAt: &enabledAt}
+	user.ID = 4
+	return user
+}
+
+func TestTwoFactorSigninIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("The password alone gets a ticket, not a session", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("Signin", mock.AnythingOfType("*gin.Context"), "tim", "password1").
+			Return(newTwoFactorUser(), nil)
+		mockSessionService := new(mocks.MockSessionService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		mockTwoFactorService.
+			On("StartChallenge", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return("ticket", nil)
+
+		router := buildTwoFactorRouter(mockUserService, mockSessionService, mockTwoFactorService, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/signin", gin.H{
+			"username": "tim",
+			"password": "password1",
+		}))
+
+		assert.Equal(t, 200, rr.Code)
+		assert.JSONEq(t, `{"twoFactorRequired":true,"ticket":"ticket"}`, rr.Body.String())
+		assert.Empty(t, rr.Header().Get("Set-Cookie"))
+		mockSessionService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("The ticket and a code get a session", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On("Create", mock.AnythingOfType("*gin.Context"), uint(4), mock.AnythingOfType("model.SessionClient")).
+			Return("session-token", &model.Session{ID: 9, UserID: 4}, nil)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		mockTwoFactorService.
+			On("CompleteChallenge", mock.AnythingOfType("*gin.Context"), "ticket", "123456").
+			Return(newTwoFactorUser(), nil)
+
+		router := buildTwoFactorRouter(new(mocks.MockUserService), mockSessionService, mockTwoFactorService, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/signin/2fa", gin.H{
+			"ticket": "ticket",
+			"code":   "123456",
+		}))
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=4%3A%3Asession-token")
+		assert.JSONEq(t, `{"uid":4,"username":"tim"}`, rr.Body.String())
+	})
+
+	t.Run("Wrong code", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		mockTwoFactorService.
+			On("CompleteChallenge", mock.AnythingOfType("*gin.Context"), "ticket", "000000").
+			Return(nil, apperrors.NewBadRequest("Invalid two-factor code"))
+
+		router := buildTwoFactorRouter(new(mocks.MockUserService), mockSessionService, mockTwoFactorService, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/signin/2fa", gin.H{
+			"ticket": "ticket",
+			"code":   "000000",
+		}))
+
+		assert.Equal(t, 400, rr.Code)
+		assert.Empty(t, rr.Header().Get("Set-Cookie"))
+		mockSessionService.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestTwoFactorEnrollmentIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	user := &model.User{Username: "tim"}
+	user.ID = 4
+
+	t.Run("Status", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		mockTwoFactorService.
+			On("Status", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(&model.TwoFactorStatus{Enabled: true, RecoveryCodesLeft: 7}, nil)
+
+		router := buildTwoFactorRouter(new(mocks.MockUserService), new(mocks.MockSessionService), mockTwoFactorService, user)
+		rr := httptest.NewRecorder()
+		request, _ := http.NewRequest(http.MethodGet, "/api/accounts/2fa", nil)
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		assert.JSONEq(t, `{"enabled":true,"recoveryCodesLeft":7}`, rr.Body.String())
+	})
+
+	t.Run("Begins with a secret and otpauth URI", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		mockTwoFactorService.
+			On("BeginEnrollment", mock.AnythingOfType("*gin.Context"), uint(4)).
+			Return(&model.TwoFactorEnrollment{Secret: "ABC", URI: "otpauth://totp/net-go:tim?secret=ABC"}, nil)
+
+		router := buildTwoFactorRouter(new(mocks.MockUserService), new(mocks.MockSessionService), mockTwoFactorService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/2fa", gin.H{}))
+
+		assert.Equal(t, 200, rr.Code)
+		assert.JSONEq(t, `{"secret":"ABC","uri":"otpauth://totp/net-go:tim?secret=ABC"}`, rr.Body.String())
+	})
+
+	t.Run("Confirming returns the recovery codes", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		mockTwoFactorService.
+			On("ConfirmEnrollment", mock.AnythingOfType("*gin.Context"), uint(4), "123456").
+			Return([]string{"aaaa-bbbb-cccc"}, nil)
+
+		router := buildTwoFactorRouter(new(mocks.MockUserService), new(mocks.MockSessionService), mockTwoFactorService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/2fa/confirm", gin.H{
+			"code": "123456",
+		}))
+
+		assert.Equal(t, 200, rr.Code)
+		assert.JSONEq(t, `{"recoveryCodes":["aaaa-bbbb-cccc"]}`, rr.Body.String())
+	})
+
+	t.Run("Code required", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+
+		router := buildTwoFactorRouter(new(mocks.MockUserService), new(mocks.MockSessionService), mockTwoFactorService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/2fa/disable", gin.H{}))
+
+		assert.Equal(t, 400, rr.Code)
+		mockTwoFactorService.AssertNotCalled(t, "Disable")
+	})
+
+	t.Run("Disables", func(t *testing.T) {
+		mockTwoFactorService := new(mocks.MockTwoFactorService)
+		mockTwoFactorService.
+			On("Disable", mock.AnythingOfType("*gin.Context"), uint(4), "123456").
+			Return(nil)
+
+		router := buildTwoFactorRouter(new(mocks.MockUserService), new(mocks.MockSessionService), mockTwoFactorService, user)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, jsonRequest(t, http.MethodPost, "/api/accounts/2fa/disable", gin.H{
+			"code": "123456",
+		}))
+
+		assert.Equal(t, 204, rr.Code)
+		mockTwoFactorService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,66 @@
+package endpoints
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type userUri struct {
+	Username string `uri:"username" binding:"required,gte=1,lte=30"`
+}
+
+// public facing user data; never include password or session data here!
+type userProfile struct {
+	ID        uint      `json:"uid"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func newUserProfile(user model.User) userProfile {
+	return userProfile{
+		ID:        user.ID,
+		Username:  user.Username,
+		CreatedAt: user.CreatedAt,
+	}
+}
+
+// GET /:username
+func (rhandler RouteHandler) GetUserProfile(c *gin.Context) {
+	var uriParams userUri
+	if err := c.ShouldBindUri(&uriParams); err != nil {
+		logger.WarnCtx(c, "Failed to parse user URI params", zap.Error(err))
+		badReqErr := apperrors.NewBadRequest("Invalid URI parameter for username")
+		c.JSON(badReqErr.Status(), gin.H{
+			"error": badReqErr.Error(),
+		})
+		return
+	}
+
+	user, err := rhandler.Provider.UserService.FindByUsername(c, uriParams.Username)
+	if err != nil {
+		logger.DebugCtx(c, "Error fetching user profile", zap.String("username", uriParams.Username), zap.Error(err))
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	stats, err := rhandler.Provider.GameService.GetUserStats(c, user.ID)
+	if err != nil {
+		c.JSON(apperrors.Status(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user":  newUserProfile(*user),
+		"stats": stats,
+	})
+}
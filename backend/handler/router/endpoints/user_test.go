@@ -0,0 +1,149 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/model"
+	"net-go/server/backend/model/types"
+	"net-go/server/backend/services/mocks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func buildUserRouter(mockGameService *mocks.MockGameService, mockUserService *mocks.MockUserService) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:           router,
+		GameService: mockGameService,
+		UserService: mockUserService,
+	}
+	rhandler := NewRouteHandler(p)
+
+	// keep this in sync w/ route defintion in router.go
+	// (couldnt use SetRouter directly w/o import cycle)
+	router.GET("/api/users/:username", rhandler.GetUserProfile)
+	return router
+}
+
+func TestGetUserProfileIntegration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("success", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+			Password: "pwnd",
+		}
+		user.ID = 123
+		stats := model.UserStats{
+			GamesPlayed: 1,
+			Record:      model.WinLossRecord{Wins: 1},
+			ByColor: map[types.ColorChoice]model.WinLossRecord{
+				types.Black: {Wins: 1},
+			},
+			ByBoardSize: map[types.BoardSize]model.WinLossRecord{
+				types.Full: {Wins: 1},
+			},
+			WinsByScore: 1,
+			CurrentStreak: model.Streak{
+				Result: model.WinStreak,
+				Length: 1,
+			},
+		}
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("FindByUsername", mock.AnythingOfType("*gin.Context"), "tim").
+			Return(&user, nil)
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On("GetUserStats", mock.AnythingOfType("*gin.Context"), uint(123)).
+			Return(&stats, nil)
+
+		rr := httptest.NewRecorder()
+		router := buildUserRouter(mockGameService, mockUserService)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/users/tim", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(gin.H{
+			"user":  newUserProfile(user),
+			"stats": stats,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, expectedResp, rr.Body.Bytes())
+		assert.NotContains(t, rr.Body.String(), "pwnd")
+
+		mockUserService.AssertExpectations(t)
+		mockGameService.AssertExpectations(t)
+	})
+	t.Run("404 returned when user isn't found", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("FindByUsername", mock.AnythingOfType("*gin.Context"), "nobody").
+			Return(nil, apperrors.NewNotFound("User", "nobody"))
+		mockGameService := new(mocks.MockGameService)
+
+		rr := httptest.NewRecorder()
+		router := buildUserRouter(mockGameService, mockUserService)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/users/nobody", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 404, rr.Code)
+		mockGameService.AssertNotCalled(t, "GetUserStats")
+	})
+	t.Run("stats failure returns error", func(t *testing.T) {
+		user := model.User{
+			Username: "tim",
+		}
+		user.ID = 123
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("FindByUsername", mock.AnythingOfType("*gin.Context"), "tim").
+			Return(&user, nil)
+		mockGameService := new(mocks.MockGameService)
+		mockGameService.
+			On("GetUserStats", mock.AnythingOfType("*gin.Context"), uint(123)).
+			Return(nil, apperrors.NewInternal())
+
+		rr := httptest.NewRecorder()
+		router := buildUserRouter(mockGameService, mockUserService)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/users/tim", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 500, rr.Code)
+		mockGameService.AssertExpectations(t)
+	})
+	t.Run("non-apperror from user lookup is internal", func(t *testing.T) {
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("FindByUsername", mock.AnythingOfType("*gin.Context"), "tim").
+			Return(nil, errors.New("db exploded"))
+
+		rr := httptest.NewRecorder()
+		router := buildUserRouter(new(mocks.MockGameService), mockUserService)
+
+		req, err := http.NewRequest(http.MethodGet, "/api/users/tim", nil)
+		assert.NoError(t, err)
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 500, rr.Code)
+	})
+}
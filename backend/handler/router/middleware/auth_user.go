@@ -4,13 +4,26 @@ import (
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/handler/cookies"
 	"net-go/server/backend/handler/router/endpoints"
+	"net-go/server/backend/logger"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-// wrapped for typing
+/**
+ * Authenticates the request by its auth cookie, or by the personal API
+ * token in its `Authorization: Bearer` header, which takes precedence.
+ * Token requests get no session, and are limited to the token's scopes
+ * (see RequireScope and RequireSession).
+ */
 func AuthUser(handler endpoints.RouteHandler) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if token, ok := bearerToken(c); ok {
+			authAPIToken(c, handler, token)
+			return
+		}
+
 		// extract auth cookie
 		cookie, err := cookies.GetAuthCookieFromRequest(c)
 		if err != nil {
@@ -24,6 +37,7 @@ func AuthUser(handler endpoints.RouteHandler) gin.HandlerFunc {
 
 		userId, sessToken, err := cookies.DeconstructAuthCookie(cookie)
 		if err != nil {
+			logger.DebugCtx(c, "Rejected malformed auth cookie", zap.Error(err))
 			// del expired/bad auth cookie
 			cookies.DeleteAuthCookiesInResponse(c)
 
@@ -34,9 +48,20 @@ func AuthUser(handler endpoints.RouteHandler) gin.HandlerFunc {
 			return
 		}
 
-		// get/auth the user
+		// check the session is still live, then get the user it's for
+		session, err := handler.Provider.SessionService.Authenticate(c, uint(userId), sessToken)
+		if err != nil {
+			// del expired/revoked auth cookie
+			cookies.DeleteAuthCookiesInResponse(c)
+
+			unauthErr := apperrors.NewUnauthorized()
+			c.AbortWithStatusJSON(unauthErr.Status(), gin.H{
+				"error": unauthErr.Error(),
+			})
+			return
+		}
 		user, err := handler.Provider.UserService.Get(c, uint(userId))
-		if err != nil || sessToken != user.SessionToken {
+		if err != nil {
 			// del expired/bad auth cookie
 			cookies.DeleteAuthCookiesInResponse(c)
 
@@ -47,10 +72,58 @@ func AuthUser(handler endpoints.RouteHandler) gin.HandlerFunc {
 			return
 		}
 
-		// save the user for later use
+		// swap a cookie from before signing for a signed one
+		if !cookies.IsSignedAuthCookie(cookie) {
+			cookies.SetAuthCookiesInResponse(*user, sessToken, c)
+		}
+
+		// save the user and their session for later use
 		c.Set("user", user)
+		c.Set("session", session)
+		// and tag the request's logs with them
+		c.Request = c.Request.WithContext(
+			logger.WithFields(c.Request.Context(), zap.Uint("user_id", user.ID)),
+		)
 
 		// proceed to next req handler
 		c.Next()
 	}
 }
+
+// the token in the request's `Authorization: Bearer` header, if it has one
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func authAPIToken(c *gin.Context, handler endpoints.RouteHandler, token string) {
+	apiToken, err := handler.Provider.APITokenService.Authenticate(c, token)
+	if err != nil {
+		logger.DebugCtx(c, "Rejected API token", zap.Error(err))
+		unauthErr := apperrors.NewUnauthorized()
+		c.AbortWithStatusJSON(unauthErr.Status(), gin.H{
+			"error": unauthErr.Error(),
+		})
+		return
+	}
+	user, err := handler.Provider.UserService.Get(c, apiToken.UserID)
+	if err != nil {
+		unauthErr := apperrors.NewUnauthorized()
+		c.AbortWithStatusJSON(unauthErr.Status(), gin.H{
+			"error": unauthErr.Error(),
+		})
+		return
+	}
+
+	c.Set("user", user)
+	c.Set("apiToken", apiToken)
+	c.Request = c.Request.WithContext(
+		logger.WithFields(c.Request.Context(),
+			zap.Uint("user_id", user.ID), zap.Uint("api_token_id", apiToken.ID)),
+	)
+
+	c.Next()
+}
@@ -4,8 +4,11 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/handler/cookies"
 	"net-go/server/backend/handler/provider"
 	"net-go/server/backend/handler/router/endpoints"
 	"net-go/server/backend/model"
@@ -16,34 +19,52 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-func buildRouterWithMiddleware(mockUserService *mocks.MockUserService) *gin.Engine {
+func buildRouterWithMiddleware(mockUserService *mocks.MockUserService, mockSessionService *mocks.MockSessionService) *gin.Engine {
 	router := gin.Default()
 
 	// use the middleware under test
 	p := provider.Provider{
-		R:           router,
-		UserService: mockUserService,
+		R:              router,
+		UserService:    mockUserService,
+		SessionService: mockSessionService,
 	}
 	rhandler := endpoints.NewRouteHandler(p)
 	router.Use(AuthUser(rhandler))
 
 	// add a dummy endpoint for us to test against in isolation from other code
 	router.GET("/test", func(c *gin.Context) {
-		c.String(http.StatusOK, "success")
+		session := c.MustGet("session").(*model.Session)
+		c.String(http.StatusOK, "success %d", session.ID)
 	})
 	return router
 }
 
+// a signed auth cookie, as set on signin
+func signedAuthCookie(user model.User, sessionToken string) *http.Cookie {
+	rr := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rr)
+	cookies.SetAuthCookiesInResponse(user, sessionToken, c)
+	return rr.Result().Cookies()[0]
+}
+
 func TestAuthUser(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	t.Run("Valid auth data continues middleware", func(t *testing.T) {
 		mockUser := model.User{
-			Username:     "tim",
-			Password:     "doesnt-matter",
-			SessionToken: "value",
+			Username: "tim",
+			Password: "doesnt-matter",
 		}
+		mockUser.ID = 1
 
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On(
+				"Authenticate",
+				mock.AnythingOfType("*gin.Context"),
+				uint(1),
+				"value").
+			Return(&model.Session{ID: 7, UserID: 1}, nil)
 		mockUserService := new(mocks.MockUserService)
 		mockUserService.
 			On(
@@ -52,7 +73,7 @@ func TestAuthUser(t *testing.T) {
 				mock.AnythingOfType("uint")).
 			Return(&mockUser, nil)
 
-		router := buildRouterWithMiddleware(mockUserService)
+		router := buildRouterWithMiddleware(mockUserService, mockSessionService)
 
 		rr := httptest.NewRecorder()
 
@@ -61,6 +82,51 @@ func TestAuthUser(t *testing.T) {
 		assert.NoError(t, err)
 
 		// set the valid auth cookie
+		request.AddCookie(signedAuthCookie(mockUser, "value"))
+
+		// perform request
+		router.ServeHTTP(rr, request)
+
+		// request should pass middleware and return success
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, "success 7", rr.Body.String())
+		assert.Empty(t, rr.Header().Get("Set-Cookie"))
+		mockUserService.AssertCalled(t, "Get", mock.Anything, mock.Anything)
+		mockSessionService.AssertExpectations(t)
+	})
+
+	t.Run("Unsigned auth cookie is accepted and re-signed", func(t *testing.T) {
+		mockUser := model.User{
+			Username: "tim",
+			Password: "doesnt-matter",
+		}
+		mockUser.ID = 1
+
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On(
+				"Authenticate",
+				mock.AnythingOfType("*gin.Context"),
+				uint(1),
+				"value").
+			Return(&model.Session{ID: 7, UserID: 1}, nil)
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On(
+				"Get",
+				mock.AnythingOfType("*gin.Context"),
+				mock.AnythingOfType("uint")).
+			Return(&mockUser, nil)
+
+		router := buildRouterWithMiddleware(mockUserService, mockSessionService)
+
+		rr := httptest.NewRecorder()
+
+		// build the request
+		request, err := http.NewRequest(http.MethodGet, "/test", nil)
+		assert.NoError(t, err)
+
+		// cookie from before they were signed
 		cookie := &http.Cookie{
 			Name:  "ngo_auth",
 			Value: "1::value",
@@ -72,10 +138,80 @@ func TestAuthUser(t *testing.T) {
 
 		// request should pass middleware and return success
 		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, "success 7", rr.Body.String())
+		reissued := rr.Result().Cookies()[0]
+		assert.Equal(t, signedAuthCookie(mockUser, "value").Value, reissued.Value)
 		mockUserService.AssertCalled(t, "Get", mock.Anything, mock.Anything)
+		mockSessionService.AssertExpectations(t)
+	})
+
+	t.Run("Auth cookie with a bad signature returns error", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockUserService := new(mocks.MockUserService)
+
+		router := buildRouterWithMiddleware(mockUserService, mockSessionService)
+
+		rr := httptest.NewRecorder()
+
+		// build the request
+		request, err := http.NewRequest(http.MethodGet, "/test", nil)
+		assert.NoError(t, err)
+
+		// signed for user 0, but claiming to be user 2
+		cookie := signedAuthCookie(model.User{}, "value")
+		cookie.Value = "2" + strings.TrimPrefix(cookie.Value, "0")
+		request.AddCookie(cookie)
+
+		// perform request
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 401, rr.Code)
+		mockSessionService.AssertNotCalled(t, "Authenticate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Revoked or unknown session returns error", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On(
+				"Authenticate",
+				mock.AnythingOfType("*gin.Context"),
+				uint(1),
+				"value").
+			Return(nil, apperrors.NewUnauthorized())
+		mockUserService := new(mocks.MockUserService)
+
+		router := buildRouterWithMiddleware(mockUserService, mockSessionService)
+
+		rr := httptest.NewRecorder()
+
+		// build the request
+		request, err := http.NewRequest(http.MethodGet, "/test", nil)
+		assert.NoError(t, err)
+
+		cookie := &http.Cookie{
+			Name:  "ngo_auth",
+			Value: "1::value",
+		}
+		request.AddCookie(cookie)
+
+		// perform request
+		router.ServeHTTP(rr, request)
+
+		// request should fail middleware and clear the dead cookie
+		assert.Equal(t, 401, rr.Code)
+		assert.Contains(t, rr.Header().Get("Set-Cookie"), "ngo_auth=;")
+		mockUserService.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
 	})
 
 	t.Run("No user corresponding to auth token returns error", func(t *testing.T) {
+		mockSessionService := new(mocks.MockSessionService)
+		mockSessionService.
+			On(
+				"Authenticate",
+				mock.AnythingOfType("*gin.Context"),
+				uint(1),
+				"value").
+			Return(&model.Session{ID: 7, UserID: 1}, nil)
 		mockUserService := new(mocks.MockUserService)
 		mockUserService.
 			On(
@@ -84,7 +220,7 @@ func TestAuthUser(t *testing.T) {
 				mock.AnythingOfType("uint")).
 			Return(nil, errors.New("no user found"))
 
-		router := buildRouterWithMiddleware(mockUserService)
+		router := buildRouterWithMiddleware(mockUserService, mockSessionService)
 
 		rr := httptest.NewRecorder()
 
@@ -110,7 +246,7 @@ func TestAuthUser(t *testing.T) {
 	t.Run("Invalid auth cookie value returns error", func(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 
-		router := buildRouterWithMiddleware(mockUserService)
+		router := buildRouterWithMiddleware(mockUserService, new(mocks.MockSessionService))
 
 		rr := httptest.NewRecorder()
 
@@ -135,7 +271,7 @@ func TestAuthUser(t *testing.T) {
 	t.Run("No auth cookie fails middleware check", func(t *testing.T) {
 		mockUserService := new(mocks.MockUserService)
 
-		router := buildRouterWithMiddleware(mockUserService)
+		router := buildRouterWithMiddleware(mockUserService, new(mocks.MockSessionService))
 
 		rr := httptest.NewRecorder()
 
@@ -150,3 +286,71 @@ func TestAuthUser(t *testing.T) {
 		assert.Equal(t, 401, rr.Code)
 	})
 }
+
+func buildRouterWithAPITokens(mockUserService *mocks.MockUserService, mockSessionService *mocks.MockSessionService, mockAPITokenService *mocks.MockAPITokenService) *gin.Engine {
+	router := gin.Default()
+
+	p := provider.Provider{
+		R:               router,
+		UserService:     mockUserService,
+		SessionService:  mockSessionService,
+		APITokenService: mockAPITokenService,
+	}
+	rhandler := endpoints.NewRouteHandler(p)
+	router.Use(AuthUser(rhandler))
+
+	router.GET("/test", func(c *gin.Context) {
+		user := c.MustGet("user").(*model.User)
+		_, hasSession := c.Get("session")
+		c.String(http.StatusOK, "success %d %t", user.ID, hasSession)
+	})
+	return router
+}
+
+func TestAuthUserAPIToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockUser := model.User{Username: "tim"}
+	mockUser.ID = 1
+
+	t.Run("Bearer tokens authenticate without a session", func(t *testing.T) {
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("Authenticate", mock.AnythingOfType("*gin.Context"), "ngo_pat_abc").
+			Return(&model.APIToken{ID: 3, UserID: 1, Scopes: model.ScopeReadGames}, nil)
+		mockUserService := new(mocks.MockUserService)
+		mockUserService.
+			On("Get", mock.AnythingOfType("*gin.Context"), uint(1)).
+			Return(&mockUser, nil)
+		mockSessionService := new(mocks.MockSessionService)
+
+		router := buildRouterWithAPITokens(mockUserService, mockSessionService, mockAPITokenService)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/test", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer ngo_pat_abc")
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 200, rr.Code)
+		assert.Equal(t, "success 1 false", rr.Body.String())
+		mockSessionService.AssertNotCalled(t, "Authenticate", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Bad tokens fail, even alongside a valid cookie", func(t *testing.T) {
+		mockAPITokenService := new(mocks.MockAPITokenService)
+		mockAPITokenService.
+			On("Authenticate", mock.AnythingOfType("*gin.Context"), "ngo_pat_revoked").
+			Return(nil, apperrors.NewUnauthorized())
+		mockSessionService := new(mocks.MockSessionService)
+
+		router := buildRouterWithAPITokens(new(mocks.MockUserService), mockSessionService, mockAPITokenService)
+		rr := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/test", nil)
+		assert.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer ngo_pat_revoked")
+		request.AddCookie(signedAuthCookie(mockUser, "value"))
+		router.ServeHTTP(rr, request)
+
+		assert.Equal(t, 401, rr.Code)
+		mockSessionService.AssertNotCalled(t, "Authenticate", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
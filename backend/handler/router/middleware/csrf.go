@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/constants"
+	"net-go/server/backend/handler/cookies"
+	"net-go/server/backend/logger"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+/**
+ * Rejects cross-site requests that could change state using the
+ * visitor's cookies. A mutating request gets through when either:
+ *   - its X-CSRF-Token header matches the ngo_csrf cookie (see GET
+ *     /api/csrf), which only our own pages can read, or
+ *   - without that header, its Origin (or Referer) is this site.
+ * Browsers send an Origin on every cross-origin POST/DELETE, so the
+ * Elm app is covered by the latter without needing a token. Requests
+ * with an API token in their Authorization header are let through, as
+ * they're authenticated by that rather than by cookies, and other sites
+ * can't make browsers send it.
+ */
+func CSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if _, ok := bearerToken(c); ok {
+			c.Next()
+			return
+		}
+
+		if token := c.GetHeader(cookies.CSRFHeader); token != "" {
+			if !cookies.ValidCSRFToken(c, token) {
+				rejectCSRF(c, "token mismatch")
+				return
+			}
+			c.Next()
+			return
+		}
+
+		source := c.GetHeader("Origin")
+		if source == "" {
+			source = c.GetHeader("Referer")
+		}
+		if source == "" {
+			rejectCSRF(c, "no token or origin")
+			return
+		}
+		if !isSameSite(c, source) {
+			rejectCSRF(c, "cross site origin")
+			return
+		}
+		c.Next()
+	}
+}
+
+// whether the `source` url (an Origin or Referer) is our own site
+func isSameSite(c *gin.Context, source string) bool {
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		// includes the opaque "null" origin
+		return false
+	}
+	// the configured domain, or whatever host the request was made to (e.g. localhost in dev)
+	return u.Hostname() == constants.GetDomain() || u.Host == c.Request.Host
+}
+
+func rejectCSRF(c *gin.Context, reason string) {
+	logger.WarnCtx(c, "Rejected possible CSRF request",
+		zap.String("reason", reason),
+		zap.String("origin", c.GetHeader("Origin")))
+	forbiddenErr := apperrors.NewForbidden()
+	c.AbortWithStatusJSON(forbiddenErr.Status(), gin.H{
+		"error": forbiddenErr.Error(),
+	})
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildCSRFRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(CSRF())
+	router.GET("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+	router.POST("/test", func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+	return router
+}
+
+func TestCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		cookie  string
+		code    int
+	}{
+		{"Safe methods pass", http.MethodGet, nil, "", 200},
+		{"Matching token passes", http.MethodPost, map[string]string{"X-CSRF-Token": "abc"}, "abc", 200},
+		{"Mismatched token fails", http.MethodPost, map[string]string{"X-CSRF-Token": "abc"}, "def", 403},
+		{"Token without cookie fails", http.MethodPost, map[string]string{"X-CSRF-Token": "abc"}, "", 403},
+		{"Bad token fails even from our origin", http.MethodPost, map[string]string{
+			"X-CSRF-Token": "abc",
+			"Origin":       "https://playonlinego.xyz",
+		}, "def", 403},
+		{"Our domain's origin passes", http.MethodPost, map[string]string{"Origin": "https://playonlinego.xyz"}, "", 200},
+		{"Request host's origin passes", http.MethodPost, map[string]string{"Origin": "http://localhost:8080"}, "", 200},
+		{"Our referer passes", http.MethodPost, map[string]string{"Referer": "https://playonlinego.xyz/games/1"}, "", 200},
+		{"Other origin fails", http.MethodPost, map[string]string{"Origin": "https://evil.example"}, "", 403},
+		{"Lookalike origin fails", http.MethodPost, map[string]string{"Origin": "https://playonlinego.xyz.evil.example"}, "", 403},
+		{"Null origin fails", http.MethodPost, map[string]string{"Origin": "null"}, "", 403},
+		{"No token or origin fails", http.MethodPost, nil, "", 403},
+		{"API token requests pass", http.MethodPost, map[string]string{"Authorization": "Bearer ngo_pat_abc"}, "", 200},
+		{"Other authorization schemes don't", http.MethodPost, map[string]string{"Authorization": "Basic dGltOnB3"}, "", 403},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router := buildCSRFRouter()
+			rr := httptest.NewRecorder()
+			request, err := http.NewRequest(tc.method, "http://localhost:8080/test", nil)
+			assert.NoError(t, err)
+			for key, value := range tc.headers {
+				request.Header.Set(key, value)
+			}
+			if tc.cookie != "" {
+				request.AddCookie(&http.Cookie{Name: "ngo_csrf", Value: tc.cookie})
+			}
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, tc.code, rr.Code)
+		})
+	}
+}
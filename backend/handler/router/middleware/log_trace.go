@@ -1,23 +1,34 @@
 package middleware
 
 import (
+	"net-go/server/backend/logger"
+
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+/**
+ * Attaches a logger for this request to the request context, tagged
+ * with the route and (when traced) the trace/span ids. Log with the
+ * logger.XCtx functions, passing the gin.Context, to include them.
+ */
 func AttachLogTraceMetadata() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		spanContext := trace.SpanContextFromContext(c)
-		logger := zap.L()
+		ctx := c.Request.Context()
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+		}
 		// attach trace id to the log
+		spanContext := trace.SpanContextFromContext(ctx)
 		if spanContext.IsValid() {
-			logger = logger.With(
+			fields = append(fields,
 				zap.String("trace_id", spanContext.TraceID().String()),
 				zap.String("span_id", spanContext.SpanID().String()),
 			)
-			zap.ReplaceGlobals(logger)
 		}
+		c.Request = c.Request.WithContext(logger.WithFields(ctx, fields...))
 
 		c.Next()
 	}
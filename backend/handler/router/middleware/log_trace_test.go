@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"net-go/server/backend/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAttachLogTraceMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Request logs are tagged with the route", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		restore := zap.ReplaceGlobals(zap.New(core))
+		defer restore()
+
+		router := gin.New()
+		router.ContextWithFallback = true
+		router.Use(AttachLogTraceMetadata())
+		router.GET("/test/:id", func(c *gin.Context) {
+			logger.InfoCtx(c, "handled")
+			c.String(http.StatusOK, "success")
+		})
+
+		req, err := http.NewRequest(http.MethodGet, "/test/123", nil)
+		assert.NoError(t, err)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, 1, logs.Len())
+		fields := logs.All()[0].ContextMap()
+		assert.Equal(t, "/test/:id", fields["route"])
+		assert.Equal(t, http.MethodGet, fields["method"])
+		// no otelgin span in this router
+		assert.NotContains(t, fields, "trace_id")
+	})
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net-go/server/backend/instrumentation"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// records the duration of each request, labelled by its gin route
+func RecordRequestMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		// the route pattern (e.g. /api/games/:id), not the raw path, to keep label cardinality low
+		route := c.FullPath()
+		if route == "" {
+			route = instrumentation.UnmatchedRoute
+		}
+		instrumentation.RequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
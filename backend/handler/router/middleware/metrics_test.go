@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"net-go/server/backend/instrumentation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// number of requests recorded for the given labels
+func requestCount(t *testing.T, method string, route string, status string) uint64 {
+	var metric dto.Metric
+	observer := instrumentation.RequestDuration.WithLabelValues(method, route, status)
+	assert.NoError(t, observer.(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestRecordRequestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RecordRequestMetrics())
+	router.GET("/test/:id", func(c *gin.Context) {
+		c.String(http.StatusTeapot, "success")
+	})
+
+	t.Run("Labels requests by route", func(t *testing.T) {
+		before := requestCount(t, http.MethodGet, "/test/:id", "418")
+
+		req, err := http.NewRequest(http.MethodGet, "/test/123", nil)
+		assert.NoError(t, err)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, before+1, requestCount(t, http.MethodGet, "/test/:id", "418"))
+	})
+
+	t.Run("Groups requests without a route", func(t *testing.T) {
+		before := requestCount(t, http.MethodGet, instrumentation.UnmatchedRoute, "404")
+
+		req, err := http.NewRequest(http.MethodGet, "/elsewhere", nil)
+		assert.NoError(t, err)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, before+1, requestCount(t, http.MethodGet, instrumentation.UnmatchedRoute, "404"))
+	})
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/ratelimit"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// picks the key a request is rate limited by; "" lets the request through
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// limits by the client's IP (see gin's trusted proxies for X-Forwarded-For handling)
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// most of a request body read when looking for the username
+const maxPeekBytes = 1 << 16
+
+/**
+ * Limits by the "username" in a JSON request body, e.g. of signin. The
+ * body is restored afterwards so the handler can still bind it.
+ */
+func UsernameKey(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBytes))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Username string `json:"username"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		// let the handler reject the malformed body
+		return ""
+	}
+	return strings.ToLower(req.Username)
+}
+
+/**
+ * Responds 429, with a Retry-After header, to requests over the
+ * allowance of their key in `limiter`. If the limiter fails, requests
+ * are let through rather than failing the endpoint.
+ */
+func RateLimit(limiter ratelimit.Limiter, key RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := limiter.Allow(c, k)
+		if err != nil {
+			logger.WarnCtx(c, "Rate limiter failed, allowing request", zap.Error(err))
+			c.Next()
+			return
+		}
+		if !allowed {
+			logger.DebugCtx(c, "Rate limited request", zap.Duration("retry_after", retryAfter))
+			tooManyErr := apperrors.NewTooManyRequests(retryAfter)
+			c.Header("Retry-After", apperrors.RetryAfterHeader(tooManyErr))
+			c.AbortWithStatusJSON(tooManyErr.Status(), gin.H{
+				"error": tooManyErr.Error(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"net-go/server/backend/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// limiter that always fails
+type brokenLimiter struct{}
+
+func (brokenLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	return false, 0, errors.New("limiter unavailable")
+}
+
+func buildRateLimitedRouter(limiter ratelimit.Limiter, key RateLimitKeyFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(RateLimit(limiter, key))
+
+	// echo the body, to check it survives being peeked at
+	router.POST("/test", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	return router
+}
+
+func postTest(router *gin.Engine, body string, remoteAddr string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/test", bytes.NewBufferString(body))
+	req.RemoteAddr = remoteAddr
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Requests over the limit get a 429", func(t *testing.T) {
+		limiter := ratelimit.NewMemoryLimiter(ratelimit.MemoryLimiterConfig{Limit: 2, Per: time.Minute})
+		router := buildRateLimitedRouter(limiter, ClientIPKey)
+
+		assert.Equal(t, http.StatusOK, postTest(router, "", "1.2.3.4:1234").Code)
+		assert.Equal(t, http.StatusOK, postTest(router, "", "1.2.3.4:1234").Code)
+		rr := postTest(router, "", "1.2.3.4:1234")
+
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+		// other clients are unaffected
+		assert.Equal(t, http.StatusOK, postTest(router, "", "5.6.7.8:1234").Code)
+	})
+
+	t.Run("Limits by username across clients", func(t *testing.T) {
+		limiter := ratelimit.NewMemoryLimiter(ratelimit.MemoryLimiterConfig{Limit: 1, Per: time.Minute})
+		router := buildRateLimitedRouter(limiter, UsernameKey)
+		body := `{"username":"tim","password":"password"}`
+
+		rr := postTest(router, body, "1.2.3.4:1234")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, body, rr.Body.String())
+
+		assert.Equal(t, http.StatusTooManyRequests, postTest(router, `{"username":"TIM"}`, "5.6.7.8:1234").Code)
+		assert.Equal(t, http.StatusOK, postTest(router, `{"username":"tom"}`, "5.6.7.8:1234").Code)
+	})
+
+	t.Run("Requests without a username are left to the handler", func(t *testing.T) {
+		limiter := ratelimit.NewMemoryLimiter(ratelimit.MemoryLimiterConfig{Limit: 1, Per: time.Minute})
+		router := buildRateLimitedRouter(limiter, UsernameKey)
+
+		assert.Equal(t, http.StatusOK, postTest(router, "not json", "1.2.3.4:1234").Code)
+		assert.Equal(t, http.StatusOK, postTest(router, "not json", "1.2.3.4:1234").Code)
+	})
+
+	t.Run("Failing limiters let requests through", func(t *testing.T) {
+		router := buildRateLimitedRouter(brokenLimiter{}, ClientIPKey)
+
+		assert.Equal(t, http.StatusOK, postTest(router, "", "1.2.3.4:1234").Code)
+	})
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// the API token the request authenticated with (see AuthUser), or nil for a cookie session
+func apiTokenFromCtx(c *gin.Context) *model.APIToken {
+	untypedToken, exists := c.Get("apiToken")
+	if !exists {
+		return nil
+	}
+	return untypedToken.(*model.APIToken)
+}
+
+// 403s requests made with an API token lacking `scope`; signed in devices can do anything
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := apiTokenFromCtx(c); token != nil && !token.HasScope(scope) {
+			logger.DebugCtx(c, "Rejected API token missing scope", zap.String("scope", scope))
+			forbiddenErr := apperrors.NewForbidden()
+			c.AbortWithStatusJSON(forbiddenErr.Status(), gin.H{
+				"error": forbiddenErr.Error(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// 403s requests made with an API token, for routes only signed in devices may use
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiTokenFromCtx(c) != nil {
+			logger.DebugCtx(c, "Rejected API token on session only route")
+			forbiddenErr := apperrors.NewForbidden()
+			c.AbortWithStatusJSON(forbiddenErr.Status(), gin.H{
+				"error": forbiddenErr.Error(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
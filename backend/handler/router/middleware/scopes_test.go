@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/model"
+)
+
+// router standing in for AuthUser by setting `token` (nil for a cookie session)
+func buildScopeRouter(token *model.APIToken) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if token != nil {
+			c.Set("apiToken", token)
+		}
+	})
+	router.GET("/games", RequireScope(model.ScopeReadGames), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+	router.GET("/accounts", RequireSession(), func(c *gin.Context) {
+		c.String(http.StatusOK, "success")
+	})
+	return router
+}
+
+func TestScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name  string
+		token *model.APIToken
+		path  string
+		code  int
+	}{
+		{"Sessions pass scope checks", nil, "/games", 200},
+		{"Tokens with the scope pass", &model.APIToken{Scopes: "games:read games:play"}, "/games", 200},
+		{"Tokens without it don't", &model.APIToken{Scopes: "games:play"}, "/games", 403},
+		{"Sessions pass session only routes", nil, "/accounts", 200},
+		{"Tokens don't, whatever their scopes", &model.APIToken{Scopes: "games:read games:play"}, "/accounts", 403},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			router := buildScopeRouter(tc.token)
+			rr := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, tc.path, nil)
+			assert.NoError(t, err)
+
+			router.ServeHTTP(rr, request)
+
+			assert.Equal(t, tc.code, rr.Code)
+		})
+	}
+}
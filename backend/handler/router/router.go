@@ -3,10 +3,12 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"log"
 	"net-go/server/backend/constants"
 	"net-go/server/backend/handler/provider"
 	"net-go/server/backend/handler/router/endpoints"
 	"net-go/server/backend/handler/router/middleware"
+	"net-go/server/backend/model"
 	"net/http"
 )
 
@@ -17,6 +19,19 @@ func SetRouter(p provider.Provider) {
 	handler := endpoints.NewRouteHandler(p)
 
 	router.RedirectTrailingSlash = true
+	// handlers pass the gin.Context on as the ctx of service calls; this
+	// makes it expose the request's context (trace span, cancellation
+	// when the client disconnects) rather than acting as an empty context
+	router.ContextWithFallback = true
+
+	// gin trusts every proxy by default, which would let clients dodge the
+	// per IP auth rate limits by sending their own X-Forwarded-For
+	if err := router.SetTrustedProxies(p.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
+	// request latency for the metrics listener; applies to every route registered below
+	router.Use(middleware.RecordRequestMetrics())
 
 	if gin.Mode() != gin.TestMode {
 		// load HTML files from glob pattern so gin can reference them
@@ -25,32 +40,95 @@ func SetRouter(p provider.Provider) {
 		router.Static("/static", "frontend/static")
 	}
 
+	// probes for container orchestration; kept out of the /api
+	// group so they skip its tracing, logging and auth middleware
+	router.GET("/healthz", handler.Healthz)
+	router.GET("/readyz", handler.Readyz)
+
 	// API request routes
 	apiGroup := router.Group("/api")
 
 	// global middlware
 	apiGroup.Use(otelgin.Middleware(constants.GetOtelServiceName()))
 	apiGroup.Use(middleware.AttachLogTraceMetadata())
+	// POST/DELETE requests act with the visitor's cookies, so must come from our own pages
+	apiGroup.Use(middleware.CSRF())
+
+	// token for clients that can't rely on the Origin header to pass the CSRF check
+	apiGroup.GET("/csrf", handler.CSRFToken)
 
 	// -- UNAUTHENTICATED ROUTES --
 
 	// auth
 	authGroup := apiGroup.Group("/accounts")
-	authGroup.POST("/signup", handler.Signup)
-	authGroup.POST("/signin", handler.Signin)
-	authGroup.GET("/signout", handler.Signout)
+	// password hashing makes these expensive, so they're throttled
+	throttledAuthGroup := authGroup.Group("", authRateLimits(p)...)
+	throttledAuthGroup.POST("/signup", handler.Signup)
+	throttledAuthGroup.POST("/signin", handler.Signin)
+	throttledAuthGroup.POST("/signin/2fa", handler.CompleteTwoFactorSignin)
+	throttledAuthGroup.POST("/password/reset", handler.RequestPasswordReset)
+	throttledAuthGroup.POST("/password/reset/confirm", handler.ConfirmPasswordReset)
+	throttledAuthGroup.POST("/email/verify", handler.VerifyEmail)
+	// sign in through an external identity provider
+	authGroup.GET("/oidc/login", handler.OIDCLogin)
+	authGroup.GET("/oidc/callback", handler.OIDCCallback)
+	throttledAuthGroup.POST("/oidc/signup", handler.OIDCSignup)
+	// POST only, so other sites can't sign users out with a link or image
+	authGroup.POST("/signout", handler.Signout)
+
+	// public user profiles
+	userGroup := apiGroup.Group("/users")
+	userGroup.GET("/:username", handler.GetUserProfile)
 
 	// -- AUTHENTICATED ROUTES --
 	apiGroup.Use(middleware.AuthUser(handler))
 
-	// game play
+	// the account itself is only managed from signed in devices, not with API tokens
+	sessionOnlyGroup := apiGroup.Group("", middleware.RequireSession())
+
+	// account management; checks passwords, so throttled like signin
+	accountGroup := sessionOnlyGroup.Group("/accounts", authRateLimits(p)...)
+	accountGroup.POST("/password", handler.ChangePassword)
+	// sends mail, so throttled too
+	accountGroup.POST("/email", handler.RequestEmailVerification)
+	sessionOnlyGroup.GET("/accounts/email", handler.GetEmail)
+	sessionOnlyGroup.DELETE("/accounts/email", handler.RemoveEmail)
+	// two-factor auth; checks codes, so throttled too
+	sessionOnlyGroup.GET("/accounts/2fa", handler.GetTwoFactorStatus)
+	accountGroup.POST("/2fa", handler.BeginTwoFactorEnrollment)
+	accountGroup.POST("/2fa/confirm", handler.ConfirmTwoFactorEnrollment)
+	accountGroup.POST("/2fa/disable", handler.DisableTwoFactor)
+	accountGroup.POST("/2fa/recovery-codes", handler.RegenerateRecoveryCodes)
+
+	// identity provider accounts that sign in as the user
+	sessionOnlyGroup.GET("/accounts/oidc/link", handler.OIDCLink)
+	sessionOnlyGroup.GET("/accounts/identities", handler.ListIdentities)
+	sessionOnlyGroup.DELETE("/accounts/identities/:id", handler.UnlinkIdentity)
+
+	// signed in devices
+	sessionGroup := sessionOnlyGroup.Group("/accounts/sessions")
+	sessionGroup.GET("", handler.ListSessions)
+	sessionGroup.DELETE("", handler.RevokeAllSessions)
+	sessionGroup.DELETE("/:id", handler.RevokeSession)
+
+	// personal API tokens for scripts and bots
+	tokenGroup := sessionOnlyGroup.Group("/accounts/tokens")
+	tokenGroup.GET("", handler.ListAPITokens)
+	tokenGroup.POST("", handler.CreateAPIToken)
+	tokenGroup.DELETE("/:id", handler.RevokeAPIToken)
+
+	// game play; API tokens need the scope for each route
+	readGames := middleware.RequireScope(model.ScopeReadGames)
+	playMoves := middleware.RequireScope(model.ScopePlayMoves)
 	gameGroup := apiGroup.Group("/games")
-	gameGroup.GET("/:id", handler.GetGame)
-	gameGroup.GET("/:id/long", handler.GetGameLongPoll)
-	gameGroup.GET("/", handler.ListGamesByUser)
-	gameGroup.POST("/:id", handler.UpdateGame)
-	gameGroup.POST("/", handler.CreateGame)
-	gameGroup.DELETE("/:id", handler.DeleteGame)
+	gameGroup.GET("/:id", readGames, handler.GetGame)
+	gameGroup.GET("/:id/long", readGames, handler.GetGameLongPoll)
+	gameGroup.GET("/", readGames, handler.ListGamesByUser)
+	gameGroup.GET("/summaries", readGames, handler.ListGameSummaries)
+	gameGroup.GET("/pending", readGames, handler.ListPendingGames)
+	gameGroup.POST("/:id", playMoves, handler.UpdateGame)
+	gameGroup.POST("/", playMoves, handler.CreateGame)
+	gameGroup.DELETE("/:id", playMoves, handler.DeleteGame)
 
 	// serve the Elm app HTML for any other route; the
 	// Elm SPA will handle its own routing internally
@@ -58,3 +136,15 @@ func SetRouter(p provider.Provider) {
 		c.HTML(http.StatusOK, "index.html", gin.H{})
 	})
 }
+
+// rate limiting middleware for the auth routes, per the limiters in `p`
+func authRateLimits(p provider.Provider) []gin.HandlerFunc {
+	var limits []gin.HandlerFunc
+	if p.AuthIPLimiter != nil {
+		limits = append(limits, middleware.RateLimit(p.AuthIPLimiter, middleware.ClientIPKey))
+	}
+	if p.AuthUsernameLimiter != nil {
+		limits = append(limits, middleware.RateLimit(p.AuthUsernameLimiter, middleware.UsernameKey))
+	}
+	return limits
+}
@@ -0,0 +1,49 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/handler/provider"
+	"net-go/server/backend/ratelimit"
+	"net-go/server/backend/services/mocks"
+)
+
+func TestSetRouter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Spoofed X-Forwarded-For doesn't dodge the auth IP limit", func(t *testing.T) {
+		p := provider.Provider{
+			R:           gin.New(),
+			UserService: new(mocks.MockUserService),
+			AuthIPLimiter: ratelimit.NewMemoryLimiter(ratelimit.MemoryLimiterConfig{
+				Limit: 1,
+				Per:   time.Minute,
+			}),
+		}
+		SetRouter(p)
+
+		signin := func(forwardedFor string) int {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/accounts/signin", bytes.NewBufferString("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Origin", "http://"+req.Host)
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+			req.RemoteAddr = "1.2.3.4:1234"
+			p.R.ServeHTTP(rr, req)
+			return rr.Code
+		}
+
+		// the incomplete body is rejected by the handler, after the limiter
+		assert.Equal(t, http.StatusBadRequest, signin("10.0.0.1"))
+		for i := 2; i < 5; i++ {
+			assert.Equal(t, http.StatusTooManyRequests, signin(fmt.Sprintf("10.0.0.%d", i)))
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// a single dependency the server needs in order to serve traffic
+type Check struct {
+	Name string
+	// returns nil when the dependency is usable
+	Run func(ctx context.Context) error
+}
+
+// outcome of a readiness check
+type Report struct {
+	Ready    bool
+	Draining bool
+	// error message of each failed check, or "ok", by check name
+	Checks map[string]string
+}
+
+/**
+ * Decides whether the server should receive traffic. It is ready when
+ * every check passes, until StartDraining is called on shutdown; from
+ * then on it reports not ready so load balancers stop routing to it.
+ * Safe for concurrent use.
+ */
+type Readiness struct {
+	checks   []Check
+	draining atomic.Bool
+}
+
+func NewReadiness(checks ...Check) *Readiness {
+	return &Readiness{
+		checks: checks,
+	}
+}
+
+// Marks the server as shutting down. Cannot be undone.
+func (r *Readiness) StartDraining() {
+	r.draining.Store(true)
+}
+
+func (r *Readiness) IsDraining() bool {
+	return r.draining.Load()
+}
+
+// Runs every check concurrently, reporting whether the server is ready.
+func (r *Readiness) Check(ctx context.Context) Report {
+	report := Report{
+		Ready:    true,
+		Draining: r.IsDraining(),
+		Checks:   make(map[string]string, len(r.checks)),
+	}
+	if report.Draining {
+		// no point checking dependencies of a server that is going away
+		report.Ready = false
+		return report
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range r.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			err := check.Run(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				report.Ready = false
+				report.Checks[check.Name] = err.Error()
+			} else {
+				report.Checks[check.Name] = "ok"
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func passingCheck(name string) Check {
+	return Check{
+		Name: name,
+		Run:  func(ctx context.Context) error { return nil },
+	}
+}
+
+func failingCheck(name string, err error) Check {
+	return Check{
+		Name: name,
+		Run:  func(ctx context.Context) error { return err },
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("ready when all checks pass", func(t *testing.T) {
+		readiness := NewReadiness(passingCheck("database"), passingCheck("realtime"))
+
+		report := readiness.Check(context.Background())
+
+		assert.True(t, report.Ready)
+		assert.False(t, report.Draining)
+		assert.Equal(t, map[string]string{"database": "ok", "realtime": "ok"}, report.Checks)
+	})
+	t.Run("ready without checks", func(t *testing.T) {
+		report := NewReadiness().Check(context.Background())
+
+		assert.True(t, report.Ready)
+		assert.Empty(t, report.Checks)
+	})
+	t.Run("not ready when a check fails", func(t *testing.T) {
+		readiness := NewReadiness(
+			passingCheck("database"),
+			failingCheck("migrations", errors.New("1 pending migration(s)")),
+		)
+
+		report := readiness.Check(context.Background())
+
+		assert.False(t, report.Ready)
+		assert.Equal(t, map[string]string{
+			"database":   "ok",
+			"migrations": "1 pending migration(s)",
+		}, report.Checks)
+	})
+	t.Run("not ready once draining", func(t *testing.T) {
+		ran := false
+		readiness := NewReadiness(Check{
+			Name: "database",
+			Run: func(ctx context.Context) error {
+				ran = true
+				return nil
+			},
+		})
+		assert.True(t, readiness.Check(context.Background()).Ready)
+		ran = false
+
+		readiness.StartDraining()
+		report := readiness.Check(context.Background())
+
+		assert.True(t, readiness.IsDraining())
+		assert.False(t, report.Ready)
+		assert.True(t, report.Draining)
+		assert.False(t, ran, "checks should not run while draining")
+	})
+}
@@ -0,0 +1,71 @@
+package identity
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+
+	"golang.org/x/oauth2"
+)
+
+/*
+ * Sign in through an external identity provider. Only OpenID Connect
+ * is implemented (see OIDCProvider), which works with any issuer that
+ * supports discovery; oidctest has a mock one for tests.
+ */
+
+// who the provider says signed in, from their verified ID token
+type Claims struct {
+	// together, Issuer and Subject identify the account at the provider
+	Issuer  string
+	Subject string
+	Email   string
+	// whether the provider checked the user owns Email
+	EmailVerified bool
+	// the username the user goes by at the provider, as a suggestion for ours
+	PreferredUsername string
+}
+
+/**
+ * What a sign in has to carry from sending the user to the provider to
+ * their coming back: the state that ties the two together, the nonce
+ * that ties the ID token to it, and the PKCE verifier the code is
+ * redeemed with. Keep it somewhere only this user can read it.
+ */
+type AuthRequest struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func NewAuthRequest() (*AuthRequest, error) {
+	state, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return nil, err
+	}
+	return &AuthRequest{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+	}, nil
+}
+
+type Provider interface {
+	// where to send the user to sign in, for `req`
+	AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error)
+	// trades the authorization `code` the user came back with for their verified claims
+	Exchange(ctx context.Context, code string, req *AuthRequest) (*Claims, error)
+}
+
+// 256 random bits, url safe
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,120 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+type OIDCConfig struct {
+	// issuer URL; its /.well-known/openid-configuration is fetched for the rest
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// our callback, as registered with the provider
+	RedirectURL string
+	// "openid" is always asked for
+	Scopes []string
+}
+
+/**
+ * Generic OpenID Connect client: authorization code flow with PKCE, and
+ * ID tokens checked against the issuer's keys, our client ID and the
+ * sign in's nonce. Discovery happens on first use (and is retried until
+ * it works), so an unreachable provider doesn't stop the server starting.
+ */
+type OIDCProvider struct {
+	config OIDCConfig
+
+	mu       sync.Mutex
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func NewOIDCProvider(config OIDCConfig) (*OIDCProvider, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, errors.New("OIDC needs an issuer, client ID and redirect URL")
+	}
+	return &OIDCProvider{config: config}, nil
+}
+
+// the discovered provider's oauth2 config and ID token verifier
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth2 != nil {
+		return p.oauth2, p.verifier, nil
+	}
+
+	// the keys fetched later outlive this request, so they don't get its context
+	provider, err := oidc.NewProvider(context.WithoutCancel(ctx), p.config.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("OIDC discovery failed: %w", err)
+	}
+	scopes := []string{oidc.ScopeOpenID}
+	for _, scope := range p.config.Scopes {
+		if scope != oidc.ScopeOpenID {
+			scopes = append(scopes, scope)
+		}
+	}
+	p.oauth2 = &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  p.config.RedirectURL,
+		Scopes:       scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.config.ClientID})
+	return p.oauth2, p.verifier, nil
+}
+
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, req *AuthRequest) (string, error) {
+	config, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(req.State, oidc.Nonce(req.Nonce), oauth2.S256ChallengeOption(req.Verifier)), nil
+}
+
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, req *AuthRequest) (*Claims, error) {
+	config, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(req.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("OIDC code exchange failed: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("OIDC token response had no ID token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("OIDC ID token is invalid: %w", err)
+	}
+	if idToken.Nonce != req.Nonce {
+		return nil, errors.New("OIDC ID token is for another sign in")
+	}
+
+	var extra struct {
+		Email string `json:"email"`
+		// some providers send this as a string
+		EmailVerified     any    `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+	}
+	if err := idToken.Claims(&extra); err != nil {
+		return nil, fmt.Errorf("OIDC ID token claims are invalid: %w", err)
+	}
+	return &Claims{
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             extra.Email,
+		EmailVerified:     extra.EmailVerified == true || extra.EmailVerified == "true",
+		PreferredUsername: extra.PreferredUsername,
+	}, nil
+}
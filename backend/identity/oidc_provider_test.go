@@ -0,0 +1,135 @@
+package identity
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/identity/oidctest"
+)
+
+var tim = oidctest.User{
+	Subject:           "tim-at-provider",
+	Email:             "tim@example.com",
+	EmailVerified:     true,
+	PreferredUsername: "tim",
+}
+
+// an OIDCProvider for a fresh mock provider
+func newTestOIDCProvider(t *testing.T) (*OIDCProvider, *oidctest.Server) {
+	t.Helper()
+	server, err := oidctest.NewServer("net-go", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+	provider, err := NewOIDCProvider(OIDCConfig{
+		Issuer:       server.URL,
+		ClientID:     "net-go",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost/api/accounts/oidc/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider, server
+}
+
+// signs `user` in at the mock provider for `req`, returning the code they come back with
+func authorize(t *testing.T, provider *OIDCProvider, server *oidctest.Server, req *AuthRequest, user oidctest.User) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.TODO(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback, err := server.Authorize(authURL, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(callback)
+	assert.Equal(t, req.State, u.Query().Get("state"))
+	return u.Query().Get("code")
+}
+
+func TestOIDCProvider(t *testing.T) {
+	t.Run("Signs in through the provider", func(t *testing.T) {
+		provider, server := newTestOIDCProvider(t)
+		req, err := NewAuthRequest()
+		assert.NoError(t, err)
+
+		code := authorize(t, provider, server, req, tim)
+		claims, err := provider.Exchange(context.TODO(), code, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, &Claims{
+			Issuer:            server.URL,
+			Subject:           "tim-at-provider",
+			Email:             "tim@example.com",
+			EmailVerified:     true,
+			PreferredUsername: "tim",
+		}, claims)
+	})
+
+	t.Run("Asks for openid scope, a nonce and a PKCE challenge", func(t *testing.T) {
+		provider, _ := newTestOIDCProvider(t)
+		req, _ := NewAuthRequest()
+
+		authURL, err := provider.AuthCodeURL(context.TODO(), req)
+
+		assert.NoError(t, err)
+		u, _ := url.Parse(authURL)
+		assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+		assert.Equal(t, req.Nonce, u.Query().Get("nonce"))
+		assert.Equal(t, "S256", u.Query().Get("code_challenge_method"))
+		assert.NotContains(t, authURL, req.Verifier)
+	})
+
+	t.Run("Codes only work with their own verifier", func(t *testing.T) {
+		provider, server := newTestOIDCProvider(t)
+		req, _ := NewAuthRequest()
+		code := authorize(t, provider, server, req, tim)
+
+		other, _ := NewAuthRequest()
+		_, err := provider.Exchange(context.TODO(), code, &AuthRequest{State: req.State, Nonce: req.Nonce, Verifier: other.Verifier})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("ID tokens only work for their own sign in", func(t *testing.T) {
+		provider, server := newTestOIDCProvider(t)
+		req, _ := NewAuthRequest()
+		code := authorize(t, provider, server, req, tim)
+
+		_, err := provider.Exchange(context.TODO(), code, &AuthRequest{State: req.State, Nonce: "another", Verifier: req.Verifier})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Codes are single use", func(t *testing.T) {
+		provider, server := newTestOIDCProvider(t)
+		req, _ := NewAuthRequest()
+		code := authorize(t, provider, server, req, tim)
+
+		_, err := provider.Exchange(context.TODO(), code, req)
+		assert.NoError(t, err)
+		_, err = provider.Exchange(context.TODO(), code, req)
+		assert.Error(t, err)
+	})
+
+	t.Run("Unreachable issuer", func(t *testing.T) {
+		provider, server := newTestOIDCProvider(t)
+		server.Close()
+		req, _ := NewAuthRequest()
+
+		_, err := provider.AuthCodeURL(context.TODO(), req)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("Config needs an issuer, client and redirect", func(t *testing.T) {
+		_, err := NewOIDCProvider(OIDCConfig{ClientID: "net-go", RedirectURL: "http://localhost/cb"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,136 @@
+package instrumentation
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"gorm.io/gorm"
+)
+
+/*
+ * Prometheus metrics, served at /metrics on the internal METRICS_ADDR
+ * listener for environments that scrape rather than consume OTLP. They
+ * are registered on the default registry, which also reports the go
+ * runtime and process metrics.
+ */
+
+var (
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "netgo",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle HTTP requests, by gin route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	GamesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "netgo",
+		Name:      "games_created_total",
+		Help:      "Number of games created.",
+	})
+
+	GamesFinished = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "netgo",
+		Name:      "games_finished_total",
+		Help:      "Number of games that have ended.",
+	})
+
+	// moves per minute is `rate(netgo_moves_total[1m]) * 60`
+	Moves = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "netgo",
+		Name:      "moves_total",
+		Help:      "Number of moves (plays and passes) made.",
+	})
+
+	SigninFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "netgo",
+		Name:      "signin_failures_total",
+		Help:      "Number of failed signin attempts, by cause.",
+	}, []string{"reason"})
+
+	DbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "netgo",
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Time taken by gorm db operations, by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+)
+
+// label for requests that matched no gin route (e.g. the Elm app's own routes)
+const UnmatchedRoute = "unmatched"
+
+/**
+ * Reports the number of requests waiting on realtime (long-poll) game
+ * updates, as given by `count` at scrape time. Call at most once.
+ */
+func RegisterLongPollSubscribers(count func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "netgo",
+		Name:      "longpoll_subscribers",
+		Help:      "Number of requests currently waiting on game updates.",
+	}, func() float64 {
+		return float64(count())
+	})
+}
+
+/* gorm plugin */
+
+const dbMetricsStartKey = "netgo:metrics_start"
+
+// gorm plugin recording the duration of every db operation in DbQueryDuration
+type dbMetricsPlugin struct{}
+
+func (dbMetricsPlugin) Name() string {
+	return "netgo:metrics"
+}
+
+func (p dbMetricsPlugin) Initialize(db *gorm.DB) error {
+	type registerFunc = func(name string, fn func(*gorm.DB)) error
+	callbacks := []struct {
+		operation     string
+		before, after registerFunc
+	}{
+		{"create", db.Callback().Create().Before("*").Register, db.Callback().Create().After("*").Register},
+		{"query", db.Callback().Query().Before("*").Register, db.Callback().Query().After("*").Register},
+		{"update", db.Callback().Update().Before("*").Register, db.Callback().Update().After("*").Register},
+		{"delete", db.Callback().Delete().Before("*").Register, db.Callback().Delete().After("*").Register},
+		{"row", db.Callback().Row().Before("*").Register, db.Callback().Row().After("*").Register},
+		{"raw", db.Callback().Raw().Before("*").Register, db.Callback().Raw().After("*").Register},
+	}
+	for _, callback := range callbacks {
+		if err := callback.before("netgo:metrics_before_"+callback.operation, startDbTimer); err != nil {
+			return err
+		}
+		if err := callback.after("netgo:metrics_after_"+callback.operation, observeDbTimer(callback.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startDbTimer(db *gorm.DB) {
+	db.InstanceSet(dbMetricsStartKey, time.Now())
+}
+
+func observeDbTimer(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(dbMetricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		DbQueryDuration.
+			WithLabelValues(operation, db.Statement.Table).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// Records the duration of every operation on `db` in DbQueryDuration.
+func InstrumentDbMetrics(db *gorm.DB) error {
+	return db.Use(dbMetricsPlugin{})
+}
@@ -0,0 +1,45 @@
+package instrumentation
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type metricsTestRow struct {
+	ID   uint
+	Name string
+}
+
+// number of observations of DbQueryDuration for `operation` on the test table
+func dbQueryCount(t *testing.T, operation string) uint64 {
+	var metric dto.Metric
+	observer := DbQueryDuration.WithLabelValues(operation, "metrics_test_rows")
+	assert.NoError(t, observer.(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestInstrumentDbMetrics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&metricsTestRow{}))
+	assert.NoError(t, InstrumentDbMetrics(db))
+
+	row := metricsTestRow{Name: "tim"}
+	assert.NoError(t, db.Create(&row).Error)
+	assert.NoError(t, db.First(&metricsTestRow{}, row.ID).Error)
+	assert.NoError(t, db.Model(&row).Update("name", "sally").Error)
+	assert.NoError(t, db.Delete(&row).Error)
+
+	for _, operation := range []string{"create", "query", "update", "delete"} {
+		assert.Equal(t, uint64(1), dbQueryCount(t, operation), operation)
+	}
+}
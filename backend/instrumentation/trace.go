@@ -19,13 +19,15 @@ func InstrumentDbConnection(db *gorm.DB) {
 }
 
 /**
- * Starts a trace span with the given `name`.
+ * Starts a trace span with the given `name`, as a child of the span
+ * in `ctx` (e.g. the otelgin request span) if there is one.
  *
- * @returns ctx - a Context that should be used with gorm.DB calls to be traced
+ * @returns ctx - a Context that should be used with gorm.DB calls to be traced;
+ *               it is cancelled along with `ctx`
  * 		 endSpan - a function for ending the trace span
  */
-func StartDbTrace(name string) (context.Context, func()) {
+func StartDbTrace(ctx context.Context, name string) (context.Context, func()) {
 	trace := otel.Tracer(constants.GetOtelServiceName())
-	ctx, span := trace.Start(context.Background(), name)
+	ctx, span := trace.Start(ctx, name)
 	return ctx, func() { span.End() }
 }
@@ -0,0 +1,43 @@
+package instrumentation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStartDbTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(prevProvider) })
+
+	t.Run("span is a child of the request span", func(t *testing.T) {
+		reqCtx, reqSpan := otel.Tracer("test").Start(context.Background(), "GET /api/games/:id")
+
+		_, endSpan := StartDbTrace(reqCtx, "GameRepository.FindByID")
+		endSpan()
+		reqSpan.End()
+
+		spans := recorder.Ended()
+		assert.Len(t, spans, 2)
+		dbSpan := spans[0]
+		assert.Equal(t, "GameRepository.FindByID", dbSpan.Name())
+		assert.Equal(t, reqSpan.SpanContext().TraceID(), dbSpan.SpanContext().TraceID())
+		assert.Equal(t, reqSpan.SpanContext().SpanID(), dbSpan.Parent().SpanID())
+	})
+
+	t.Run("cancelling the request cancels the db ctx", func(t *testing.T) {
+		reqCtx, cancel := context.WithCancel(context.Background())
+
+		ctx, endSpan := StartDbTrace(reqCtx, "GameRepository.FindByID")
+		defer endSpan()
+		cancel()
+
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+}
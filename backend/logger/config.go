@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+/**
+ * Limits how often the same message is logged: within each Tick, the
+ * first Initial entries with a given level and message are logged, then
+ * only every Thereafter-th one. An Initial of 0 disables sampling.
+ */
+type Sampling struct {
+	Initial    int           `json:"initial"`
+	Thereafter int           `json:"thereafter"`
+	Tick       time.Duration `json:"tick"`
+}
+
+func (s Sampling) enabled() bool {
+	return s.Initial > 0
+}
+
+// the minimum level logged; changing it takes effect immediately
+var level = zap.NewAtomicLevelAt(zapcore.InfoLevel)
+
+func SetLevel(l zapcore.Level) {
+	level.SetLevel(l)
+}
+
+func GetLevel() zapcore.Level {
+	return level.Level()
+}
+
+// state shared by every logger built by New
+type root struct {
+	base     zapcore.Core
+	sampling atomic.Pointer[Sampling]
+	// `base`, wrapped in a sampler for the current sampling config
+	core atomic.Pointer[zapcore.Core]
+}
+
+// the root of the logger built by New; nil until then
+var current atomic.Pointer[root]
+
+/**
+ * Builds a logger writing to `base` (which should accept every level),
+ * whose level and sampling can be changed while running through
+ * SetLevel/SetSampling. Install it with zap.ReplaceGlobals.
+ */
+func New(base zapcore.Core, sampling Sampling, options ...zap.Option) *zap.Logger {
+	r := &root{base: base}
+	r.setSampling(sampling)
+	current.Store(r)
+	return zap.New(&dynamicCore{root: r}, options...)
+}
+
+// Replaces the sampling config of the logger built by New.
+func SetSampling(sampling Sampling) {
+	if r := current.Load(); r != nil {
+		r.setSampling(sampling)
+	}
+}
+
+func GetSampling() Sampling {
+	if r := current.Load(); r != nil {
+		return *r.sampling.Load()
+	}
+	return Sampling{}
+}
+
+func (r *root) setSampling(sampling Sampling) {
+	core := r.base
+	if sampling.enabled() {
+		tick := sampling.Tick
+		if tick <= 0 {
+			tick = time.Second
+		}
+		core = zapcore.NewSamplerWithOptions(core, tick, sampling.Initial, sampling.Thereafter)
+	}
+	r.sampling.Store(&sampling)
+	r.core.Store(&core)
+}
+
+/**
+ * Core that filters by the package level and writes through the root's
+ * current (possibly sampled) core, so config changes also apply to
+ * loggers derived before the change (e.g. request loggers).
+ */
+type dynamicCore struct {
+	root   *root
+	fields []zapcore.Field
+}
+
+func (c *dynamicCore) Enabled(l zapcore.Level) bool {
+	return level.Enabled(l)
+}
+
+func (c *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	combined := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	combined = append(combined, c.fields...)
+	combined = append(combined, fields...)
+	return &dynamicCore{root: c.root, fields: combined}
+}
+
+func (c *dynamicCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(entry.Level) {
+		return checked
+	}
+	return c.core().Check(entry, checked)
+}
+
+func (c *dynamicCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.core().Write(entry, fields)
+}
+
+func (c *dynamicCore) Sync() error {
+	return (*c.root.core.Load()).Sync()
+}
+
+func (c *dynamicCore) core() zapcore.Core {
+	core := *c.root.core.Load()
+	if len(c.fields) > 0 {
+		core = core.With(c.fields)
+	}
+	return core
+}
+
+/**
+ * Serves the logger config as JSON. GET /level and PUT /level
+ * ({"level": "debug"}) read and set the level; GET /sampling and
+ * PUT /sampling ({"initial": 100, "thereafter": 100, "tick": 1000000000})
+ * read and set sampling. Meant for an internal admin listener only.
+ */
+func AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/level", level)
+	mux.HandleFunc("/sampling", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var sampling Sampling
+			if err := json.NewDecoder(r.Body).Decode(&sampling); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if sampling.Initial < 0 || sampling.Thereafter < 0 {
+				http.Error(w, "sampling values must not be negative", http.StatusBadRequest)
+				return
+			}
+			SetSampling(sampling)
+		default:
+			http.Error(w, "only GET and PUT are supported", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(GetSampling())
+	})
+	return mux
+}
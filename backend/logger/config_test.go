@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// logger built by New on an observed core, with level/sampling reset afterwards
+func newObservedLogger(t *testing.T, sampling Sampling) (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	prevLevel := GetLevel()
+	t.Cleanup(func() { SetLevel(prevLevel) })
+	return New(core, sampling), logs
+}
+
+func TestNew(t *testing.T) {
+	t.Run("level changes apply to derived loggers", func(t *testing.T) {
+		l, logs := newObservedLogger(t, Sampling{})
+		SetLevel(zapcore.InfoLevel)
+		reqLogger := l.With(zap.String("route", "/api/games/:id"))
+
+		reqLogger.Debug("hidden")
+		SetLevel(zapcore.DebugLevel)
+		reqLogger.Debug("shown")
+
+		assert.Equal(t, 1, logs.Len())
+		entry := logs.All()[0]
+		assert.Equal(t, "shown", entry.Message)
+		assert.Equal(t, "/api/games/:id", entry.ContextMap()["route"])
+	})
+
+	t.Run("sampling drops repeated messages", func(t *testing.T) {
+		l, logs := newObservedLogger(t, Sampling{Initial: 2, Thereafter: 0})
+		SetLevel(zapcore.DebugLevel)
+
+		for i := 0; i < 5; i++ {
+			l.Info("repeated")
+		}
+		l.Info("other")
+
+		assert.Equal(t, 2, logs.FilterMessage("repeated").Len())
+		assert.Equal(t, 1, logs.FilterMessage("other").Len())
+	})
+
+	t.Run("sampling can be turned off at runtime", func(t *testing.T) {
+		l, logs := newObservedLogger(t, Sampling{Initial: 1, Thereafter: 0})
+		SetLevel(zapcore.DebugLevel)
+		reqLogger := l.With(zap.Uint("user_id", 1))
+
+		reqLogger.Info("repeated")
+		reqLogger.Info("repeated")
+		SetSampling(Sampling{})
+		reqLogger.Info("repeated")
+
+		assert.Equal(t, 2, logs.Len())
+		assert.Equal(t, Sampling{}, GetSampling())
+	})
+}
+
+func TestAdminHandler(t *testing.T) {
+	newObservedLogger(t, Sampling{})
+	handler := AdminHandler()
+
+	t.Run("sets the level", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(`{"level":"warn"}`))
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, zapcore.WarnLevel, GetLevel())
+	})
+
+	t.Run("sets sampling", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/sampling", strings.NewReader(`{"initial":10,"thereafter":5}`))
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"initial":10,"thereafter":5,"tick":0}`, rr.Body.String())
+		assert.Equal(t, Sampling{Initial: 10, Thereafter: 5}, GetSampling())
+	})
+
+	t.Run("rejects negative sampling", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/sampling", strings.NewReader(`{"initial":-1}`))
+
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestContextLogger(t *testing.T) {
+	t.Run("falls back to the global logger", func(t *testing.T) {
+		assert.Equal(t, zap.L(), FromContext(context.Background()))
+	})
+
+	t.Run("helpers log with the ctx fields", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		ctx := WithLogger(context.Background(), zap.New(core))
+		ctx = WithFields(ctx, zap.String("trace_id", "abc"))
+		ctx = WithFields(ctx, zap.Uint("game_id", 7))
+
+		InfoCtx(ctx, "updated game", zap.Uint("version", 2))
+		ErrorCtx(context.Background(), "not captured")
+
+		assert.Equal(t, 1, logs.Len())
+		entry := logs.All()[0]
+		assert.Equal(t, "updated game", entry.Message)
+		assert.Equal(t, map[string]interface{}{
+			"trace_id": "abc",
+			"game_id":  uint64(7),
+			"version":  uint64(2),
+		}, entry.ContextMap())
+	})
+
+	t.Run("requests don't share fields", func(t *testing.T) {
+		core, logs := observer.New(zapcore.DebugLevel)
+		base := WithLogger(context.Background(), zap.New(core))
+		first := WithFields(base, zap.String("trace_id", "first"))
+		second := WithFields(base, zap.String("trace_id", "second"))
+
+		InfoCtx(first, "a")
+		InfoCtx(second, "b")
+
+		assert.Equal(t, "first", logs.All()[0].ContextMap()["trace_id"])
+		assert.Equal(t, "second", logs.All()[1].ContextMap()["trace_id"])
+	})
+}
@@ -0,0 +1,30 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	t.Run("Writes each message to its own file, in order", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "mail")
+		m, err := NewFileMailer(dir)
+		assert.NoError(t, err)
+
+		assert.NoError(t, m.Send(context.TODO(), Message{To: "tim", Subject: "first", Body: "hello"}))
+		assert.NoError(t, m.Send(context.TODO(), Message{To: "tom", Subject: "second", Body: "bye"}))
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), "To: tim\r\n")
+		assert.Contains(t, string(content), "Subject: first\r\n")
+		assert.Contains(t, string(content), "\r\n\r\nhello")
+	})
+}
@@ -0,0 +1,21 @@
+package mailer
+
+import (
+	"context"
+)
+
+// an email to send
+type Message struct {
+	To      string
+	Subject string
+	// plain text
+	Body string
+}
+
+/**
+ * Delivers emails to users, e.g. password reset links. Implementations
+ * range from just logging messages (local development) to real SMTP.
+ */
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
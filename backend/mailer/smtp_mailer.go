@@ -0,0 +1,135 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// how long a send may take when the context has no deadline of its own
+const smtpSendTimeout = 30 * time.Second
+
+type SMTPConfig struct {
+	// host:port of the SMTP server
+	Addr string
+	// optional; without it the server must accept mail unauthenticated
+	Username string
+	Password string
+	// sender of every message, e.g. "net-go <noreply@example.com>"
+	From string
+}
+
+/**
+ * Mailer that relays messages through an SMTP server. It upgrades to TLS
+ * whenever the server offers STARTTLS; net/smtp refuses to send the
+ * password over a plain connection to anything but localhost.
+ */
+type SMTPMailer struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from *mail.Address
+	now  func() time.Time
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("bad SMTP address %q: %w", cfg.Addr, err)
+	}
+	if cfg.From == "" {
+		return nil, errors.New("no sender address for SMTP mailer")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("bad sender address %q: %w", cfg.From, err)
+	}
+	m := &SMTPMailer{
+		addr: cfg.Addr,
+		host: host,
+		from: from,
+		now:  time.Now,
+	}
+	if cfg.Username != "" {
+		m.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = m.now().Add(smtpSendTimeout)
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return err
+		}
+	}
+	if m.auth != nil {
+		if err := client.Auth(m.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.format(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message `msg` as headers and body; the data writer takes care of dot stuffing
+func (m *SMTPMailer) format(msg Message) []byte {
+	var b strings.Builder
+	header := func(name string, value string) {
+		// no line breaks, or a value could smuggle in headers of its own
+		value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", m.from.String())
+	header("To", msg.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", m.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,158 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// what a fakeSMTPServer was sent in one session
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+/**
+ * Just enough of an SMTP server on localhost to take one message per
+ * connection. Sessions are sent on the returned channel once they QUIT.
+ */
+func fakeSMTPServer(t *testing.T, withAuth bool) (string, <-chan smtpSession) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	sessions := make(chan smtpSession, 1)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(textproto.NewConn(conn), withAuth, sessions)
+		}
+	}()
+	return listener.Addr().String(), sessions
+}
+
+func serveSMTP(conn *textproto.Conn, withAuth bool, sessions chan<- smtpSession) {
+	defer conn.Close()
+	var session smtpSession
+	conn.PrintfLine("220 localhost fake SMTP")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			if withAuth {
+				conn.PrintfLine("250-localhost")
+				conn.PrintfLine("250 AUTH PLAIN")
+			} else {
+				conn.PrintfLine("250 localhost")
+			}
+		case "AUTH":
+			_, creds, _ := strings.Cut(arg, " ")
+			decoded, _ := base64.StdEncoding.DecodeString(creds)
+			session.auth = string(decoded)
+			conn.PrintfLine("235 accepted")
+		case "MAIL":
+			session.from = arg
+			conn.PrintfLine("250 ok")
+		case "RCPT":
+			session.to = append(session.to, arg)
+			conn.PrintfLine("250 ok")
+		case "DATA":
+			conn.PrintfLine("354 go ahead")
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			session.data = string(data)
+			conn.PrintfLine("250 queued")
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			sessions <- session
+			return
+		default:
+			conn.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	t.Run("Sends the message", func(t *testing.T) {
+		addr, sessions := fakeSMTPServer(t, false)
+		m, err := NewSMTPMailer(SMTPConfig{Addr: addr, From: "net-go <noreply@example.com>"})
+		assert.NoError(t, err)
+
+		err = m.Send(context.TODO(), Message{
+			To:      "tim@example.com",
+			Subject: "Verify your email",
+			Body:    "hello\n.dot line",
+		})
+
+		assert.NoError(t, err)
+		session := <-sessions
+		assert.Equal(t, "", session.auth)
+		assert.Equal(t, "FROM:<noreply@example.com>", session.from)
+		assert.Equal(t, []string{"TO:<tim@example.com>"}, session.to)
+		assert.Contains(t, session.data, "From: \"net-go\" <noreply@example.com>\n")
+		assert.Contains(t, session.data, "To: tim@example.com\n")
+		assert.Contains(t, session.data, "Subject: Verify your email\n")
+		assert.Contains(t, session.data, "Content-Type: text/plain; charset=utf-8\n")
+		assert.Contains(t, session.data, "\n\nhello\n.dot line\n")
+	})
+
+	t.Run("Authenticates when given a username", func(t *testing.T) {
+		addr, sessions := fakeSMTPServer(t, true)
+		m, err := NewSMTPMailer(SMTPConfig{Addr: addr, Username: "user", Password: "pass", From: "noreply@example.com"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, m.Send(context.TODO(), Message{To: "tim@example.com", Subject: "hi", Body: "hello"}))
+
+		session := <-sessions
+		assert.Equal(t, "\x00user\x00pass", session.auth)
+	})
+
+	t.Run("Header values can't add headers", func(t *testing.T) {
+		addr, sessions := fakeSMTPServer(t, false)
+		m, err := NewSMTPMailer(SMTPConfig{Addr: addr, From: "noreply@example.com"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, m.Send(context.TODO(), Message{To: "tim@example.com", Subject: "hi\r\nBcc: tom@example.com", Body: "hello"}))
+
+		session := <-sessions
+		assert.NotContains(t, session.data, "\nBcc:")
+	})
+
+	t.Run("Server unreachable", func(t *testing.T) {
+		addr, _ := fakeSMTPServer(t, false)
+		m, err := NewSMTPMailer(SMTPConfig{Addr: addr, From: "noreply@example.com"})
+		assert.NoError(t, err)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.Error(t, m.Send(ctx, Message{To: "tim@example.com", Subject: "hi", Body: "hello"}))
+	})
+
+	t.Run("Config needs an address and sender", func(t *testing.T) {
+		_, err := NewSMTPMailer(SMTPConfig{Addr: "localhost", From: "noreply@example.com"})
+		assert.Error(t, err)
+		_, err = NewSMTPMailer(SMTPConfig{Addr: "localhost:25"})
+		assert.Error(t, err)
+		_, err = NewSMTPMailer(SMTPConfig{Addr: "localhost:25", From: "not an address"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,227 @@
+package migrations
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+/*
+ * Snapshots of the models as of this migration. Later migrations must
+ * not depend on the live models in `model`, since those keep changing
+ * after the migration is written. The column types of `model/types`
+ * are copied below for the same reason.
+ */
+
+// JSON column, like types.PlayArea and model.MoveSlice
+type baselineJSON []byte
+
+func (j baselineJSON) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+func (j *baselineJSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append(baselineJSON(nil), v...)
+	case string:
+		*j = baselineJSON(v)
+	default:
+		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	}
+	return nil
+}
+
+func (baselineJSON) GormDataType() string {
+	return "json"
+}
+
+func (baselineJSON) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "postgres" {
+		return "JSONB"
+	}
+	return "JSON"
+}
+
+// one entry of games.history, which is stored newest first
+type baselineMove struct {
+	MoveType uint `json:"moveType"`
+	Piece    int  `json:"piece"`
+	Coord    uint `json:"coord"`
+}
+
+// values of types.Piece and types.ColorChoice
+const (
+	baselineWhiteStone = -1
+	baselineBlackStone = 1
+	baselineBlack      = "black"
+	baselineWhite      = "white"
+)
+
+type baselineUser struct {
+	gorm.Model
+	Username     string `gorm:"uniqueIndex"`
+	Password     string
+	SessionToken string
+}
+
+func (baselineUser) TableName() string {
+	return "users"
+}
+
+type baselineGame struct {
+	gorm.Model
+	History           baselineJSON
+	BoardSize         uint `gorm:"type:integer"`
+	BoardMap          baselineJSON
+	IsOver            bool
+	ScoreForfeitColor *string `gorm:"type:varchar(32)"`
+	ScoreBlackPoints  float32
+	ScoreWhitePoints  float32
+	ScoreKomi         float32
+	BlackPlayerId     uint         `gorm:"index"`
+	WhitePlayerId     uint         `gorm:"index"`
+	BlackPlayer       baselineUser `gorm:"foreignKey:BlackPlayerId;"`
+	WhitePlayer       baselineUser `gorm:"foreignKey:WhitePlayerId;"`
+	MoveCount         uint
+	ActivePlayerId    uint `gorm:"index"`
+	Version           uint `gorm:"not null;default:1"`
+}
+
+/**
+ * Decodes the history column. Games saved without a history
+ * (NULL) have no moves.
+ */
+func (g baselineGame) moves() ([]baselineMove, error) {
+	var moves []baselineMove
+	if len(g.History) == 0 {
+		return moves, nil
+	}
+	err := json.Unmarshal(g.History, &moves)
+	return moves, err
+}
+
+func (baselineGame) TableName() string {
+	return "games"
+}
+
+type baselineGameMove struct {
+	ID         uint   `gorm:"primarykey"`
+	GameID     uint   `gorm:"uniqueIndex:idx_game_moves_game_number;not null"`
+	MoveNumber uint   `gorm:"uniqueIndex:idx_game_moves_game_number;not null"`
+	Color      string `gorm:"type:varchar(32);not null"`
+	MoveType   uint   `gorm:"type:integer;not null"`
+	Coord      uint
+	PlayedAt   *time.Time
+	TimeLeftMs *int64
+}
+
+func (baselineGameMove) TableName() string {
+	return "game_moves"
+}
+
+/**
+ * The schema as it was when migrations were introduced. Databases that
+ * were set up by the old startup AutoMigrate are adopted as-is (missing
+ * columns are added), and games saved before the turn state columns or
+ * the game_moves table existed are backfilled.
+ */
+var baselineSchema = Migration{
+	Version: 1,
+	Name:    "baseline_schema",
+	Up: func(tx *gorm.DB) error {
+		err := tx.Migrator().AutoMigrate(&baselineUser{}, &baselineGame{}, &baselineGameMove{})
+		if err != nil {
+			return err
+		}
+		if err := backfillTurnState(tx); err != nil {
+			return err
+		}
+		return backfillGameMoves(tx)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&baselineGameMove{}, &baselineGame{}, &baselineUser{})
+	},
+}
+
+/**
+ * Populates the denormalized turn columns for games saved before
+ * they existed (the columns are NULL for those rows). UpdateColumns
+ * is used so UpdatedAt (the time of the last move) is left untouched.
+ */
+func backfillTurnState(tx *gorm.DB) error {
+	var games []baselineGame
+	return tx.
+		Where("COALESCE(move_count, 0) = ? AND COALESCE(active_player_id, 0) = ?", 0, 0).
+		FindInBatches(&games, 100, func(_ *gorm.DB, _ int) error {
+			for _, game := range games {
+				moves, err := game.moves()
+				if err != nil {
+					return err
+				}
+				// same as model.Game.SyncTurnState, copied so this
+				// migration never changes
+				activePlayerId := game.BlackPlayerId
+				if game.IsOver {
+					activePlayerId = 0
+				} else if len(moves) > 0 && moves[0].Piece == baselineBlackStone {
+					activePlayerId = game.WhitePlayerId
+				}
+				err = tx.Model(&game).UpdateColumns(map[string]interface{}{
+					"move_count":       len(moves),
+					"active_player_id": activePlayerId,
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
+
+/**
+ * Populates game_moves from the History of games saved before
+ * the table existed. Play times of those moves are unknown.
+ */
+func backfillGameMoves(tx *gorm.DB) error {
+	var games []baselineGame
+	return tx.
+		Where("move_count > ?", 0).
+		Where("NOT EXISTS (SELECT 1 FROM game_moves WHERE game_moves.game_id = games.id)").
+		FindInBatches(&games, 100, func(_ *gorm.DB, _ int) error {
+			moves := make([]baselineGameMove, 0)
+			for _, game := range games {
+				history, err := game.moves()
+				if err != nil {
+					return err
+				}
+				// history is newest first, move numbers start at the oldest
+				for i, move := range history {
+					color := baselineBlack
+					if move.Piece == baselineWhiteStone {
+						color = baselineWhite
+					}
+					moves = append(moves, baselineGameMove{
+						GameID:     game.ID,
+						MoveNumber: uint(len(history) - i),
+						Color:      color,
+						MoveType:   move.MoveType,
+						Coord:      move.Coord,
+					})
+				}
+			}
+			if len(moves) == 0 {
+				return nil
+			}
+			return tx.CreateInBatches(&moves, 500).Error
+		}).Error
+}
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+	"net-go/server/backend/model/types"
+)
+
+// games table as created by the startup AutoMigrate, before the turn state columns
+type legacyGame struct {
+	gorm.Model
+	History       model.MoveSlice
+	Board         types.Board `gorm:"embedded;embeddedPrefix:board_"`
+	IsOver        bool
+	Score         types.Score `gorm:"embedded;embeddedPrefix:score_"`
+	BlackPlayerId uint        `gorm:"index"`
+	WhitePlayerId uint        `gorm:"index"`
+}
+
+func (legacyGame) TableName() string {
+	return "games"
+}
+
+func TestBaselineSchema(t *testing.T) {
+	t.Run("Creates the schema and rolls it back", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{baselineSchema})
+
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+		for _, table := range []string{"users", "games", "game_moves"} {
+			assert.True(t, db.Migrator().HasTable(table), table)
+		}
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		for _, table := range []string{"users", "games", "game_moves"} {
+			assert.False(t, db.Migrator().HasTable(table), table)
+		}
+	})
+
+	t.Run("Adopts and backfills a database from before migrations", func(t *testing.T) {
+		db := newTestDb(t)
+		assert.NoError(t, db.AutoMigrate(&baselineUser{}, &legacyGame{}))
+		black := baselineUser{Username: "tim"}
+		white := baselineUser{Username: "tom"}
+		assert.NoError(t, db.Create(&black).Error)
+		assert.NoError(t, db.Create(&white).Error)
+		game := legacyGame{
+			// newest first
+			History: model.MoveSlice{
+				{MoveType: types.PlayPiece, Coord: 5, Piece: types.BlackStone},
+				{MoveType: types.Pass, Piece: types.WhiteStone},
+				{MoveType: types.PlayPiece, Coord: 3, Piece: types.BlackStone},
+			},
+			BlackPlayerId: black.ID,
+			WhitePlayerId: white.ID,
+		}
+		assert.NoError(t, db.Create(&game).Error)
+
+		_, err := NewMigrator(db, All).Up()
+		assert.NoError(t, err)
+
+		var migrated baselineGame
+		assert.NoError(t, db.First(&migrated, game.ID).Error)
+		assert.Equal(t, uint(3), migrated.MoveCount)
+		assert.Equal(t, white.ID, migrated.ActivePlayerId)
+		assert.Equal(t, uint(1), migrated.Version)
+		assert.True(t, game.UpdatedAt.Equal(migrated.UpdatedAt))
+
+		var moves []baselineGameMove
+		assert.NoError(t, db.Order("move_number").Find(&moves, "game_id = ?", game.ID).Error)
+		assert.Len(t, moves, 3)
+		assert.Equal(t, types.White.ToString(), moves[1].Color)
+		assert.Equal(t, uint(types.Pass), moves[1].MoveType)
+		assert.Equal(t, uint(3), moves[0].Coord)
+		assert.Equal(t, uint(5), moves[2].Coord)
+		assert.Nil(t, moves[2].PlayedAt)
+	})
+}
@@ -0,0 +1,73 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type sessionsSession struct {
+	ID         uint   `gorm:"primarykey"`
+	UserID     uint   `gorm:"index;not null"`
+	TokenHash  string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	UserAgent  string `gorm:"size:512"`
+	IP         string `gorm:"size:64"`
+}
+
+func (sessionsSession) TableName() string {
+	return "sessions"
+}
+
+/**
+ * Adds the sessions table, so users can be signed in on several devices.
+ * The single session each user had (users.session_token) is carried
+ * over, so nobody is signed out by the upgrade.
+ */
+var sessions = Migration{
+	Version: 2,
+	Name:    "sessions",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&sessionsSession{}); err != nil {
+			return err
+		}
+		return backfillSessions(tx)
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&sessionsSession{})
+	},
+}
+
+func backfillSessions(tx *gorm.DB) error {
+	var users []struct {
+		ID           uint
+		SessionToken string
+	}
+	err := tx.Table("users").
+		Select("id", "session_token").
+		Where("session_token <> '' AND deleted_at IS NULL").
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rows := make([]sessionsSession, 0, len(users))
+	for _, user := range users {
+		// same as model.HashToken, copied so this migration never changes
+		sum := sha256.Sum256([]byte(user.SessionToken))
+		rows = append(rows, sessionsSession{
+			UserID:     user.ID,
+			TokenHash:  hex.EncodeToString(sum[:]),
+			CreatedAt:  now,
+			LastSeenAt: now,
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(&rows, 500).Error
+}
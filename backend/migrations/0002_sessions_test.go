@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/model"
+)
+
+func TestSessions(t *testing.T) {
+	t.Run("Carries over each user's signed in session", func(t *testing.T) {
+		db := newTestDb(t)
+		_, err := NewMigrator(db, []Migration{baselineSchema}).Up()
+		assert.NoError(t, err)
+
+		signedIn := baselineUser{Username: "tim", SessionToken: "tims-token"}
+		signedOut := baselineUser{Username: "tom"}
+		assert.NoError(t, db.Create(&signedIn).Error)
+		assert.NoError(t, db.Create(&signedOut).Error)
+
+		_, err = NewMigrator(db, All).Up()
+		assert.NoError(t, err)
+
+		var sessions []sessionsSession
+		assert.NoError(t, db.Find(&sessions).Error)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, signedIn.ID, sessions[0].UserID)
+		assert.Equal(t, model.HashToken("tims-token"), sessions[0].TokenHash)
+		assert.False(t, sessions[0].LastSeenAt.IsZero())
+	})
+
+	t.Run("Rolls back", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{baselineSchema, sessions})
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+		assert.True(t, db.Migrator().HasTable("sessions"))
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		assert.False(t, db.Migrator().HasTable("sessions"))
+		assert.True(t, db.Migrator().HasTable("users"))
+	})
+}
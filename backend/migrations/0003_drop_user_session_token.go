@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type dropSessionTokenUser struct {
+	SessionToken string
+}
+
+func (dropSessionTokenUser) TableName() string {
+	return "users"
+}
+
+/**
+ * Drops the raw session tokens left in the users table; migration 2
+ * copied them (hashed) to the sessions table, which is all that's
+ * checked now. Rolling back restores the column, but not the tokens.
+ */
+var dropUserSessionToken = Migration{
+	Version: 3,
+	Name:    "drop_user_session_token",
+	Up: func(tx *gorm.DB) error {
+		if !tx.Migrator().HasColumn(&dropSessionTokenUser{}, "SessionToken") {
+			return nil
+		}
+		// not Migrator().DropColumn, which rebuilds the table on sqlite and
+		// trips the foreign keys on it; mysql and sqlite 3.35+ both do this
+		return tx.Exec("ALTER TABLE users DROP COLUMN session_token").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().AddColumn(&dropSessionTokenUser{}, "SessionToken")
+	},
+}
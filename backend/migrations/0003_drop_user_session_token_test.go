@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropUserSessionToken(t *testing.T) {
+	t.Run("Drops the raw tokens once they're moved to sessions", func(t *testing.T) {
+		db := newTestDb(t)
+		_, err := NewMigrator(db, []Migration{baselineSchema}).Up()
+		assert.NoError(t, err)
+		user := baselineUser{Username: "tim", SessionToken: "tims-token"}
+		assert.NoError(t, db.Create(&user).Error)
+
+		migrator := NewMigrator(db, []Migration{baselineSchema, sessions, dropUserSessionToken})
+		_, err = migrator.Up()
+		assert.NoError(t, err)
+
+		assert.False(t, db.Migrator().HasColumn("users", "session_token"))
+		var count int64
+		assert.NoError(t, db.Table("sessions").Where("user_id = ?", user.ID).Count(&count).Error)
+		assert.Equal(t, int64(1), count)
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		assert.True(t, db.Migrator().HasColumn("users", "session_token"))
+	})
+}
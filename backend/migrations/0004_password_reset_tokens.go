@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type passwordResetToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (passwordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// adds the password_reset_tokens table, for the password reset flow
+var passwordResetTokens = Migration{
+	Version: 4,
+	Name:    "password_reset_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&passwordResetToken{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&passwordResetToken{})
+	},
+}
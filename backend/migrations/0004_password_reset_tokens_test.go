@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetTokens(t *testing.T) {
+	t.Run("Creates the table and rolls it back", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{baselineSchema, sessions, dropUserSessionToken, passwordResetTokens})
+
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+		assert.True(t, db.Migrator().HasTable("password_reset_tokens"))
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		assert.False(t, db.Migrator().HasTable("password_reset_tokens"))
+	})
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userEmailsUser struct {
+	Email           *string `gorm:"uniqueIndex;size:254"`
+	EmailVerifiedAt *time.Time
+}
+
+func (userEmailsUser) TableName() string {
+	return "users"
+}
+
+type emailVerificationToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	Email     string `gorm:"size:254;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func (emailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
+
+/**
+ * Adds optional (verified) email addresses to users, and the
+ * email_verification_tokens table for verifying them.
+ */
+var userEmails = Migration{
+	Version: 5,
+	Name:    "user_emails",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, column := range []string{"Email", "EmailVerifiedAt"} {
+			if err := m.AddColumn(&userEmailsUser{}, column); err != nil {
+				return err
+			}
+		}
+		if err := m.CreateIndex(&userEmailsUser{}, "Email"); err != nil {
+			return err
+		}
+		return m.CreateTable(&emailVerificationToken{})
+	},
+	Down: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.DropTable(&emailVerificationToken{}); err != nil {
+			return err
+		}
+		if err := m.DropIndex(&userEmailsUser{}, "Email"); err != nil {
+			return err
+		}
+		// plain ALTER TABLE, like dropUserSessionToken, to keep sqlite's foreign keys intact
+		for _, column := range []string{"email_verified_at", "email"} {
+			if err := tx.Exec("ALTER TABLE users DROP COLUMN " + column).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserEmails(t *testing.T) {
+	t.Run("Adds unique emails to users and rolls them back", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{baselineSchema, sessions, dropUserSessionToken, passwordResetTokens, userEmails})
+
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+		assert.True(t, db.Migrator().HasColumn("users", "email"))
+		assert.True(t, db.Migrator().HasTable("email_verification_tokens"))
+
+		// users without an email don't collide
+		assert.NoError(t, db.Exec("INSERT INTO users (username) VALUES ('tim'), ('tom')").Error)
+		assert.NoError(t, db.Exec("UPDATE users SET email = 'tim@example.com' WHERE username = 'tim'").Error)
+		assert.Error(t, db.Exec("UPDATE users SET email = 'tim@example.com' WHERE username = 'tom'").Error)
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		assert.False(t, db.Migrator().HasColumn("users", "email"))
+		assert.False(t, db.Migrator().HasTable("email_verification_tokens"))
+	})
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type twoFactorUser struct {
+	TOTPSecret    *string `gorm:"size:64"`
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64 `gorm:"not null;default:0"`
+}
+
+func (twoFactorUser) TableName() string {
+	return "users"
+}
+
+type twoFactorTicket struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	Attempts  int `gorm:"not null;default:0"`
+}
+
+func (twoFactorTicket) TableName() string {
+	return "two_factor_tickets"
+}
+
+type recoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"size:64;not null"`
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
+
+func (recoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+/**
+ * Adds TOTP two-factor auth: the users' secrets, tickets for signins
+ * waiting on a code, and recovery codes.
+ */
+var twoFactor = Migration{
+	Version: 6,
+	Name:    "two_factor",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		for _, column := range []string{"TOTPSecret", "TOTPEnabledAt", "TOTPLastStep"} {
+			if err := m.AddColumn(&twoFactorUser{}, column); err != nil {
+				return err
+			}
+		}
+		return m.CreateTable(&twoFactorTicket{}, &recoveryCode{})
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable(&twoFactorTicket{}, &recoveryCode{}); err != nil {
+			return err
+		}
+		// plain ALTER TABLE, like dropUserSessionToken, to keep sqlite's foreign keys intact
+		for _, column := range []string{"totp_last_step", "totp_enabled_at", "totp_secret"} {
+			if err := tx.Exec("ALTER TABLE users DROP COLUMN " + column).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwoFactor(t *testing.T) {
+	t.Run("Adds 2FA columns and tables and rolls them back", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{baselineSchema, sessions, dropUserSessionToken, passwordResetTokens, userEmails, twoFactor})
+
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+		for _, column := range []string{"totp_secret", "totp_enabled_at", "totp_last_step"} {
+			assert.True(t, db.Migrator().HasColumn("users", column), column)
+		}
+		assert.True(t, db.Migrator().HasTable("two_factor_tickets"))
+		assert.True(t, db.Migrator().HasTable("recovery_codes"))
+
+		// existing users start with 2FA off
+		assert.NoError(t, db.Exec("INSERT INTO users (username) VALUES ('tim')").Error)
+		var lastStep int64
+		assert.NoError(t, db.Raw("SELECT totp_last_step FROM users WHERE username = 'tim'").Scan(&lastStep).Error)
+		assert.Equal(t, int64(0), lastStep)
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		assert.False(t, db.Migrator().HasColumn("users", "totp_secret"))
+		assert.False(t, db.Migrator().HasTable("two_factor_tickets"))
+		assert.False(t, db.Migrator().HasTable("recovery_codes"))
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userIdentity struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	Issuer    string `gorm:"uniqueIndex:idx_user_identities_issuer_subject;size:255;not null"`
+	Subject   string `gorm:"uniqueIndex:idx_user_identities_issuer_subject;size:255;not null"`
+	Email     string `gorm:"size:254"`
+	CreatedAt time.Time
+}
+
+func (userIdentity) TableName() string {
+	return "user_identities"
+}
+
+// adds the user_identities table, linking users to accounts at OIDC providers
+var userIdentities = Migration{
+	Version: 7,
+	Name:    "user_identities",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&userIdentity{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&userIdentity{})
+	},
+}
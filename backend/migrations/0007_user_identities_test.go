@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserIdentities(t *testing.T) {
+	t.Run("Creates the table and rolls it back", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{baselineSchema, sessions, dropUserSessionToken, passwordResetTokens, userEmails, twoFactor, userIdentities})
+
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+		assert.True(t, db.Migrator().HasTable("user_identities"))
+
+		// one link per account at a provider
+		insert := "INSERT INTO user_identities (user_id, issuer, subject) VALUES (?, 'https://idp.example.com', 'abc')"
+		assert.NoError(t, db.Exec(insert, 1).Error)
+		assert.Error(t, db.Exec(insert, 2).Error)
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		assert.False(t, db.Migrator().HasTable("user_identities"))
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type apiToken struct {
+	ID         uint   `gorm:"primarykey"`
+	UserID     uint   `gorm:"index;not null"`
+	Name       string `gorm:"size:100;not null"`
+	TokenHash  string `gorm:"uniqueIndex;size:64;not null"`
+	Scopes     string `gorm:"size:255;not null"`
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func (apiToken) TableName() string {
+	return "api_tokens"
+}
+
+// adds the api_tokens table, for personal access tokens
+var apiTokens = Migration{
+	Version: 8,
+	Name:    "api_tokens",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&apiToken{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&apiToken{})
+	},
+}
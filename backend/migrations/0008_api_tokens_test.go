@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokens(t *testing.T) {
+	t.Run("Creates the table and rolls it back", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{baselineSchema, sessions, dropUserSessionToken, passwordResetTokens, userEmails, twoFactor, userIdentities, apiTokens})
+
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+		assert.True(t, db.Migrator().HasTable("api_tokens"))
+
+		insert := "INSERT INTO api_tokens (user_id, name, token_hash, scopes) VALUES (?, 'bot', 'hash', 'games:read')"
+		assert.NoError(t, db.Exec(insert, 1).Error)
+		assert.Error(t, db.Exec(insert, 2).Error)
+
+		_, err = migrator.Down(1)
+		assert.NoError(t, err)
+		assert.False(t, db.Migrator().HasTable("api_tokens"))
+	})
+}
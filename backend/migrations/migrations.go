@@ -0,0 +1,17 @@
+package migrations
+
+/**
+ * Every schema migration, oldest first. To change the schema, add a
+ * new migration with the next version number rather than editing an
+ * existing one; applied migrations are never re-run.
+ */
+var All = []Migration{
+	baselineSchema,
+	sessions,
+	dropUserSessionToken,
+	passwordResetTokens,
+	userEmails,
+	twoFactor,
+	userIdentities,
+	apiTokens,
+}
@@ -0,0 +1,216 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * A single versioned schema change. Down must undo everything Up does,
+ * so the schema can be rolled back one migration at a time.
+ *
+ * Up/Down get a transaction; raw SQL (tx.Exec) and the gorm Migrator
+ * (tx.Migrator()) can both be used. Note that mysql implicitly commits
+ * DDL statements, so a migration that fails part way through can leave
+ * its earlier statements applied there.
+ */
+type Migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// row of the schema table, recording one applied migration
+type schemaMigration struct {
+	Version   uint `gorm:"primarykey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time // nil when pending
+}
+
+func (s MigrationStatus) IsApplied() bool {
+	return s.AppliedAt != nil
+}
+
+// returned by Migrator.CheckCurrent when there are migrations left to apply
+var ErrSchemaOutOfDate = errors.New("database schema is out of date")
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration // sorted by version
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration) *Migrator {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+	return &Migrator{
+		db:         db,
+		migrations: sorted,
+	}
+}
+
+/**
+ * Applies all pending migrations in version order, each in its own
+ * transaction. Returns the migrations that were applied; on error,
+ * the ones applied before the failing migration stay applied.
+ */
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+/**
+ * Rolls back the `steps` most recently applied migrations, newest
+ * first. Returns the migrations that were rolled back.
+ */
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if err := m.checkKnown(); err != nil {
+		return nil, err
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for i := len(statuses) - 1; i >= 0 && len(ran) < steps; i-- {
+		if !statuses[i].IsApplied() {
+			continue
+		}
+		migration := statuses[i].Migration
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return ran, fmt.Errorf("rollback of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		ran = append(ran, migration)
+	}
+	return ran, nil
+}
+
+// Lists every known migration in version order, with when it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+/**
+ * Returns an error wrapping ErrSchemaOutOfDate if any migration is
+ * pending, or an error if the database has a migration applied that
+ * is not known to this build (i.e. it was migrated by a newer build).
+ */
+func (m *Migrator) CheckCurrent() error {
+	if err := m.checkKnown(); err != nil {
+		return err
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if !status.IsApplied() {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d pending migration(s)", ErrSchemaOutOfDate, pending)
+	}
+	return nil
+}
+
+// errors if any applied migration is missing from m.migrations
+func (m *Migrator) checkKnown() error {
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return err
+	}
+	known := make(map[uint]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+	}
+	for version, row := range applied {
+		if !known[version] {
+			return fmt.Errorf("database has unknown migration %d (%s) applied", version, row.Name)
+		}
+	}
+	return nil
+}
+
+// applied migrations by version; empty if the schema table doesn't exist yet
+func (m *Migrator) appliedVersions() (map[uint]schemaMigration, error) {
+	applied := make(map[uint]schemaMigration)
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
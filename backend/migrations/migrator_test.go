@@ -0,0 +1,156 @@
+package migrations
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// opens an empty sqlite database in a temp dir
+func newTestDb(t *testing.T) *gorm.DB {
+	t.Helper()
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := gorm.Open(sqlite.Open("file:"+dbPath+"?_pragma=foreign_keys(1)"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDb, err := db.DB(); err == nil {
+			sqlDb.Close()
+		}
+	})
+	return db
+}
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func createWidgets(version uint) Migration {
+	return Migration{
+		Version: version,
+		Name:    "create_widgets",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY, name TEXT)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE widgets").Error
+		},
+	}
+}
+
+func addWidgetColor(version uint) Migration {
+	return Migration{
+		Version: version,
+		Name:    "add_widget_color",
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE widgets ADD COLUMN color TEXT").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE widgets DROP COLUMN color").Error
+		},
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	t.Run("Up applies pending migrations in order", func(t *testing.T) {
+		db := newTestDb(t)
+		// listed out of order on purpose
+		migrator := NewMigrator(db, []Migration{addWidgetColor(2), createWidgets(1)})
+
+		assert.ErrorIs(t, migrator.CheckCurrent(), ErrSchemaOutOfDate)
+		applied, err := migrator.Up()
+
+		assert.NoError(t, err)
+		assert.Len(t, applied, 2)
+		assert.Equal(t, uint(1), applied[0].Version)
+		assert.True(t, db.Migrator().HasColumn(&widget{}, "color"))
+		assert.NoError(t, migrator.CheckCurrent())
+
+		applied, err = migrator.Up()
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("Only new migrations are applied", func(t *testing.T) {
+		db := newTestDb(t)
+		_, err := NewMigrator(db, []Migration{createWidgets(1)}).Up()
+		assert.NoError(t, err)
+
+		migrator := NewMigrator(db, []Migration{createWidgets(1), addWidgetColor(2)})
+		assert.ErrorIs(t, migrator.CheckCurrent(), ErrSchemaOutOfDate)
+		applied, err := migrator.Up()
+
+		assert.NoError(t, err)
+		assert.Len(t, applied, 1)
+		assert.Equal(t, uint(2), applied[0].Version)
+	})
+
+	t.Run("Down rolls back most recent migrations", func(t *testing.T) {
+		db := newTestDb(t)
+		migrator := NewMigrator(db, []Migration{createWidgets(1), addWidgetColor(2)})
+		_, err := migrator.Up()
+		assert.NoError(t, err)
+
+		rolledBack, err := migrator.Down(1)
+		assert.NoError(t, err)
+		assert.Len(t, rolledBack, 1)
+		assert.Equal(t, uint(2), rolledBack[0].Version)
+		assert.False(t, db.Migrator().HasColumn(&widget{}, "color"))
+
+		statuses, err := migrator.Status()
+		assert.NoError(t, err)
+		assert.True(t, statuses[0].IsApplied())
+		assert.False(t, statuses[1].IsApplied())
+
+		rolledBack, err = migrator.Down(5)
+		assert.NoError(t, err)
+		assert.Len(t, rolledBack, 1)
+		assert.False(t, db.Migrator().HasTable(&widget{}))
+	})
+
+	t.Run("Failed migration is not recorded", func(t *testing.T) {
+		db := newTestDb(t)
+		broken := Migration{
+			Version: 2,
+			Name:    "broken",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.Exec("INSERT INTO widgets (name) VALUES ('half done')").Error; err != nil {
+					return err
+				}
+				return errors.New("oops")
+			},
+		}
+		migrator := NewMigrator(db, []Migration{createWidgets(1), broken})
+
+		applied, err := migrator.Up()
+
+		assert.ErrorContains(t, err, "migration 2 (broken) failed: oops")
+		assert.Len(t, applied, 1)
+		var count int64
+		db.Table("widgets").Count(&count)
+		assert.Equal(t, int64(0), count)
+		assert.ErrorIs(t, migrator.CheckCurrent(), ErrSchemaOutOfDate)
+	})
+
+	t.Run("Unknown applied migration fails check", func(t *testing.T) {
+		db := newTestDb(t)
+		_, err := NewMigrator(db, []Migration{createWidgets(1), addWidgetColor(2)}).Up()
+		assert.NoError(t, err)
+
+		olderBuild := NewMigrator(db, []Migration{createWidgets(1)})
+
+		assert.ErrorContains(t, olderBuild.CheckCurrent(), "unknown migration 2 (add_widget_color)")
+		assert.NotErrorIs(t, olderBuild.CheckCurrent(), ErrSchemaOutOfDate)
+		_, err = olderBuild.Down(1)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// what an API token may do
+const (
+	// view games, and the lists of them
+	ScopeReadGames = "games:read"
+	// create games, play moves in them and delete them
+	ScopePlayMoves = "games:play"
+)
+
+var APITokenScopes = []string{ScopeReadGames, ScopePlayMoves}
+
+// prefix of every API token, so they're recognizable (e.g. to secret scanners)
+const APITokenPrefix = "ngo_pat_"
+
+/**
+ * A personal access token, letting a user's scripts and bots call the
+ * API as them, limited to its scopes. Like session tokens, only its
+ * hash is stored; the token itself is shown once, when it's created.
+ */
+type APIToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	Name      string `gorm:"size:100;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	// space separated
+	Scopes     string `gorm:"size:255;not null"`
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func (t APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.ScopeList(), scope)
+}
+
+func IsAPITokenScope(scope string) bool {
+	return slices.Contains(APITokenScopes, scope)
+}
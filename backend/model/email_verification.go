@@ -0,0 +1,33 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// how long an email verification link can be used for
+const EmailVerificationTokenTTL = 24 * time.Hour
+
+/**
+ * A single use token proving a user can read mail sent to Email; using
+ * it makes Email the user's address. Only the token's hash is stored.
+ */
+type EmailVerificationToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	Email     string `gorm:"size:254;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// set once the token's been used
+	UsedAt *time.Time
+}
+
+func (t EmailVerificationToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
+
+// emails are stored and compared lowercased, so one address can't be claimed twice
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -3,30 +3,40 @@ package model
 import (
 	"database/sql/driver"
 	"encoding/json"
-	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 	"net-go/server/backend/model/types"
 )
 
-// / custom type for JSON encoding array of structs
+// / custom type for JSON encoding array of structs.
+// / Moves are ordered newest first, the way the client builds its history.
 type MoveSlice []types.Move
 
 func (m MoveSlice) Value() (driver.Value, error) {
-	return json.Marshal(m)
+	bytes, err := json.Marshal(m)
+	return string(bytes), err
 }
 
 func (m *MoveSlice) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	bytes, err := types.ScanJSONBytes(value)
+	if err != nil {
+		return err
 	}
 	return json.Unmarshal(bytes, m)
 }
 
+func (MoveSlice) GormDataType() string {
+	return "json"
+}
+
+func (MoveSlice) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return types.JSONDataType(db.Dialector.Name())
+}
+
 // struct sub-types get unpacked under the hood into the columns of Game, using the specified embeddedPrefix
 type Game struct {
 	gorm.Model
-	History       MoveSlice   `gorm:"type:json"`
+	History       MoveSlice
 	Board         types.Board `gorm:"embedded;embeddedPrefix:board_"`
 	IsOver        bool
 	Score         types.Score `gorm:"embedded;embeddedPrefix:score_"`
@@ -34,6 +44,59 @@ type Game struct {
 	WhitePlayerId uint        `gorm:"index"`
 	BlackPlayer   User        `gorm:"foreignKey:BlackPlayerId;"`
 	WhitePlayer   User        `gorm:"foreignKey:WhitePlayerId;"`
+	// denormalized from History so games can be filtered by turn in queries;
+	// kept in sync by the BeforeSave hook
+	MoveCount      uint
+	ActivePlayerId uint `gorm:"index"` // 0 when the game is over
+	// incremented on every update, so concurrent updates can be detected
+	Version uint `gorm:"not null;default:1"`
+}
+
+// gorm hook to start every game at the first version
+func (g *Game) BeforeCreate(tx *gorm.DB) error {
+	if g.Version == 0 {
+		g.Version = 1
+	}
+	return nil
+}
+
+// gorm hook to keep the denormalized History columns in sync on every write
+func (g *Game) BeforeSave(tx *gorm.DB) error {
+	g.SyncTurnState()
+	return nil
+}
+
+/**
+ * Recomputes MoveCount and ActivePlayerId from the current
+ * History and player ids.
+ */
+func (g *Game) SyncTurnState() {
+	g.MoveCount = uint(len(g.History))
+	if g.IsOver {
+		g.ActivePlayerId = 0
+	} else if g.ActiveColor() == types.White {
+		g.ActivePlayerId = g.WhitePlayerId
+	} else {
+		g.ActivePlayerId = g.BlackPlayerId
+	}
+}
+
+/**
+ * Returns the color of the player who should make the next move.
+ * Black always starts; after that, it is the turn of whoever did not
+ * make the last move. Deriving the turn from the last move rather than
+ * the History length keeps this correct for handicap games, where
+ * White makes the first recorded move.
+ */
+func (g Game) ActiveColor() types.ColorChoice {
+	if len(g.History) == 0 {
+		return types.Black
+	}
+	// History is newest first
+	if g.History[0].Piece == types.BlackStone {
+		return types.White
+	}
+	return types.Black
 }
 
 /**
@@ -46,3 +109,50 @@ func (recGame *Game) UpdateLegalValues(updateGame Game) {
 	recGame.IsOver = updateGame.IsOver
 	recGame.Score = updateGame.Score
 }
+
+/**
+ * Returns the color the user with id `userId` is playing as.
+ * Assumes the user is a member of the game.
+ */
+func (g Game) PlayerColor(userId uint) types.ColorChoice {
+	if g.WhitePlayerId == userId {
+		return types.White
+	}
+	return types.Black
+}
+
+/**
+ * Returns the color of the winning player, or nil when the
+ * game is still in progress or ended in a draw.
+ */
+func (g Game) Winner() *types.ColorChoice {
+	if !g.IsOver {
+		return nil
+	}
+
+	var winner types.ColorChoice
+	if g.Score.ForfeitColor != nil {
+		// forfeiting player loses
+		if *g.Score.ForfeitColor == types.Black {
+			winner = types.White
+		} else {
+			winner = types.Black
+		}
+		return &winner
+	}
+
+	whiteTotal := g.Score.WhitePoints + g.Score.Komi
+	if g.Score.BlackPoints > whiteTotal {
+		winner = types.Black
+	} else if g.Score.BlackPoints < whiteTotal {
+		winner = types.White
+	} else {
+		return nil
+	}
+	return &winner
+}
+
+// whether the game ended by one of the players resigning
+func (g Game) IsResignation() bool {
+	return g.IsOver && g.Score.ForfeitColor != nil
+}
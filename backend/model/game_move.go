@@ -0,0 +1,48 @@
+package model
+
+import (
+	"net-go/server/backend/model/types"
+	"time"
+)
+
+/**
+ * One move of a game, stored as its own row so moves can be queried
+ * across games. Mirrors the game's History, which remains the source
+ * of truth served to clients.
+ */
+type GameMove struct {
+	ID         uint              `gorm:"primarykey"`
+	GameID     uint              `gorm:"uniqueIndex:idx_game_moves_game_number;not null"`
+	MoveNumber uint              `gorm:"uniqueIndex:idx_game_moves_game_number;not null"` // 1 for the first move played; History is newest first
+	Color      types.ColorChoice `gorm:"not null"`
+	MoveType   types.MoveType    `gorm:"not null"`
+	Coord      uint              // 0 when MoveType is Pass
+	PlayedAt   *time.Time        // nil for moves backfilled from before this table existed
+	TimeLeftMs *int64            // mover's remaining clock time; always nil until games have a clock
+}
+
+func NewGameMove(gameId uint, moveNumber uint, move types.Move, playedAt *time.Time) GameMove {
+	color := types.Black
+	if move.Piece == types.WhiteStone {
+		color = types.White
+	}
+	return GameMove{
+		GameID:     gameId,
+		MoveNumber: moveNumber,
+		Color:      color,
+		MoveType:   move.MoveType,
+		Coord:      move.Coord,
+		PlayedAt:   playedAt,
+	}
+}
+
+/**
+ * Whether `other` records the same move as the receiver,
+ * regardless of when it was played.
+ */
+func (m GameMove) SameMove(other GameMove) bool {
+	return m.MoveNumber == other.MoveNumber &&
+		m.Color == other.Color &&
+		m.MoveType == other.MoveType &&
+		m.Coord == other.Coord
+}
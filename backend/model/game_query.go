@@ -0,0 +1,106 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net-go/server/backend/model/types"
+	"time"
+)
+
+type GameStatusFilter string
+
+const (
+	AnyStatus     GameStatusFilter = ""
+	ActiveGames   GameStatusFilter = "active"
+	FinishedGames GameStatusFilter = "finished"
+)
+
+type GameTurnFilter string
+
+const (
+	AnyTurn      GameTurnFilter = ""
+	MyTurn       GameTurnFilter = "mine"
+	OpponentTurn GameTurnFilter = "theirs"
+)
+
+type GameSortOrder string
+
+const (
+	CreatedDesc GameSortOrder = "created_desc"
+	CreatedAsc  GameSortOrder = "created_asc"
+	UpdatedDesc GameSortOrder = "updated_desc"
+	UpdatedAsc  GameSortOrder = "updated_asc"
+)
+
+// column to sort by and whether to sort descending
+func (o GameSortOrder) Column() (string, bool) {
+	switch o {
+	case CreatedAsc:
+		return "created_at", false
+	case UpdatedDesc:
+		return "updated_at", true
+	case UpdatedAsc:
+		return "updated_at", false
+	default:
+		return "created_at", true
+	}
+}
+
+// position of the last item of a page; the next page starts after it
+type GameCursor struct {
+	SortValue time.Time `json:"t"`
+	ID        uint      `json:"id"`
+}
+
+/**
+ * Builds the cursor pointing just past `summary` in a listing
+ * sorted by `order`.
+ */
+func NewGameCursor(summary GameSummary, order GameSortOrder) GameCursor {
+	sortValue := summary.CreatedAt
+	if column, _ := order.Column(); column == "updated_at" {
+		sortValue = summary.UpdatedAt
+	}
+	return GameCursor{
+		SortValue: sortValue,
+		ID:        summary.ID,
+	}
+}
+
+// opaque string form of the cursor for handing to clients
+func (c GameCursor) Encode() string {
+	bytes, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func DecodeGameCursor(encoded string) (*GameCursor, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor GameCursor
+	if err := json.Unmarshal(bytes, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// filters and ordering for listing the games of user `UserId`
+type GameListOptions struct {
+	UserId        uint
+	Status        GameStatusFilter
+	Turn          GameTurnFilter
+	OpponentId    uint            // 0 for any opponent
+	BoardSize     types.BoardSize // Undefined for any size
+	CreatedAfter  time.Time       // zero value for no lower bound
+	CreatedBefore time.Time       // zero value for no upper bound
+	Sort          GameSortOrder
+	Cursor        *GameCursor // nil for first page
+	Limit         int         // 0 for no limit
+}
+
+type GameSummaryPage struct {
+	Games []GameSummary
+	// nil when there are no more pages
+	NextCursor *GameCursor
+}
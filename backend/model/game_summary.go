@@ -0,0 +1,28 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"net-go/server/backend/model/types"
+)
+
+/**
+ * Read-only projection of the games table that omits the
+ * heavy JSON columns (board map and move history), for listing
+ * many games at once.
+ */
+type GameSummary struct {
+	gorm.Model
+	BoardSize      types.BoardSize `gorm:"column:board_size"`
+	IsOver         bool
+	Score          types.Score `gorm:"embedded;embeddedPrefix:score_"`
+	MoveCount      uint
+	ActivePlayerId uint
+	BlackPlayerId  uint
+	WhitePlayerId  uint
+	BlackPlayer    User `gorm:"foreignKey:BlackPlayerId;"`
+	WhitePlayer    User `gorm:"foreignKey:WhitePlayerId;"`
+}
+
+func (GameSummary) TableName() string {
+	return "games"
+}
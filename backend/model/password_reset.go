@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+)
+
+// how long a password reset link can be used for
+const PasswordResetTokenTTL = time.Hour
+
+/**
+ * A single use token letting a user set a new password without knowing
+ * their current one. Like sessions, only the token's hash is stored.
+ */
+type PasswordResetToken struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// set once the token's been used
+	UsedAt *time.Time
+}
+
+func (t PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// sessions unused for this long are expired (matches the auth cookie's lifetime)
+const SessionIdleTimeout = 30 * 24 * time.Hour
+
+/**
+ * A signed in device of a user. The session token itself is only
+ * given to the device (in its auth cookie); just its hash is stored.
+ */
+type Session struct {
+	ID         uint   `gorm:"primarykey"`
+	UserID     uint   `gorm:"index;not null"`
+	TokenHash  string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	UserAgent  string `gorm:"size:512"`
+	IP         string `gorm:"size:64"`
+}
+
+// where a session was started from
+type SessionClient struct {
+	UserAgent string
+	IP        string
+}
+
+func (s Session) IsExpired(now time.Time) bool {
+	return now.Sub(s.LastSeenAt) >= SessionIdleTimeout
+}
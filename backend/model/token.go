@@ -0,0 +1,16 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+/**
+ * What's stored of a secret token (session, password reset, ...) in
+ * place of the token itself, so a leaked db doesn't hand out working
+ * tokens. The tokens are random, so a fast unsalted hash is enough.
+ */
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
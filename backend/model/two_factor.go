@@ -0,0 +1,67 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// how long after the password check a signin has to give its 2FA code
+	TwoFactorTicketTTL = 5 * time.Minute
+	// wrong codes a ticket takes before the signin has to start over
+	TwoFactorTicketMaxAttempts = 5
+	// how many recovery codes a user gets at a time
+	RecoveryCodeCount = 10
+)
+
+/**
+ * A signin of a 2FA user that passed the password check and is waiting
+ * on a code. Only the ticket's hash is stored, like sessions.
+ */
+type TwoFactorTicket struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	TokenHash string `gorm:"uniqueIndex;size:64;not null"`
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	// wrong codes given so far
+	Attempts int `gorm:"not null;default:0"`
+}
+
+func (t TwoFactorTicket) IsUsable(now time.Time) bool {
+	return t.Attempts < TwoFactorTicketMaxAttempts && now.Before(t.ExpiresAt)
+}
+
+/**
+ * A single use code that stands in for a TOTP code, for users who've
+ * lost their authenticator. Stored hashed (see HashRecoveryCode).
+ */
+type RecoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index;not null"`
+	CodeHash  string `gorm:"size:64;not null"`
+	CreatedAt time.Time
+	// set once the code's been used
+	UsedAt *time.Time
+}
+
+// hash of recovery code `code`, ignoring case, spaces and dashes as typed by the user
+func HashRecoveryCode(code string) string {
+	code = strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	return HashToken(code)
+}
+
+// a user's 2FA settings, as shown to them
+type TwoFactorStatus struct {
+	Enabled bool
+	// unused recovery codes; 0 while 2FA is off
+	RecoveryCodesLeft int64
+}
+
+// what a user needs to add their new TOTP secret to an authenticator app
+type TwoFactorEnrollment struct {
+	// base32, for typing in
+	Secret string
+	// otpauth:// URI, for a QR code
+	URI string
+}
@@ -4,27 +4,38 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
-	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 // custom type to json encode since gorm doesn't like 2d array of Pieces
 type PlayArea [][]Piece
 
 func (p PlayArea) Value() (driver.Value, error) {
-	return json.Marshal(p)
+	bytes, err := json.Marshal(p)
+	return string(bytes), err
 }
 
 func (p *PlayArea) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	bytes, err := ScanJSONBytes(value)
+	if err != nil {
+		return err
 	}
 	return json.Unmarshal(bytes, p)
 }
 
+func (PlayArea) GormDataType() string {
+	return "json"
+}
+
+func (PlayArea) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return JSONDataType(db.Dialector.Name())
+}
+
 type Board struct {
 	Size BoardSize `json:"size"`
-	Map  PlayArea  `json:"map" gorm:"type:json"`
+	Map  PlayArea  `json:"map"`
 }
 
 func BoardFromArray(size BoardSize, board1d []Piece) (*Board, error) {
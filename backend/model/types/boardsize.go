@@ -71,16 +71,15 @@ func (bs *BoardSize) Scan(value interface{}) error {
 		*bs = Undefined
 		return nil
 	}
-	switch v := value.(type) {
-	case int64:
-		boardSize, err := UintToBoardSize(uint(v))
-		if err != nil {
-			return err
-		}
-		*bs = boardSize
-	default:
+	v, err := scanInt64(value)
+	if err != nil {
 		return fmt.Errorf("unsupported Scan value type for BoardSize: %T", value)
 	}
+	boardSize, err := UintToBoardSize(uint(v))
+	if err != nil {
+		return err
+	}
+	*bs = boardSize
 	return nil
 }
 
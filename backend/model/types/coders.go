@@ -0,0 +1,54 @@
+package types
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/**
+ * Extracts an integer from a raw database value. Drivers differ in
+ * how they return integer columns: most use int64, but mysql returns
+ * []byte for queries sent over its text protocol.
+ */
+func scanInt64(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("unsupported Scan value type for integer: %T", value)
+	}
+}
+
+/**
+ * Extracts the raw JSON bytes from a database value. Depending on
+ * the driver and column type, JSON is returned as []byte or string.
+ */
+func ScanJSONBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	}
+}
+
+// JSON column type for the connected dialect
+func JSONDataType(dialect string) string {
+	switch dialect {
+	case "postgres":
+		return "JSONB"
+	default:
+		// mysql, sqlite
+		return "JSON"
+	}
+}
@@ -0,0 +1,99 @@
+package types
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+/**
+ * Drivers return integer columns as int64 (sqlite, postgres, mysql
+ * binary protocol) or []byte (mysql text protocol), and text columns
+ * as string or []byte, so every scanner has to accept all of those.
+ */
+func TestDatabaseCoders(t *testing.T) {
+	cases := []struct {
+		name    string
+		value   driver.Valuer
+		scanned func() sql.Scanner // fresh zero value to scan into
+		raw     []interface{}
+	}{
+		{"Piece", WhiteStone, func() sql.Scanner { return new(Piece) }, []interface{}{int64(-1), []byte("-1"), "-1"}},
+		{"BoardSize", Medium, func() sql.Scanner { return new(BoardSize) }, []interface{}{int64(12), []byte("12"), "12"}},
+		{"MoveType", PlayPiece, func() sql.Scanner { return new(MoveType) }, []interface{}{int64(1), []byte("1"), "1"}},
+		{"ColorChoice", White, func() sql.Scanner { return new(ColorChoice) }, []interface{}{"white", []byte("white")}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name+" Value is a valid driver value", func(t *testing.T) {
+			v, err := tc.value.Value()
+
+			assert.NoError(t, err)
+			assert.True(t, driver.IsValue(v), "%T is not a driver.Value", v)
+		})
+
+		t.Run(tc.name+" Value round trips through Scan", func(t *testing.T) {
+			v, _ := tc.value.Value()
+			scanned := tc.scanned()
+
+			assert.NoError(t, scanned.Scan(v))
+			assert.Equal(t, tc.value, deref(scanned))
+		})
+
+		t.Run(tc.name+" Scan accepts driver representations", func(t *testing.T) {
+			for _, raw := range tc.raw {
+				scanned := tc.scanned()
+
+				assert.NoError(t, scanned.Scan(raw), "scanning %T", raw)
+				assert.Equal(t, tc.value, deref(scanned), "scanning %T", raw)
+			}
+		})
+
+		t.Run(tc.name+" Scan rejects unsupported types", func(t *testing.T) {
+			assert.Error(t, tc.scanned().Scan(3.5))
+		})
+	}
+}
+
+// the value a scanner from TestDatabaseCoders points to
+func deref(c sql.Scanner) interface{} {
+	switch v := c.(type) {
+	case *Piece:
+		return *v
+	case *BoardSize:
+		return *v
+	case *MoveType:
+		return *v
+	case *ColorChoice:
+		return *v
+	}
+	return nil
+}
+
+func TestJSONColumnCoders(t *testing.T) {
+	t.Run("PlayArea", func(t *testing.T) {
+		area := PlayArea{{BlackStone, None}, {None, WhiteStone}}
+		v, err := area.Value()
+		assert.NoError(t, err)
+		assert.True(t, driver.IsValue(v))
+
+		for _, raw := range []interface{}{v, []byte(v.(string))} {
+			var scanned PlayArea
+			assert.NoError(t, scanned.Scan(raw))
+			assert.Equal(t, area, scanned)
+		}
+	})
+
+	t.Run("Move", func(t *testing.T) {
+		move := Move{MoveType: PlayPiece, Piece: BlackStone, Coord: 42}
+		v, err := move.Value()
+		assert.NoError(t, err)
+		assert.True(t, driver.IsValue(v))
+
+		var scanned Move
+		assert.NoError(t, scanned.Scan([]byte(v.(string))))
+		assert.Equal(t, move, scanned)
+	})
+}
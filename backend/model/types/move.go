@@ -64,16 +64,15 @@ func (mt *MoveType) Scan(value interface{}) error {
 		*mt = Pass
 		return nil
 	}
-	switch v := value.(type) {
-	case int64:
-		moveType, err := UintToMoveType(uint(v))
-		if err != nil {
-			return err
-		}
-		*mt = moveType
-	default:
+	v, err := scanInt64(value)
+	if err != nil {
 		return fmt.Errorf("unsupported Scan value type for MoveType: %T", value)
 	}
+	moveType, err := UintToMoveType(uint(v))
+	if err != nil {
+		return err
+	}
+	*mt = moveType
 	return nil
 }
 
@@ -94,24 +93,18 @@ func (m Move) IsPass() bool {
 /// DATABASE CODERS ///
 
 func (m Move) Value() (driver.Value, error) {
-	return json.Marshal(m)
+	bytes, err := json.Marshal(m)
+	return string(bytes), err
 }
 
 func (m *Move) Scan(value interface{}) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to unmarshal JSON value: %v", value)
+	bytes, err := ScanJSONBytes(value)
+	if err != nil {
+		return err
 	}
 	return json.Unmarshal(bytes, m)
 }
 
 func (Move) GormDBDataType(db *gorm.DB, field *schema.Field) string {
-	switch db.Dialector.Name() {
-	case "mysql", "sqlite":
-		return "JSON"
-	case "postgres":
-		return "JSONB"
-	}
-	// fallback hope it's json
-	return "JSON"
+	return JSONDataType(db.Dialector.Name())
 }
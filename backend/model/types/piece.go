@@ -57,7 +57,8 @@ func (p *Piece) MarshalJSON() ([]byte, error) {
 /// DATABASE CODERS ///
 
 func (p Piece) Value() (driver.Value, error) {
-	return p.ToInt(), nil
+	// int64 bcus plain int isn't a valid driver.Value
+	return int64(p), nil
 }
 
 func (p *Piece) Scan(value interface{}) error {
@@ -65,16 +66,15 @@ func (p *Piece) Scan(value interface{}) error {
 		*p = None
 		return nil
 	}
-	switch v := value.(type) {
-	case int:
-		piece, err := IntToPiece(v)
-		if err != nil {
-			return err
-		}
-		*p = piece
-	default:
+	v, err := scanInt64(value)
+	if err != nil {
 		return fmt.Errorf("unsupported Scan value type for Piece: %T", value)
 	}
+	piece, err := IntToPiece(int(v))
+	if err != nil {
+		return err
+	}
+	*p = piece
 	return nil
 }
 
@@ -1,13 +1,27 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 // backtick contains meta info about how model data should be stored in db
 type User struct {
 	gorm.Model
-	Username     string `gorm:"uniqueIndex"`
-	Password     string // hashed password
-	SessionToken string
+	Username string `gorm:"uniqueIndex"`
+	Password string // hashed password
+	// optional; only ever set to an address the user has verified (lowercased)
+	Email           *string `gorm:"uniqueIndex;size:254"`
+	EmailVerifiedAt *time.Time
+	// base32 TOTP secret, set on enrolling in 2FA; it's only on once TOTPEnabledAt is set too
+	TOTPSecret    *string `gorm:"size:64"`
+	TOTPEnabledAt *time.Time
+	// period of the last TOTP code accepted, so no code works twice
+	TOTPLastStep int64 `gorm:"not null;default:0"`
+}
+
+// whether signing in takes a TOTP (or recovery) code after the password
+func (u User) TwoFactorEnabled() bool {
+	return u.TOTPSecret != nil && u.TOTPEnabledAt != nil
 }
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+)
+
+/**
+ * An account at an external identity provider (OIDC) that signs in as
+ * a user. Issuer and Subject together identify it, as the OIDC spec
+ * recommends; emails and usernames at the provider can change.
+ */
+type UserIdentity struct {
+	ID      uint   `gorm:"primarykey"`
+	UserID  uint   `gorm:"index;not null"`
+	Issuer  string `gorm:"uniqueIndex:idx_user_identities_issuer_subject;size:255;not null"`
+	Subject string `gorm:"uniqueIndex:idx_user_identities_issuer_subject;size:255;not null"`
+	// as the provider reported it when linked, to show users which account it is
+	Email     string `gorm:"size:254"`
+	CreatedAt time.Time
+}
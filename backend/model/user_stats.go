@@ -0,0 +1,124 @@
+package model
+
+import (
+	"net-go/server/backend/model/types"
+	"sort"
+)
+
+type StreakResult string
+
+const (
+	NoStreak   StreakResult = ""
+	WinStreak  StreakResult = "win"
+	LossStreak StreakResult = "loss"
+)
+
+type WinLossRecord struct {
+	Wins   uint `json:"wins"`
+	Losses uint `json:"losses"`
+	Draws  uint `json:"draws"`
+}
+
+type Streak struct {
+	Result StreakResult `json:"result"`
+	Length uint         `json:"length"`
+}
+
+// aggregate stats over all the games a user has been a member of
+type UserStats struct {
+	GamesPlayed         uint                                `json:"gamesPlayed"` // finished games only
+	GamesInProgress     uint                                `json:"gamesInProgress"`
+	Record              WinLossRecord                       `json:"record"`
+	ByColor             map[types.ColorChoice]WinLossRecord `json:"byColor"`
+	ByBoardSize         map[types.BoardSize]WinLossRecord   `json:"byBoardSize"`
+	WinsByResignation   uint                                `json:"winsByResignation"`
+	WinsByScore         uint                                `json:"winsByScore"`
+	LossesByResignation uint                                `json:"lossesByResignation"`
+	LossesByScore       uint                                `json:"lossesByScore"`
+	CurrentStreak       Streak                              `json:"currentStreak"`
+}
+
+func (r *WinLossRecord) add(result StreakResult) {
+	switch result {
+	case WinStreak:
+		r.Wins++
+	case LossStreak:
+		r.Losses++
+	default:
+		r.Draws++
+	}
+}
+
+// result of a finished game from the perspective of user `userId`
+func gameResultForUser(game Game, userId uint) StreakResult {
+	winner := game.Winner()
+	if winner == nil {
+		return NoStreak
+	}
+	if *winner == game.PlayerColor(userId) {
+		return WinStreak
+	}
+	return LossStreak
+}
+
+/**
+ * Computes the aggregate UserStats for user `userId` from all
+ * of the `games` they are a member of.
+ */
+func BuildUserStats(userId uint, games []Game) UserStats {
+	stats := UserStats{
+		ByColor:     make(map[types.ColorChoice]WinLossRecord),
+		ByBoardSize: make(map[types.BoardSize]WinLossRecord),
+	}
+
+	finished := make([]Game, 0, len(games))
+	for _, game := range games {
+		if !game.IsOver {
+			stats.GamesInProgress++
+			continue
+		}
+		finished = append(finished, game)
+
+		result := gameResultForUser(game, userId)
+		stats.GamesPlayed++
+		stats.Record.add(result)
+
+		colorRecord := stats.ByColor[game.PlayerColor(userId)]
+		colorRecord.add(result)
+		stats.ByColor[game.PlayerColor(userId)] = colorRecord
+
+		sizeRecord := stats.ByBoardSize[game.Board.Size]
+		sizeRecord.add(result)
+		stats.ByBoardSize[game.Board.Size] = sizeRecord
+
+		switch {
+		case result == WinStreak && game.IsResignation():
+			stats.WinsByResignation++
+		case result == WinStreak:
+			stats.WinsByScore++
+		case result == LossStreak && game.IsResignation():
+			stats.LossesByResignation++
+		case result == LossStreak:
+			stats.LossesByScore++
+		}
+	}
+
+	// most recently finished games first; a game is last updated when it ends
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].UpdatedAt.After(finished[j].UpdatedAt)
+	})
+	for _, game := range finished {
+		result := gameResultForUser(game, userId)
+		if result == NoStreak {
+			// draws end a streak
+			break
+		}
+		if stats.CurrentStreak.Result != NoStreak && stats.CurrentStreak.Result != result {
+			break
+		}
+		stats.CurrentStreak.Result = result
+		stats.CurrentStreak.Length++
+	}
+
+	return stats
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+type MemoryLimiterConfig struct {
+	// requests allowed per `Per`; also the most that can be made at once
+	Limit int
+	Per   time.Duration
+}
+
+// a token bucket; tokens are added continuously, up to the limit
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+/**
+ * Token bucket Limiter kept in memory. Each key gets `Limit` tokens,
+ * refilled at `Limit` per `Per`, and every request takes one.
+ */
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	burst     float64
+	rate      float64 // tokens added per second
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter(config MemoryLimiterConfig) *MemoryLimiter {
+	return &MemoryLimiter{
+		burst:     float64(config.Limit),
+		rate:      float64(config.Limit) / config.Per.Seconds(),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / l.rate
+		return false, time.Duration(math.Ceil(wait * float64(time.Second))), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// time for an empty bucket to fill back up
+func (l *MemoryLimiter) refillTime() time.Duration {
+	return time.Duration(l.burst / l.rate * float64(time.Second))
+}
+
+/**
+ * Drops buckets that have refilled completely, since a new bucket
+ * would be the same, so memory only grows with recently active keys.
+ * Callers must hold l.mu.
+ */
+func (l *MemoryLimiter) sweep(now time.Time) {
+	refill := l.refillTime()
+	if now.Sub(l.lastSweep) < refill {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
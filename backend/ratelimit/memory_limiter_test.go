@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// a fake clock for the memory implementations
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(limit int, per time.Duration) (*MemoryLimiter, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := NewMemoryLimiter(MemoryLimiterConfig{Limit: limit, Per: per})
+	limiter.now = clock.Now
+	limiter.lastSweep = clock.now
+	return limiter, clock
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Allows bursts up to the limit", func(t *testing.T) {
+		limiter, _ := newTestLimiter(3, time.Minute)
+
+		for i := 0; i < 3; i++ {
+			allowed, _, err := limiter.Allow(ctx, "1.2.3.4")
+			assert.NoError(t, err)
+			assert.True(t, allowed)
+		}
+		allowed, retryAfter, err := limiter.Allow(ctx, "1.2.3.4")
+
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		// one token refills every 20s
+		assert.Equal(t, 20*time.Second, retryAfter)
+	})
+
+	t.Run("Refills over time", func(t *testing.T) {
+		limiter, clock := newTestLimiter(3, time.Minute)
+		for i := 0; i < 3; i++ {
+			limiter.Allow(ctx, "1.2.3.4")
+		}
+
+		clock.Advance(15 * time.Second)
+		allowed, retryAfter, _ := limiter.Allow(ctx, "1.2.3.4")
+		assert.False(t, allowed)
+		assert.Equal(t, 5*time.Second, retryAfter)
+
+		clock.Advance(5 * time.Second)
+		allowed, _, _ = limiter.Allow(ctx, "1.2.3.4")
+		assert.True(t, allowed)
+	})
+
+	t.Run("Keys are limited separately", func(t *testing.T) {
+		limiter, _ := newTestLimiter(1, time.Minute)
+
+		first, _, _ := limiter.Allow(ctx, "tim")
+		second, _, _ := limiter.Allow(ctx, "tom")
+		again, _, _ := limiter.Allow(ctx, "tim")
+
+		assert.True(t, first)
+		assert.True(t, second)
+		assert.False(t, again)
+	})
+
+	t.Run("Forgets idle keys", func(t *testing.T) {
+		limiter, clock := newTestLimiter(2, time.Minute)
+		limiter.Allow(ctx, "tim")
+		limiter.Allow(ctx, "tom")
+		clock.Advance(30 * time.Second)
+		limiter.Allow(ctx, "tom")
+
+		clock.Advance(45 * time.Second)
+		limiter.Allow(ctx, "sally")
+
+		// tim's bucket refilled completely; tom's was used too recently
+		assert.NotContains(t, limiter.buckets, "tim")
+		assert.Contains(t, limiter.buckets, "tom")
+		assert.Contains(t, limiter.buckets, "sally")
+	})
+}
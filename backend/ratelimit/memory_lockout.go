@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type MemoryLockoutConfig struct {
+	// failures allowed before the key is locked out
+	Threshold int
+	// lockout after reaching the threshold; doubles with each further failure
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+	// failures are forgotten once there have been none for this long
+	FailureWindow time.Duration
+}
+
+type lockoutEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Lockout kept in memory, with exponentially growing lockouts.
+type MemoryLockout struct {
+	mu        sync.Mutex
+	config    MemoryLockoutConfig
+	entries   map[string]*lockoutEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLockout(config MemoryLockoutConfig) *MemoryLockout {
+	return &MemoryLockout{
+		config:    config,
+		entries:   make(map[string]*lockoutEntry),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *MemoryLockout) Check(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if entry := l.entry(key, now); entry != nil && entry.lockedUntil.After(now) {
+		return entry.lockedUntil.Sub(now), nil
+	}
+	return 0, nil
+}
+
+func (l *MemoryLockout) RecordFailure(ctx context.Context, key string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	entry := l.entry(key, now)
+	if entry == nil {
+		entry = &lockoutEntry{}
+		l.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+
+	if entry.failures < l.config.Threshold {
+		return 0, nil
+	}
+	lockout := l.config.BaseLockout << (entry.failures - l.config.Threshold)
+	if lockout <= 0 || lockout > l.config.MaxLockout {
+		// `lockout` is negative when the shift overflows
+		lockout = l.config.MaxLockout
+	}
+	entry.lockedUntil = now.Add(lockout)
+	return lockout, nil
+}
+
+func (l *MemoryLockout) Reset(ctx context.Context, key string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.entries, key)
+	return nil
+}
+
+// the entry of `key`, or nil if it has none or its failures have expired; callers must hold l.mu
+func (l *MemoryLockout) entry(key string, now time.Time) *lockoutEntry {
+	entry, ok := l.entries[key]
+	if !ok {
+		return nil
+	}
+	if l.expired(entry, now) {
+		delete(l.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func (l *MemoryLockout) expired(entry *lockoutEntry, now time.Time) bool {
+	return !entry.lockedUntil.After(now) && now.Sub(entry.lastFailure) >= l.config.FailureWindow
+}
+
+// drops expired entries at most once per FailureWindow; callers must hold l.mu
+func (l *MemoryLockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.config.FailureWindow {
+		return
+	}
+	l.lastSweep = now
+	for key, entry := range l.entries {
+		if l.expired(entry, now) {
+			delete(l.entries, key)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestLockout() (*MemoryLockout, *testClock) {
+	clock := &testClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	lockout := NewMemoryLockout(MemoryLockoutConfig{
+		Threshold:     3,
+		BaseLockout:   time.Minute,
+		MaxLockout:    10 * time.Minute,
+		FailureWindow: time.Hour,
+	})
+	lockout.now = clock.Now
+	lockout.lastSweep = clock.now
+	return lockout, clock
+}
+
+// records `n` failures for `key`, returning the lockout after the last
+func recordFailures(t *testing.T, lockout *MemoryLockout, key string, n int) time.Duration {
+	var lockedFor time.Duration
+	for i := 0; i < n; i++ {
+		var err error
+		lockedFor, err = lockout.RecordFailure(context.Background(), key)
+		assert.NoError(t, err)
+	}
+	return lockedFor
+}
+
+func TestMemoryLockout(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Locks out at the threshold", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+
+		assert.Equal(t, time.Duration(0), recordFailures(t, lockout, "tim", 2))
+		lockedFor, _ := lockout.Check(ctx, "tim")
+		assert.Equal(t, time.Duration(0), lockedFor)
+
+		assert.Equal(t, time.Minute, recordFailures(t, lockout, "tim", 1))
+		lockedFor, _ = lockout.Check(ctx, "tim")
+		assert.Equal(t, time.Minute, lockedFor)
+	})
+
+	t.Run("Lockouts grow exponentially up to the max", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		recordFailures(t, lockout, "tim", 3)
+
+		assert.Equal(t, 2*time.Minute, recordFailures(t, lockout, "tim", 1))
+		assert.Equal(t, 4*time.Minute, recordFailures(t, lockout, "tim", 1))
+		assert.Equal(t, 8*time.Minute, recordFailures(t, lockout, "tim", 1))
+		assert.Equal(t, 10*time.Minute, recordFailures(t, lockout, "tim", 1))
+		assert.Equal(t, 10*time.Minute, recordFailures(t, lockout, "tim", 100))
+	})
+
+	t.Run("Lockouts expire", func(t *testing.T) {
+		lockout, clock := newTestLockout()
+		recordFailures(t, lockout, "tim", 3)
+
+		clock.Advance(40 * time.Second)
+		lockedFor, _ := lockout.Check(ctx, "tim")
+		assert.Equal(t, 20*time.Second, lockedFor)
+
+		clock.Advance(20 * time.Second)
+		lockedFor, _ = lockout.Check(ctx, "tim")
+		assert.Equal(t, time.Duration(0), lockedFor)
+	})
+
+	t.Run("Failures are forgotten after the window", func(t *testing.T) {
+		lockout, clock := newTestLockout()
+		recordFailures(t, lockout, "tim", 2)
+
+		clock.Advance(time.Hour)
+
+		assert.Equal(t, time.Duration(0), recordFailures(t, lockout, "tim", 2))
+	})
+
+	t.Run("Reset clears failures", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		recordFailures(t, lockout, "tim", 3)
+
+		assert.NoError(t, lockout.Reset(ctx, "tim"))
+
+		lockedFor, _ := lockout.Check(ctx, "tim")
+		assert.Equal(t, time.Duration(0), lockedFor)
+		assert.Equal(t, time.Duration(0), recordFailures(t, lockout, "tim", 2))
+	})
+
+	t.Run("Keys are tracked separately", func(t *testing.T) {
+		lockout, _ := newTestLockout()
+		recordFailures(t, lockout, "tim", 3)
+
+		lockedFor, _ := lockout.Check(ctx, "tom")
+		assert.Equal(t, time.Duration(0), lockedFor)
+	})
+}
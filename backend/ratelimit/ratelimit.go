@@ -0,0 +1,32 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+/*
+ * Throttling of requests by key (e.g. client IP or username). Only in
+ * memory implementations exist; with several server instances, each
+ * keeps its own counts. Something like a redis backed implementation
+ * can be swapped in through these interfaces.
+ */
+
+// decides whether requests for a key may go ahead
+type Limiter interface {
+	/**
+	 * Takes a request for `key` from its allowance. When the allowance
+	 * is used up, returns false and how long until a request is allowed.
+	 */
+	Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// tracks repeated failures (e.g. wrong passwords) per key and locks the key out
+type Lockout interface {
+	// how long `key` is still locked out for; 0 when it isn't
+	Check(ctx context.Context, key string) (time.Duration, error)
+	// Counts a failure for `key`. Returns how long it is now locked out for, if at all.
+	RecordFailure(ctx context.Context, key string) (time.Duration, error)
+	// forgets the failures of `key`, e.g. after a successful attempt
+	Reset(ctx context.Context, key string) error
+}
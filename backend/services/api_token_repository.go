@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* interface */
+
+type IAPITokenRepository interface {
+	Create(ctx context.Context, token *model.APIToken) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*model.APIToken, error)
+	// oldest first
+	ListByUserID(ctx context.Context, userId uint) ([]model.APIToken, error)
+	UpdateLastUsed(ctx context.Context, id uint, lastUsed time.Time) error
+	// gorm.ErrRecordNotFound if user `userId` has no token `id`
+	Delete(ctx context.Context, userId uint, id uint) error
+	DeleteByUserID(ctx context.Context, userId uint) error
+}
+
+/* implementation */
+
+type APITokenRepository struct {
+	BaseRepository
+}
+
+type APITokenRepoDeps struct {
+	BaseDeps *BaseRepoDeps
+}
+
+func NewAPITokenRepository(deps *APITokenRepoDeps) IAPITokenRepository {
+	return &APITokenRepository{
+		BaseRepository: NewBaseRepository(deps.BaseDeps),
+	}
+}
+
+func (r *APITokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "APITokenRepository.Create")
+	defer endSpan()
+	return r.Db.WithContext(ctx).Create(token).Error
+}
+
+func (r *APITokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.APIToken, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "APITokenRepository.FindByTokenHash")
+	defer endSpan()
+	var token model.APIToken
+	err := r.Db.WithContext(ctx).
+		First(&token, "token_hash = ?", tokenHash).
+		Error
+	return &token, err
+}
+
+func (r *APITokenRepository) ListByUserID(ctx context.Context, userId uint) ([]model.APIToken, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "APITokenRepository.ListByUserID")
+	defer endSpan()
+	tokens := []model.APIToken{}
+	err := r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("id").
+		Find(&tokens).
+		Error
+	return tokens, err
+}
+
+func (r *APITokenRepository) UpdateLastUsed(ctx context.Context, id uint, lastUsed time.Time) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "APITokenRepository.UpdateLastUsed")
+	defer endSpan()
+	return r.Db.WithContext(ctx).
+		Model(&model.APIToken{ID: id}).
+		Update("last_used_at", lastUsed).
+		Error
+}
+
+func (r *APITokenRepository) Delete(ctx context.Context, userId uint, id uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "APITokenRepository.Delete")
+	defer endSpan()
+	result := r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.APIToken{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *APITokenRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "APITokenRepository.DeleteByUserID")
+	defer endSpan()
+	return r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.APIToken{}).
+		Error
+}
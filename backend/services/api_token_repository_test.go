@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestAPITokenRepository(t *testing.T) {
+	t.Run("Finds tokens by hash and records their use", func(t *testing.T) {
+		repo := &APITokenRepository{BaseRepository{Db: newTestDb(t)}}
+		token := &model.APIToken{UserID: 1, Name: "bot", TokenHash: "hash", Scopes: model.ScopeReadGames}
+		assert.NoError(t, repo.Create(context.TODO(), token))
+
+		found, err := repo.FindByTokenHash(context.TODO(), "hash")
+		assert.NoError(t, err)
+		assert.Equal(t, token.ID, found.ID)
+		assert.Nil(t, found.LastUsedAt)
+		_, err = repo.FindByTokenHash(context.TODO(), "other")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		used := time.Now().UTC().Truncate(time.Second)
+		assert.NoError(t, repo.UpdateLastUsed(context.TODO(), token.ID, used))
+		found, _ = repo.FindByTokenHash(context.TODO(), "hash")
+		assert.True(t, used.Equal(*found.LastUsedAt))
+	})
+
+	t.Run("Users can only delete their own tokens", func(t *testing.T) {
+		repo := &APITokenRepository{BaseRepository{Db: newTestDb(t)}}
+		token := &model.APIToken{UserID: 1, Name: "bot", TokenHash: "hash", Scopes: model.ScopeReadGames}
+		assert.NoError(t, repo.Create(context.TODO(), token))
+
+		assert.ErrorIs(t, repo.Delete(context.TODO(), 2, token.ID), gorm.ErrRecordNotFound)
+		assert.NoError(t, repo.Delete(context.TODO(), 1, token.ID))
+
+		tokens, err := repo.ListByUserID(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Empty(t, tokens)
+	})
+	t.Run("Deletes all of a user's tokens", func(t *testing.T) {
+		repo := &APITokenRepository{BaseRepository{Db: newTestDb(t)}}
+		assert.NoError(t, repo.Create(context.TODO(), &model.APIToken{UserID: 1, Name: "a", TokenHash: "a", Scopes: model.ScopeReadGames}))
+		assert.NoError(t, repo.Create(context.TODO(), &model.APIToken{UserID: 1, Name: "b", TokenHash: "b", Scopes: model.ScopeReadGames}))
+		assert.NoError(t, repo.Create(context.TODO(), &model.APIToken{UserID: 2, Name: "c", TokenHash: "c", Scopes: model.ScopeReadGames}))
+
+		assert.NoError(t, repo.DeleteByUserID(context.TODO(), 1))
+
+		tokens, _ := repo.ListByUserID(context.TODO(), 1)
+		assert.Empty(t, tokens)
+		tokens, _ = repo.ListByUserID(context.TODO(), 2)
+		assert.Len(t, tokens, 1)
+	})
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// how stale a token's LastUsedAt may get before a request refreshes it
+const apiTokenTouchInterval = time.Minute
+
+/* interfaces */
+
+// methods the router handler layer interacts with
+type IAPITokenService interface {
+	// new token for `userId` with `scopes`; returns the token itself, which is never shown again
+	Create(ctx context.Context, userId uint, name string, scopes []string) (string, *model.APIToken, error)
+	List(ctx context.Context, userId uint) ([]model.APIToken, error)
+	Revoke(ctx context.Context, userId uint, id uint) error
+	// revoke every token of `userId`, e.g. when their password changes
+	RevokeAll(ctx context.Context, userId uint) error
+	// the stored token `token` is, if it's valid
+	Authenticate(ctx context.Context, token string) (*model.APIToken, error)
+}
+
+/* implementation */
+
+type APITokenService struct {
+	tokenRepository IAPITokenRepository
+	now             func() time.Time
+}
+
+// injectable deps
+type APITokenServiceDeps struct {
+	TokenRepository IAPITokenRepository
+}
+
+func NewAPITokenService(d APITokenServiceDeps) IAPITokenService {
+	return &APITokenService{
+		tokenRepository: d.TokenRepository,
+		now:             time.Now,
+	}
+}
+
+// 400 error for unknown scopes, or none
+func (s *APITokenService) Create(ctx context.Context, userId uint, name string, scopes []string) (string, *model.APIToken, error) {
+	var granted []string
+	for _, scope := range scopes {
+		if !model.IsAPITokenScope(scope) {
+			return "", nil, apperrors.NewBadRequest(fmt.Sprintf("Unknown scope %q", scope))
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) == 0 {
+		return "", nil, apperrors.NewBadRequest("A token needs at least one scope")
+	}
+
+	random, err := newRandomToken()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to generate API token", zap.Error(err))
+		return "", nil, apperrors.NewInternal()
+	}
+	token := model.APITokenPrefix + random
+	stored := &model.APIToken{
+		UserID:    userId,
+		Name:      name,
+		TokenHash: model.HashToken(token),
+		Scopes:    strings.Join(granted, " "),
+		CreatedAt: s.now(),
+	}
+	if err := s.tokenRepository.Create(ctx, stored); err != nil {
+		logger.ErrorCtx(ctx, "Unable to save API token", zap.Error(err))
+		return "", nil, apperrors.NewInternal()
+	}
+	logger.InfoCtx(ctx, "User created API token", zap.Uint("user_id", userId), zap.Uint("token_id", stored.ID))
+	return token, stored, nil
+}
+
+func (s *APITokenService) List(ctx context.Context, userId uint) ([]model.APIToken, error) {
+	tokens, err := s.tokenRepository.ListByUserID(ctx, userId)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to list API tokens", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	return tokens, nil
+}
+
+func (s *APITokenService) Revoke(ctx context.Context, userId uint, id uint) error {
+	err := s.tokenRepository.Delete(ctx, userId, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.NewNotFound("API token", strconv.FormatUint(uint64(id), 10))
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to revoke API token", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	logger.InfoCtx(ctx, "User revoked API token", zap.Uint("user_id", userId), zap.Uint("token_id", id))
+	return nil
+}
+
+func (s *APITokenService) RevokeAll(ctx context.Context, userId uint) error {
+	if err := s.tokenRepository.DeleteByUserID(ctx, userId); err != nil {
+		logger.ErrorCtx(ctx, "Unable to revoke API tokens", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	logger.InfoCtx(ctx, "User's API tokens revoked", zap.Uint("user_id", userId))
+	return nil
+}
+
+// 401 error for unknown or revoked tokens
+func (s *APITokenService) Authenticate(ctx context.Context, token string) (*model.APIToken, error) {
+	if !strings.HasPrefix(token, model.APITokenPrefix) {
+		return nil, apperrors.NewUnauthorized()
+	}
+	stored, err := s.tokenRepository.FindByTokenHash(ctx, model.HashToken(token))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to look up API token", zap.Error(err))
+		}
+		return nil, apperrors.NewUnauthorized()
+	}
+
+	now := s.now()
+	if stored.LastUsedAt == nil || now.Sub(*stored.LastUsedAt) >= apiTokenTouchInterval {
+		if err := s.tokenRepository.UpdateLastUsed(ctx, stored.ID, now); err != nil {
+			// only the token list goes stale; not worth failing the request over
+			logger.WarnCtx(ctx, "Unable to update API token last used time", zap.Error(err))
+		} else {
+			stored.LastUsedAt = &now
+		}
+	}
+	return stored, nil
+}
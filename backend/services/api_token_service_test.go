@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/model"
+)
+
+// API token service on an in memory repo, at a clock tests can move
+func newTestAPITokenService(t *testing.T) (*APITokenService, *time.Time) {
+	t.Helper()
+	s := NewAPITokenService(APITokenServiceDeps{
+		TokenRepository: NewMemoryAPITokenRepository(),
+	}).(*APITokenService)
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestAPITokenServiceCreate(t *testing.T) {
+	t.Run("Tokens authenticate, but only their hash is stored", func(t *testing.T) {
+		s, _ := newTestAPITokenService(t)
+
+		token, stored, err := s.Create(context.TODO(), 4, "bot", []string{model.ScopeReadGames, model.ScopeReadGames})
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(token, model.APITokenPrefix))
+		assert.NotContains(t, stored.TokenHash, token)
+		assert.Equal(t, []string{model.ScopeReadGames}, stored.ScopeList())
+
+		found, err := s.Authenticate(context.TODO(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(4), found.UserID)
+		assert.True(t, found.HasScope(model.ScopeReadGames))
+		assert.False(t, found.HasScope(model.ScopePlayMoves))
+	})
+
+	t.Run("Scopes must be known, and there must be some", func(t *testing.T) {
+		s, _ := newTestAPITokenService(t)
+
+		_, _, err := s.Create(context.TODO(), 4, "bot", []string{"admin"})
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		_, _, err = s.Create(context.TODO(), 4, "bot", nil)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+}
+
+func TestAPITokenServiceAuthenticate(t *testing.T) {
+	t.Run("Unknown tokens are unauthorized", func(t *testing.T) {
+		s, _ := newTestAPITokenService(t)
+
+		_, err := s.Authenticate(context.TODO(), model.APITokenPrefix+"nope")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		_, err = s.Authenticate(context.TODO(), "")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Records when tokens were last used", func(t *testing.T) {
+		s, now := newTestAPITokenService(t)
+		token, _, _ := s.Create(context.TODO(), 4, "bot", []string{model.ScopeReadGames})
+
+		found, _ := s.Authenticate(context.TODO(), token)
+		assert.True(t, now.Equal(*found.LastUsedAt))
+
+		*now = now.Add(time.Hour)
+		_, _ = s.Authenticate(context.TODO(), token)
+		tokens, _ := s.List(context.TODO(), 4)
+		assert.True(t, now.Equal(*tokens[0].LastUsedAt))
+	})
+
+	t.Run("Revoked tokens stop working", func(t *testing.T) {
+		s, _ := newTestAPITokenService(t)
+		token, stored, _ := s.Create(context.TODO(), 4, "bot", []string{model.ScopeReadGames})
+
+		err := s.Revoke(context.TODO(), 5, stored.ID)
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+		assert.NoError(t, s.Revoke(context.TODO(), 4, stored.ID))
+
+		_, err = s.Authenticate(context.TODO(), token)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+	t.Run("Revoking all tokens leaves other users' alone", func(t *testing.T) {
+		s, _ := newTestAPITokenService(t)
+		first, _, _ := s.Create(context.TODO(), 4, "bot", []string{model.ScopeReadGames})
+		second, _, _ := s.Create(context.TODO(), 4, "script", []string{model.ScopePlayMoves})
+		other, _, _ := s.Create(context.TODO(), 5, "bot", []string{model.ScopeReadGames})
+
+		assert.NoError(t, s.RevokeAll(context.TODO(), 4))
+
+		for _, token := range []string{first, second} {
+			_, err := s.Authenticate(context.TODO(), token)
+			assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		}
+		_, err := s.Authenticate(context.TODO(), other)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"net-go/server/backend/logger"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// last known state of the db connection
+type DbHealthStatus struct {
+	Healthy   bool
+	Error     error // cause of the last failed check; nil when healthy
+	CheckedAt time.Time
+	Stats     sql.DBStats
+}
+
+/**
+ * Tracks whether the db is reachable by pinging it. Checks log when
+ * the connection is lost and when it recovers, so a db restart shows
+ * up in the logs. Reconnecting itself is left to database/sql.
+ */
+type DbHealthMonitor struct {
+	db       *gorm.DB
+	interval time.Duration
+	timeout  time.Duration
+
+	mu     sync.RWMutex
+	status DbHealthStatus
+}
+
+type DbHealthMonitorDeps struct {
+	Db *gorm.DB
+	// time between checks made by Run
+	Interval time.Duration
+	// how long a single ping may take before the db counts as unhealthy
+	Timeout time.Duration
+}
+
+func NewDbHealthMonitor(deps *DbHealthMonitorDeps) *DbHealthMonitor {
+	return &DbHealthMonitor{
+		db:       deps.Db,
+		interval: deps.Interval,
+		timeout:  deps.Timeout,
+		// the db was reachable when it was opened
+		status: DbHealthStatus{Healthy: true, CheckedAt: time.Now()},
+	}
+}
+
+// Pings the db, updating the reported status. Returns the ping error, if any.
+func (m *DbHealthMonitor) Check(ctx context.Context) error {
+	sqlDb, err := m.db.DB()
+	if err == nil {
+		pingCtx, cancel := context.WithTimeout(ctx, m.timeout)
+		err = sqlDb.PingContext(pingCtx)
+		cancel()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wasHealthy := m.status.Healthy
+	m.status = DbHealthStatus{
+		Healthy:   err == nil,
+		Error:     err,
+		CheckedAt: time.Now(),
+	}
+	if sqlDb != nil {
+		m.status.Stats = sqlDb.Stats()
+	}
+
+	if wasHealthy && err != nil {
+		logger.Error("Lost connection to database: %v", err)
+	} else if !wasHealthy && err == nil {
+		logger.Info("Reconnected to database")
+	}
+	return err
+}
+
+// status as of the most recent check
+func (m *DbHealthMonitor) Status() DbHealthStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Checks the db every interval until `ctx` is done.
+func (m *DbHealthMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDbHealthMonitor(t *testing.T) {
+	t.Run("Reports reachable db as healthy", func(t *testing.T) {
+		monitor := NewDbHealthMonitor(&DbHealthMonitorDeps{
+			Db:      newTestDb(t),
+			Timeout: time.Second,
+		})
+
+		err := monitor.Check(context.TODO())
+
+		assert.NoError(t, err)
+		status := monitor.Status()
+		assert.True(t, status.Healthy)
+		assert.Nil(t, status.Error)
+		assert.GreaterOrEqual(t, status.Stats.OpenConnections, 1)
+	})
+
+	t.Run("Reports unreachable db as unhealthy", func(t *testing.T) {
+		db := newTestDb(t)
+		monitor := NewDbHealthMonitor(&DbHealthMonitorDeps{
+			Db:      db,
+			Timeout: time.Second,
+		})
+		sqlDb, _ := db.DB()
+		sqlDb.Close()
+
+		err := monitor.Check(context.TODO())
+
+		assert.Error(t, err)
+		status := monitor.Status()
+		assert.False(t, status.Healthy)
+		assert.Equal(t, err, status.Error)
+	})
+
+	t.Run("Run checks until cancelled", func(t *testing.T) {
+		monitor := NewDbHealthMonitor(&DbHealthMonitorDeps{
+			Db:       newTestDb(t),
+			Interval: time.Millisecond,
+			Timeout:  time.Second,
+		})
+		initialCheck := monitor.Status().CheckedAt
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+
+		go func() {
+			monitor.Run(ctx)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool {
+			return monitor.Status().CheckedAt.After(initialCheck)
+		}, time.Second, time.Millisecond)
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Run did not return after cancel")
+		}
+	})
+}
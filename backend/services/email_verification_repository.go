@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* interface */
+
+type IEmailVerificationRepository interface {
+	Create(ctx context.Context, t *model.EmailVerificationToken) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error)
+	// gorm.ErrRecordNotFound if token `id` was already used; this is what
+	// keeps a token single use when it's redeemed twice at once
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) error
+	// drop all of a user's tokens, e.g. once they've verified an address
+	DeleteByUserID(ctx context.Context, userId uint) error
+}
+
+/* implementation */
+
+type EmailVerificationRepository struct {
+	BaseRepository
+}
+
+type EmailVerificationRepoDeps struct {
+	BaseDeps *BaseRepoDeps
+}
+
+func NewEmailVerificationRepository(deps *EmailVerificationRepoDeps) IEmailVerificationRepository {
+	return &EmailVerificationRepository{
+		BaseRepository: NewBaseRepository(deps.BaseDeps),
+	}
+}
+
+func (r *EmailVerificationRepository) Create(ctx context.Context, token *model.EmailVerificationToken) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "EmailVerificationRepository.Create")
+	defer endSpan()
+	return r.Db.WithContext(ctx).Create(token).Error
+}
+
+func (r *EmailVerificationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "EmailVerificationRepository.FindByTokenHash")
+	defer endSpan()
+	var token model.EmailVerificationToken
+	err := r.Db.WithContext(ctx).
+		First(&token, "token_hash = ?", tokenHash).
+		Error
+	return &token, err
+}
+
+func (r *EmailVerificationRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "EmailVerificationRepository.MarkUsed")
+	defer endSpan()
+	result := r.Db.WithContext(ctx).
+		Model(&model.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *EmailVerificationRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "EmailVerificationRepository.DeleteByUserID")
+	defer endSpan()
+	return r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.EmailVerificationToken{}).
+		Error
+}
@@ -2,18 +2,26 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net-go/server/backend/instrumentation"
 	"net-go/server/backend/model"
+	"net-go/server/backend/model/types"
+	"time"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 /* interface */
 
+// returned by IGameRepository.Update when the stored game version has moved on
+var ErrVersionConflict = errors.New("game version conflict")
+
 type IGameRepository interface {
-	IMigratable
 	FindByID(ctx context.Context, id uint) (*model.Game, error)
 	ListByUserID(ctx context.Context, userId uint) ([]model.Game, error)
+	ListSummaries(ctx context.Context, opts model.GameListOptions) ([]model.GameSummary, error)
 	Create(ctx context.Context, g *model.Game) error
 	Update(ctx context.Context, g *model.Game) error
 	Delete(ctx context.Context, gameID uint) error
@@ -36,7 +44,7 @@ func NewGameRepository(deps *GameRepoDeps) IGameRepository {
 }
 
 func (g *GameRepository) FindByID(ctx context.Context, id uint) (*model.Game, error) {
-	ctx, endSpan := instrumentation.StartDbTrace("GameRepository.FindByID")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "GameRepository.FindByID")
 	defer endSpan()
 	var game model.Game
 	// Preload fills the fk references in the object
@@ -48,7 +56,7 @@ func (g *GameRepository) FindByID(ctx context.Context, id uint) (*model.Game, er
 }
 
 func (g *GameRepository) ListByUserID(ctx context.Context, userId uint) ([]model.Game, error) {
-	ctx, endSpan := instrumentation.StartDbTrace("GameRepository.ListByUserID")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "GameRepository.ListByUserID")
 	defer endSpan()
 	var games []model.Game
 	err := g.Db.WithContext(ctx).
@@ -59,33 +67,162 @@ func (g *GameRepository) ListByUserID(ctx context.Context, userId uint) ([]model
 	return games, err
 }
 
+/**
+ * Lists at most `opts.Limit` (or all, when 0) summaries of the games matching `opts`,
+ * starting after `opts.Cursor` when it is set.
+ */
+func (g *GameRepository) ListSummaries(ctx context.Context, opts model.GameListOptions) ([]model.GameSummary, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "GameRepository.ListSummaries")
+	defer endSpan()
+
+	query := g.Db.WithContext(ctx).
+		Preload("BlackPlayer").
+		Preload("WhitePlayer").
+		Where("(white_player_id = ? OR black_player_id = ?)", opts.UserId, opts.UserId)
+
+	switch opts.Status {
+	case model.ActiveGames:
+		query = query.Where("is_over = ?", false)
+	case model.FinishedGames:
+		query = query.Where("is_over = ?", true)
+	}
+
+	switch opts.Turn {
+	case model.MyTurn:
+		query = query.Where("active_player_id = ?", opts.UserId)
+	case model.OpponentTurn:
+		query = query.Where("active_player_id <> ? AND active_player_id <> 0", opts.UserId)
+	}
+
+	if opts.OpponentId != 0 {
+		query = query.Where("(white_player_id = ? OR black_player_id = ?)", opts.OpponentId, opts.OpponentId)
+	}
+	if opts.BoardSize != types.Undefined {
+		query = query.Where("board_size = ?", opts.BoardSize)
+	}
+	if !opts.CreatedAfter.IsZero() {
+		query = query.Where("created_at >= ?", opts.CreatedAfter)
+	}
+	if !opts.CreatedBefore.IsZero() {
+		query = query.Where("created_at < ?", opts.CreatedBefore)
+	}
+
+	// keyset pagination; id breaks ties between equal timestamps
+	column, desc := opts.Sort.Column()
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+	if opts.Cursor != nil {
+		query = query.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, comparison, column, comparison),
+			opts.Cursor.SortValue, opts.Cursor.SortValue, opts.Cursor.ID,
+		)
+	}
+
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+
+	var summaries []model.GameSummary
+	err := query.
+		Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)).
+		Find(&summaries).Error
+	return summaries, err
+}
+
 func (g *GameRepository) Create(ctx context.Context, game *model.Game) error {
-	ctx, endSpan := instrumentation.StartDbTrace("GameRepository.Update")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "GameRepository.Create")
 	defer endSpan()
-	err := g.Db.WithContext(ctx).Create(game).Error
-	return err
+	return g.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(game).Error; err != nil {
+			return err
+		}
+		return syncGameMoves(tx, game)
+	})
 }
 
+/**
+ * Writes `game` only if the stored game is still at `game.Version`,
+ * bumping the version on success. Returns ErrVersionConflict if another
+ * update got there first (or the game no longer exists).
+ */
 func (g *GameRepository) Update(ctx context.Context, game *model.Game) error {
-	ctx, endSpan := instrumentation.StartDbTrace("GameRepository.Update")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "GameRepository.Update")
 	defer endSpan()
-	err := g.Db.WithContext(ctx).Save(game).Error
+	expectedVersion := game.Version
+	game.Version = expectedVersion + 1
+	err := g.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(game).
+			Where("version = ?", expectedVersion).
+			Select("*").
+			Omit(clause.Associations, "created_at", "deleted_at").
+			Updates(game)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return syncGameMoves(tx, game)
+	})
+	if err != nil {
+		game.Version = expectedVersion
+	}
 	return err
 }
 
+/**
+ * Makes the game_moves rows of `game` match its History. History is
+ * newest first, so move number n is History[len(History)-n]. Stored
+ * rows are compared against the History from the first move on; from
+ * the first row that differs (an undone or rewritten move) everything
+ * is replaced, and rows before it keep their original PlayedAt.
+ */
+func syncGameMoves(tx *gorm.DB, game *model.Game) error {
+	var stored []model.GameMove
+	err := tx.
+		Where("game_id = ?", game.ID).
+		Order("move_number").
+		Find(&stored).Error
+	if err != nil {
+		return err
+	}
+
+	historyLen := len(game.History)
+	now := time.Now()
+	kept := 0
+	for kept < len(stored) && kept < historyLen {
+		move := model.NewGameMove(game.ID, uint(kept+1), game.History[historyLen-kept-1], &now)
+		if !stored[kept].SameMove(move) {
+			break
+		}
+		kept++
+	}
+
+	if kept < len(stored) {
+		err := tx.
+			Where("game_id = ? AND move_number > ?", game.ID, kept).
+			Delete(&model.GameMove{}).Error
+		if err != nil {
+			return err
+		}
+	}
+	if kept == historyLen {
+		return nil
+	}
+
+	moves := make([]model.GameMove, 0, historyLen-kept)
+	for n := kept + 1; n <= historyLen; n++ {
+		moves = append(moves, model.NewGameMove(game.ID, uint(n), game.History[historyLen-n], &now))
+	}
+	return tx.Create(&moves).Error
+}
+
 func (g *GameRepository) Delete(ctx context.Context, gameID uint) error {
-	ctx, endSpan := instrumentation.StartDbTrace("GameRepository.Delete")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "GameRepository.Delete")
 	defer endSpan()
 	err := g.Db.WithContext(ctx).Delete(&model.Game{}, gameID).Error
 	return err
 }
-
-/*
- * Performs necessary migrations on tables correlating to gorm models.
- * Does not delete unused columns; only creates missing columns etc.
- * https://gorm.io/docs/migration.html
- */
-func (g *GameRepository) MigrateAll() error {
-	err := g.Db.AutoMigrate(&model.Game{})
-	return err
-}
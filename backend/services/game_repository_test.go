@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+	"net-go/server/backend/model/types"
+)
+
+func TestGameRepositoryIntegration(t *testing.T) {
+	t.Run("Custom column types round trip", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+
+		forfeit := types.White
+		game := &model.Game{
+			Board: types.Board{
+				Size: types.Small,
+				Map: types.PlayArea{
+					{types.BlackStone, types.None},
+					{types.None, types.WhiteStone},
+				},
+			},
+			History: model.MoveSlice{
+				{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 0},
+				{MoveType: types.Pass, Piece: types.WhiteStone},
+			},
+			IsOver:        true,
+			Score:         types.Score{ForfeitColor: &forfeit, Komi: 6.5},
+			BlackPlayerId: users[0].ID,
+			WhitePlayerId: users[1].ID,
+		}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+
+		found, err := gameRepo.FindByID(context.TODO(), game.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, game.Board, found.Board)
+		assert.Equal(t, game.History, found.History)
+		assert.Equal(t, game.Score, found.Score)
+		assert.Equal(t, "tim", found.BlackPlayer.Username)
+		assert.Equal(t, "tom", found.WhitePlayer.Username)
+	})
+
+	t.Run("Scalar columns scan from raw driver values", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		game := &model.Game{
+			Board:         types.Board{Size: types.Medium},
+			History:       model.MoveSlice{{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 7}},
+			BlackPlayerId: users[0].ID,
+			WhitePlayerId: users[1].ID,
+		}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+
+		// scanning columns directly goes through the types' Scan methods with
+		// whatever the driver returns (int64 for integer columns in sqlite)
+		var size types.BoardSize
+		err := gameRepo.Db.Raw("SELECT board_size FROM games WHERE id = ?", game.ID).Row().Scan(&size)
+		assert.NoError(t, err)
+		assert.Equal(t, types.Medium, size)
+
+		var color types.ColorChoice
+		var moveType types.MoveType
+		err = gameRepo.Db.
+			Raw("SELECT color, move_type FROM game_moves WHERE game_id = ?", game.ID).
+			Row().
+			Scan(&color, &moveType)
+		assert.NoError(t, err)
+		assert.Equal(t, types.Black, color)
+		assert.Equal(t, types.PlayPiece, moveType)
+
+		// and values bind as query parameters through their Value methods
+		var piece types.Piece
+		err = gameRepo.Db.Raw("SELECT ?", types.WhiteStone).Row().Scan(&piece)
+		assert.NoError(t, err)
+		assert.Equal(t, types.WhiteStone, piece)
+	})
+
+	t.Run("Missing game is not found", func(t *testing.T) {
+		_, gameRepo := newTestRepos(t)
+
+		_, err := gameRepo.FindByID(context.TODO(), 42)
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Queries stop when the request is cancelled", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		game := &model.Game{BlackPlayerId: users[0].ID, WhitePlayerId: users[1].ID}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, findErr := gameRepo.FindByID(ctx, game.ID)
+		game.IsOver = true
+		updateErr := gameRepo.Update(ctx, game)
+
+		assert.ErrorIs(t, findErr, context.Canceled)
+		assert.ErrorIs(t, updateErr, context.Canceled)
+		found, err := gameRepo.FindByID(context.TODO(), game.ID)
+		assert.NoError(t, err)
+		assert.False(t, found.IsOver)
+	})
+
+	t.Run("ListByUserID returns games of either color with players", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom", "bob")
+		tim, tom, bob := users[0], users[1], users[2]
+		for _, players := range [][2]*model.User{{tim, tom}, {tom, bob}, {bob, tim}} {
+			assert.NoError(t, gameRepo.Create(context.TODO(), &model.Game{
+				BlackPlayerId: players[0].ID,
+				WhitePlayerId: players[1].ID,
+			}))
+		}
+
+		games, err := gameRepo.ListByUserID(context.TODO(), tim.ID)
+
+		assert.NoError(t, err)
+		assert.Len(t, games, 2)
+		assert.Equal(t, "tim", games[0].BlackPlayer.Username)
+		assert.Equal(t, "tom", games[0].WhitePlayer.Username)
+		assert.Equal(t, "bob", games[1].BlackPlayer.Username)
+		assert.Equal(t, "tim", games[1].WhitePlayer.Username)
+
+		games, err = gameRepo.ListByUserID(context.TODO(), 42)
+		assert.NoError(t, err)
+		assert.Empty(t, games)
+	})
+
+	t.Run("Update saves changes, moves and version", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		game := &model.Game{BlackPlayerId: users[0].ID, WhitePlayerId: users[1].ID}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+
+		// newest first, like the client sends it
+		game.History = model.MoveSlice{
+			{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 5},
+			{MoveType: types.Pass, Piece: types.WhiteStone},
+			{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 3},
+		}
+		assert.NoError(t, gameRepo.Update(context.TODO(), game))
+		found, err := gameRepo.FindByID(context.TODO(), game.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, game.History, found.History)
+		assert.Equal(t, uint(2), found.Version)
+		assert.Equal(t, uint(3), found.MoveCount)
+		assert.Equal(t, users[1].ID, found.ActivePlayerId)
+		// associations are left alone by updates
+		assert.Equal(t, "tim", found.BlackPlayer.Username)
+
+		var moves []model.GameMove
+		assert.NoError(t, gameRepo.Db.Order("move_number").Find(&moves, "game_id = ?", game.ID).Error)
+		assert.Len(t, moves, 3)
+		assert.Equal(t, uint(3), moves[0].Coord)
+		assert.Equal(t, types.White, moves[1].Color)
+		assert.Equal(t, types.Pass, moves[1].MoveType)
+		assert.NotNil(t, moves[1].PlayedAt)
+		assert.Equal(t, uint(5), moves[2].Coord)
+
+		// undoing the newest move drops its row
+		found.History = found.History[1:]
+		assert.NoError(t, gameRepo.Update(context.TODO(), found))
+		moves = nil
+		assert.NoError(t, gameRepo.Db.Order("move_number").Find(&moves, "game_id = ?", game.ID).Error)
+		assert.Len(t, moves, 2)
+		assert.Equal(t, uint(3), moves[0].Coord)
+		// the newest move is now white's pass, so it is black's turn
+		found, err = gameRepo.FindByID(context.TODO(), game.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), found.MoveCount)
+		assert.Equal(t, users[0].ID, found.ActivePlayerId)
+	})
+
+	t.Run("Create and Update store one ordered move row per move", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		game := &model.Game{
+			History: model.MoveSlice{
+				{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 4},
+			},
+			BlackPlayerId: users[0].ID,
+			WhitePlayerId: users[1].ID,
+		}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+		var first model.GameMove
+		assert.NoError(t, gameRepo.Db.First(&first, "game_id = ?", game.ID).Error)
+
+		// new moves go on the front of the History
+		game.History = append(model.MoveSlice{
+			{MoveType: types.Pass, Piece: types.BlackStone},
+			{MoveType: types.PlayPiece, Piece: types.WhiteStone, Coord: 9},
+		}, game.History...)
+		assert.NoError(t, gameRepo.Update(context.TODO(), game))
+		// saving again without new moves must not duplicate rows
+		assert.NoError(t, gameRepo.Update(context.TODO(), game))
+
+		var moves []model.GameMove
+		assert.NoError(t, gameRepo.Db.Order("move_number").Find(&moves, "game_id = ?", game.ID).Error)
+		assert.Len(t, moves, 3)
+		for i, move := range moves {
+			historyMove := game.History[len(game.History)-i-1]
+			assert.Equal(t, uint(i+1), move.MoveNumber)
+			assert.Equal(t, historyMove.MoveType, move.MoveType)
+			assert.Equal(t, historyMove.Coord, move.Coord)
+			assert.Nil(t, move.TimeLeftMs)
+		}
+		assert.Equal(t, types.Black, moves[0].Color)
+		assert.Equal(t, types.White, moves[1].Color)
+		assert.Equal(t, types.Black, moves[2].Color)
+		// rows of moves already stored are left alone
+		assert.Equal(t, first.ID, moves[0].ID)
+	})
+
+	t.Run("Update replaces rewritten moves of the same length", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		game := &model.Game{
+			History: model.MoveSlice{
+				{MoveType: types.PlayPiece, Piece: types.WhiteStone, Coord: 9},
+				{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 4},
+			},
+			BlackPlayerId: users[0].ID,
+			WhitePlayerId: users[1].ID,
+		}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+
+		// white's move was undone and replayed somewhere else
+		game.History = model.MoveSlice{
+			{MoveType: types.Pass, Piece: types.WhiteStone},
+			{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 4},
+		}
+		assert.NoError(t, gameRepo.Update(context.TODO(), game))
+
+		var moves []model.GameMove
+		assert.NoError(t, gameRepo.Db.Order("move_number").Find(&moves, "game_id = ?", game.ID).Error)
+		assert.Len(t, moves, 2)
+		assert.Equal(t, uint(4), moves[0].Coord)
+		assert.Equal(t, types.Pass, moves[1].MoveType)
+		assert.Equal(t, uint(0), moves[1].Coord)
+	})
+
+	t.Run("Update with a stale version conflicts", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		game := &model.Game{BlackPlayerId: users[0].ID, WhitePlayerId: users[1].ID}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+		first, _ := gameRepo.FindByID(context.TODO(), game.ID)
+		second, _ := gameRepo.FindByID(context.TODO(), game.ID)
+
+		first.IsOver = true
+		assert.NoError(t, gameRepo.Update(context.TODO(), first))
+		second.History = model.MoveSlice{{MoveType: types.Pass, Piece: types.BlackStone}}
+		err := gameRepo.Update(context.TODO(), second)
+
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		// the rejected update's version is restored so it can be retried
+		assert.Equal(t, uint(1), second.Version)
+		stored, _ := gameRepo.FindByID(context.TODO(), game.ID)
+		assert.True(t, stored.IsOver)
+		assert.Empty(t, stored.History)
+	})
+
+	t.Run("Delete soft deletes", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		game := &model.Game{BlackPlayerId: users[0].ID, WhitePlayerId: users[1].ID}
+		assert.NoError(t, gameRepo.Create(context.TODO(), game))
+
+		assert.NoError(t, gameRepo.Delete(context.TODO(), game.ID))
+		_, err := gameRepo.FindByID(context.TODO(), game.ID)
+		games, listErr := gameRepo.ListByUserID(context.TODO(), users[0].ID)
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, listErr)
+		assert.Empty(t, games)
+		var deleted model.Game
+		assert.NoError(t, gameRepo.Db.Unscoped().First(&deleted, game.ID).Error)
+		assert.True(t, deleted.DeletedAt.Valid)
+	})
+
+	t.Run("ListSummaries filters and paginates", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom", "bob")
+		tim, tom, bob := users[0], users[1], users[2]
+		newGame := func(black, white *model.User, size types.BoardSize, isOver bool) *model.Game {
+			game := &model.Game{
+				Board:         types.Board{Size: size},
+				IsOver:        isOver,
+				BlackPlayerId: black.ID,
+				WhitePlayerId: white.ID,
+			}
+			assert.NoError(t, gameRepo.Create(context.TODO(), game))
+			return game
+		}
+		// tim's turn in the active games where tim is black
+		mine1 := newGame(tim, tom, types.Small, false)
+		theirs := newGame(bob, tim, types.Small, false)
+		mine2 := newGame(tim, bob, types.Full, false)
+		mine3 := newGame(tim, tom, types.Small, false)
+		finished := newGame(tom, tim, types.Full, true)
+		newGame(tom, bob, types.Small, false)
+
+		opts := model.GameListOptions{
+			UserId: tim.ID,
+			Status: model.ActiveGames,
+			Turn:   model.MyTurn,
+			Sort:   model.CreatedAsc,
+			Limit:  2,
+		}
+		page, err := gameRepo.ListSummaries(context.TODO(), opts)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{mine1.ID, mine2.ID}, summaryIds(page))
+		assert.Equal(t, "tom", page[0].WhitePlayer.Username)
+
+		cursor := model.NewGameCursor(page[1], opts.Sort)
+		opts.Cursor = &cursor
+		page, err = gameRepo.ListSummaries(context.TODO(), opts)
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{mine3.ID}, summaryIds(page))
+
+		page, err = gameRepo.ListSummaries(context.TODO(), model.GameListOptions{
+			UserId: tim.ID,
+			Turn:   model.OpponentTurn,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{theirs.ID}, summaryIds(page))
+
+		page, err = gameRepo.ListSummaries(context.TODO(), model.GameListOptions{
+			UserId:    tim.ID,
+			Status:    model.FinishedGames,
+			BoardSize: types.Full,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{finished.ID}, summaryIds(page))
+
+		page, err = gameRepo.ListSummaries(context.TODO(), model.GameListOptions{
+			UserId:     tim.ID,
+			OpponentId: tom.ID,
+			Sort:       model.CreatedDesc,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{finished.ID, mine3.ID, mine1.ID}, summaryIds(page))
+	})
+
+	t.Run("Pending games follow the client's newest first History", func(t *testing.T) {
+		userRepo, gameRepo := newTestRepos(t)
+		users := createTestUsers(t, userRepo, "tim", "tom")
+		tim, tom := users[0], users[1]
+		// tom (white) just passed after tim's opening move, so it's tim's turn
+		timsTurn := &model.Game{
+			History: model.MoveSlice{
+				{MoveType: types.Pass, Piece: types.WhiteStone},
+				{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 4},
+			},
+			BlackPlayerId: tim.ID,
+			WhitePlayerId: tom.ID,
+		}
+		assert.NoError(t, gameRepo.Create(context.TODO(), timsTurn))
+		// tim played again in reply, so it's tom's turn
+		tomsTurn := &model.Game{
+			History: model.MoveSlice{
+				{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 7},
+				{MoveType: types.Pass, Piece: types.WhiteStone},
+				{MoveType: types.PlayPiece, Piece: types.BlackStone, Coord: 4},
+			},
+			BlackPlayerId: tim.ID,
+			WhitePlayerId: tom.ID,
+		}
+		assert.NoError(t, gameRepo.Create(context.TODO(), tomsTurn))
+		gameService := NewGameService(GameServiceDeps{GameRepository: gameRepo})
+
+		timsPending, err := gameService.ListPending(context.TODO(), tim.ID)
+		assert.NoError(t, err)
+		tomsPending, tomErr := gameService.ListPending(context.TODO(), tom.ID)
+		assert.NoError(t, tomErr)
+
+		assert.Equal(t, []uint{timsTurn.ID}, summaryIds(timsPending))
+		assert.Equal(t, []uint{tomsTurn.ID}, summaryIds(tomsPending))
+	})
+}
+
+func summaryIds(summaries []model.GameSummary) []uint {
+	ids := make([]uint, 0, len(summaries))
+	for _, summary := range summaries {
+		ids = append(ids, summary.ID)
+	}
+	return ids
+}
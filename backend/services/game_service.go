@@ -2,19 +2,24 @@ package services
 
 import (
 	"context"
+	"errors"
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/logger"
 	"net-go/server/backend/model"
 	"strconv"
+
+	"go.uber.org/zap"
 )
 
 /* interfaces */
 
 // methods the router handler layer interacts with
 type IGameService interface {
-	IMigratable
 	Get(ctx context.Context, id uint) (*model.Game, error)
 	ListByUser(ctx context.Context, userId uint) ([]model.Game, error)
+	ListSummaries(ctx context.Context, opts model.GameListOptions) (*model.GameSummaryPage, error)
+	ListPending(ctx context.Context, userId uint) ([]model.GameSummary, error)
+	GetUserStats(ctx context.Context, userId uint) (*model.UserStats, error)
 	Delete(ctx context.Context, gameID uint) error
 	Create(ctx context.Context, game *model.Game) error
 	Update(ctx context.Context, game *model.Game) error
@@ -54,17 +59,75 @@ func (s *GameService) ListByUser(ctx context.Context, userId uint) ([]model.Game
 	return games, err
 }
 
+/**
+ * Fetches one page of game summaries matching `opts`, along with
+ * the cursor for the following page (if there is one).
+ */
+func (s *GameService) ListSummaries(ctx context.Context, opts model.GameListOptions) (*model.GameSummaryPage, error) {
+	// fetch 1 extra to know whether there is another page
+	pageSize := opts.Limit
+	opts.Limit = pageSize + 1
+	summaries, err := s.gameRepository.ListSummaries(ctx, opts)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Error listing game summaries", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+
+	page := &model.GameSummaryPage{Games: summaries}
+	if len(summaries) > pageSize {
+		page.Games = summaries[:pageSize]
+		cursor := model.NewGameCursor(page.Games[pageSize-1], opts.Sort)
+		page.NextCursor = &cursor
+	}
+	return page, nil
+}
+
+/**
+ * Lists every unfinished game where it is user `userId`'s turn,
+ * longest waiting first. A game's UpdatedAt is the time of its last
+ * move, so that is how long the user has kept their opponent waiting.
+ */
+func (s *GameService) ListPending(ctx context.Context, userId uint) ([]model.GameSummary, error) {
+	summaries, err := s.gameRepository.ListSummaries(ctx, model.GameListOptions{
+		UserId: userId,
+		Status: model.ActiveGames,
+		Turn:   model.MyTurn,
+		Sort:   model.UpdatedAsc,
+	})
+	if err != nil {
+		logger.ErrorCtx(ctx, "Error listing pending games", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	return summaries, nil
+}
+
+// aggregate win/loss stats over all games user `userId` is a member of
+func (s *GameService) GetUserStats(ctx context.Context, userId uint) (*model.UserStats, error) {
+	games, err := s.gameRepository.ListByUserID(ctx, userId)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Error listing games for user stats", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	stats := model.BuildUserStats(userId, games)
+	return &stats, nil
+}
+
 func (s *GameService) Create(ctx context.Context, game *model.Game) error {
 	if err := s.gameRepository.Create(ctx, game); err != nil {
-		logger.Error("Error creating game: %v", err)
+		logger.ErrorCtx(ctx, "Error creating game", zap.Error(err))
 		return apperrors.NewInternal()
 	}
 	return nil
 }
 
+// update game, so long as the stored game is still at `game.Version`
 func (s *GameService) Update(ctx context.Context, game *model.Game) error {
 	if err := s.gameRepository.Update(ctx, game); err != nil {
-		logger.Error("Error updating game: %v", err)
+		if errors.Is(err, ErrVersionConflict) {
+			logger.DebugCtx(ctx, "Rejected stale game update", zap.Uint("game_id", game.ID), zap.Uint("version", game.Version))
+			return apperrors.NewVersionConflict("Game", strconv.FormatUint(uint64(game.ID), 10))
+		}
+		logger.ErrorCtx(ctx, "Error updating game", zap.Error(err))
 		return apperrors.NewInternal()
 	}
 	return nil
@@ -72,12 +135,8 @@ func (s *GameService) Update(ctx context.Context, game *model.Game) error {
 
 func (s *GameService) Delete(ctx context.Context, gameID uint) error {
 	if err := s.gameRepository.Delete(ctx, gameID); err != nil {
-		logger.Debug("Error deleting game: %v", err)
+		logger.DebugCtx(ctx, "Error deleting game", zap.Error(err))
 		return apperrors.NewNotFound("Game", strconv.FormatUint(uint64(gameID), 10))
 	}
 	return nil
 }
-
-func (s *GameService) MigrateAll() error {
-	return s.gameRepository.MigrateAll()
-}
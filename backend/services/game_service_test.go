@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/apperrors"
 	"net-go/server/backend/model"
 	"net-go/server/backend/model/types"
 	"net-go/server/backend/services/mocks"
@@ -60,3 +62,249 @@ func TestGameServiceGet(t *testing.T) {
 		mockGameRepository.AssertExpectations(t)
 	})
 }
+
+func TestGameServiceGetUserStats(t *testing.T) {
+	newFinishedGame := func(blackId uint, whiteId uint, size types.BoardSize, score types.Score, finishedAt time.Time) model.Game {
+		game := model.Game{
+			Board:         types.Board{Size: size},
+			IsOver:        true,
+			Score:         score,
+			BlackPlayerId: blackId,
+			WhitePlayerId: whiteId,
+		}
+		game.UpdatedAt = finishedAt
+		return game
+	}
+	black := types.Black
+	white := types.White
+	now := time.Now()
+
+	t.Run("Success", func(t *testing.T) {
+		uid := uint(1)
+		games := []model.Game{
+			// oldest: loss as white by resignation
+			newFinishedGame(2, uid, types.Small, types.Score{ForfeitColor: &white}, now.Add(-3*time.Hour)),
+			// win as black by score
+			newFinishedGame(uid, 2, types.Full, types.Score{BlackPoints: 50, WhitePoints: 40, Komi: 6.5}, now.Add(-2*time.Hour)),
+			// most recent: win as white by opponent resignation
+			newFinishedGame(2, uid, types.Full, types.Score{ForfeitColor: &black}, now.Add(-1*time.Hour)),
+			// in progress
+			{BlackPlayerId: uid, WhitePlayerId: 2},
+		}
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("ListByUserID", mock.Anything, uid).Return(games, nil)
+
+		stats, err := gs.GetUserStats(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), stats.GamesPlayed)
+		assert.Equal(t, uint(1), stats.GamesInProgress)
+		assert.Equal(t, model.WinLossRecord{Wins: 2, Losses: 1}, stats.Record)
+		assert.Equal(t, model.WinLossRecord{Wins: 1}, stats.ByColor[types.Black])
+		assert.Equal(t, model.WinLossRecord{Wins: 1, Losses: 1}, stats.ByColor[types.White])
+		assert.Equal(t, model.WinLossRecord{Wins: 2}, stats.ByBoardSize[types.Full])
+		assert.Equal(t, model.WinLossRecord{Losses: 1}, stats.ByBoardSize[types.Small])
+		assert.Equal(t, uint(1), stats.WinsByResignation)
+		assert.Equal(t, uint(1), stats.WinsByScore)
+		assert.Equal(t, uint(1), stats.LossesByResignation)
+		assert.Equal(t, uint(0), stats.LossesByScore)
+		assert.Equal(t, model.Streak{Result: model.WinStreak, Length: 2}, stats.CurrentStreak)
+		mockGameRepository.AssertExpectations(t)
+	})
+
+	t.Run("Draw breaks streak", func(t *testing.T) {
+		uid := uint(1)
+		games := []model.Game{
+			newFinishedGame(uid, 2, types.Full, types.Score{BlackPoints: 10, WhitePoints: 3.5, Komi: 6.5}, now),
+			newFinishedGame(uid, 2, types.Full, types.Score{BlackPoints: 50}, now.Add(-time.Hour)),
+		}
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("ListByUserID", mock.Anything, uid).Return(games, nil)
+
+		stats, err := gs.GetUserStats(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, model.WinLossRecord{Wins: 1, Draws: 1}, stats.Record)
+		assert.Equal(t, model.Streak{}, stats.CurrentStreak)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		uid := uint(rand.Uint32())
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("ListByUserID", mock.Anything, uid).Return(nil, fmt.Errorf("Some error"))
+
+		stats, err := gs.GetUserStats(context.TODO(), uid)
+
+		assert.Error(t, err)
+		assert.Nil(t, stats)
+		mockGameRepository.AssertExpectations(t)
+	})
+}
+
+func TestGameServiceListSummaries(t *testing.T) {
+	newSummary := func(id uint) model.GameSummary {
+		summary := model.GameSummary{}
+		summary.ID = id
+		summary.CreatedAt = time.Now().Add(-time.Duration(id) * time.Hour)
+		return summary
+	}
+
+	t.Run("Returns cursor when there is another page", func(t *testing.T) {
+		opts := model.GameListOptions{
+			UserId: 1,
+			Limit:  2,
+		}
+		summaries := []model.GameSummary{newSummary(1), newSummary(2), newSummary(3)}
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		// one extra summary is requested to check for more pages
+		mockGameRepository.
+			On("ListSummaries", mock.Anything, mock.MatchedBy(func(o model.GameListOptions) bool {
+				return o.Limit == 3
+			})).
+			Return(summaries, nil)
+
+		page, err := gs.ListSummaries(context.TODO(), opts)
+
+		assert.NoError(t, err)
+		assert.Equal(t, summaries[:2], page.Games)
+		assert.Equal(t, &model.GameCursor{SortValue: summaries[1].CreatedAt, ID: 2}, page.NextCursor)
+		mockGameRepository.AssertExpectations(t)
+	})
+
+	t.Run("Last page has no cursor", func(t *testing.T) {
+		opts := model.GameListOptions{
+			UserId: 1,
+			Sort:   model.UpdatedDesc,
+			Limit:  5,
+		}
+		summaries := []model.GameSummary{newSummary(1), newSummary(2)}
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("ListSummaries", mock.Anything, mock.Anything).Return(summaries, nil)
+
+		page, err := gs.ListSummaries(context.TODO(), opts)
+
+		assert.NoError(t, err)
+		assert.Equal(t, summaries, page.Games)
+		assert.Nil(t, page.NextCursor)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("ListSummaries", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("Some error"))
+
+		page, err := gs.ListSummaries(context.TODO(), model.GameListOptions{Limit: 10})
+
+		assert.Error(t, err)
+		assert.Nil(t, page)
+	})
+}
+
+func TestGameServiceListPending(t *testing.T) {
+	t.Run("Lists active games on user's turn, longest waiting first", func(t *testing.T) {
+		uid := uint(rand.Uint32())
+		summaries := []model.GameSummary{{ActivePlayerId: uid}}
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.
+			On("ListSummaries", mock.Anything, model.GameListOptions{
+				UserId: uid,
+				Status: model.ActiveGames,
+				Turn:   model.MyTurn,
+				Sort:   model.UpdatedAsc,
+			}).
+			Return(summaries, nil)
+
+		pending, err := gs.ListPending(context.TODO(), uid)
+
+		assert.NoError(t, err)
+		assert.Equal(t, summaries, pending)
+		mockGameRepository.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("ListSummaries", mock.Anything, mock.Anything).Return(nil, fmt.Errorf("Some error"))
+
+		pending, err := gs.ListPending(context.TODO(), 1)
+
+		assert.Error(t, err)
+		assert.Nil(t, pending)
+	})
+}
+
+func TestGameServiceUpdate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		game := &model.Game{Version: 1}
+		game.ID = uint(rand.Uint32())
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("Update", mock.Anything, game).Return(nil)
+
+		err := gs.Update(context.TODO(), game)
+
+		assert.NoError(t, err)
+		mockGameRepository.AssertExpectations(t)
+	})
+
+	t.Run("Version conflict", func(t *testing.T) {
+		game := &model.Game{Version: 1}
+		game.ID = uint(rand.Uint32())
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("Update", mock.Anything, game).Return(ErrVersionConflict)
+
+		err := gs.Update(context.TODO(), game)
+
+		assert.Equal(t, apperrors.Conflict, err.(*apperrors.Error).Type)
+		mockGameRepository.AssertExpectations(t)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		game := &model.Game{Version: 1}
+
+		mockGameRepository := new(mocks.MockGameRepository)
+		gs := NewGameService(GameServiceDeps{
+			GameRepository: mockGameRepository,
+		})
+		mockGameRepository.On("Update", mock.Anything, game).Return(fmt.Errorf("Some error"))
+
+		err := gs.Update(context.TODO(), game)
+
+		assert.Equal(t, apperrors.Internal, err.(*apperrors.Error).Type)
+	})
+}
@@ -1,41 +1,206 @@
 package services
 
 import (
+	"database/sql"
+	"fmt"
+	"net"
 	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/logger"
+	"net/url"
+	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-type IMigratable interface {
-	// utility method for Repository types db setup/migration
-	MigrateAll() error
-}
-
-var dbSingleton *gorm.DB
+// supported values for the DB_DRIVER env var
+const (
+	MySQLDriver    = "mysql"
+	PostgresDriver = "postgres"
+	SQLiteDriver   = "sqlite"
+)
 
 type BaseRepository struct {
 	Db *gorm.DB
 }
 
 type BaseRepoDeps struct {
+	Db *gorm.DB
+}
+
+// connection details for building a DbString
+type DbConnectionInfo struct {
+	User     string
+	Password string
+	Host     string
+	Port     string // driver default port when empty
+	Name     string // file path for sqlite
+	SSLMode  string // postgres only; "require" when empty
+}
+
+// database/sql pool settings; zero values keep the database/sql defaults
+type DbPoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+type DbConfig struct {
+	// one of MySQLDriver, PostgresDriver or SQLiteDriver
+	Driver string
 	// auth/location string for connecting to db
 	DbString string
 	Config   *gorm.Config
+	Pool     DbPoolConfig
+	// attempts at connecting before OpenDb gives up (at least 1 is made)
+	ConnectAttempts int
+	// wait after the first failed attempt; doubles after each
+	// failed attempt, up to MaxBackoff (uncapped when 0)
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
 }
 
 func NewBaseRepository(deps *BaseRepoDeps) BaseRepository {
-	if dbSingleton == nil {
-		dbSingleton = openDbConnection(deps.DbString, deps.Config)
+	return BaseRepository{Db: deps.Db}
+}
+
+/**
+ * Builds the connection string for `driver` in the format
+ * expected by its gorm dialector.
+ */
+func BuildDbString(driver string, info DbConnectionInfo) (string, error) {
+	switch driver {
+	case MySQLDriver:
+		port := info.Port
+		if port == "" {
+			port = "3306"
+		}
+		return fmt.Sprintf(
+			"%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+			info.User, info.Password, info.Host, port, info.Name,
+		), nil
+	case PostgresDriver:
+		port := info.Port
+		if port == "" {
+			port = "5432"
+		}
+		sslMode := info.SSLMode
+		if sslMode == "" {
+			sslMode = "require"
+		}
+		// a URL escapes credentials that would break a key=value string
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(info.User, info.Password),
+			Host:     net.JoinHostPort(info.Host, port),
+			Path:     "/" + info.Name,
+			RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+		}
+		return dsn.String(), nil
+	case SQLiteDriver:
+		// enforce foreign keys, which sqlite leaves off by default
+		return fmt.Sprintf("file:%s?_pragma=foreign_keys(1)", info.Name), nil
+	default:
+		return "", fmt.Errorf("unsupported database driver: %q", driver)
+	}
+}
+
+func dialectorFor(driver string, dbConnectionString string) (gorm.Dialector, error) {
+	switch driver {
+	case MySQLDriver:
+		return mysql.Open(dbConnectionString), nil
+	case PostgresDriver:
+		return postgres.Open(dbConnectionString), nil
+	case SQLiteDriver:
+		return sqlite.Open(dbConnectionString), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %q", driver)
 	}
-	return BaseRepository{Db: dbSingleton}
 }
 
-func openDbConnection(dbConnectionString string, config *gorm.Config) *gorm.DB {
-	db, err := gorm.Open(mysql.Open(dbConnectionString), config)
+/**
+ * Opens a connection pool to the db described by `config`. While the db
+ * can't be reached (e.g. it is still starting up), retries with exponential
+ * backoff. Once open, database/sql replaces broken connections on its own,
+ * so a db restart only fails the queries made while it is down.
+ */
+func OpenDb(config DbConfig) (*gorm.DB, error) {
+	backoff := config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		db, err := connectDb(config)
+		if err == nil {
+			instrumentation.InstrumentDbConnection(db)
+			if err := instrumentation.InstrumentDbMetrics(db); err != nil {
+				return nil, err
+			}
+			return db, nil
+		}
+		if attempt >= config.ConnectAttempts {
+			return nil, fmt.Errorf("failed to connect to database after %d attempt(s): %w", attempt, err)
+		}
+
+		logger.Warn("Failed to connect to database (attempt %d of %d), retrying in %v: %v",
+			attempt, config.ConnectAttempts, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if config.MaxBackoff > 0 && backoff > config.MaxBackoff {
+			backoff = config.MaxBackoff
+		}
+	}
+}
+
+// makes a single attempt at opening and pinging the db
+func connectDb(config DbConfig) (*gorm.DB, error) {
+	dialector, err := dialectorFor(config.Driver, config.DbString)
+	if err != nil {
+		return nil, err
+	}
+	// gorm.Open modifies the config it is given, so each attempt gets a fresh copy
+	gormConfig := &gorm.Config{}
+	if config.Config != nil {
+		*gormConfig = *config.Config
+	}
+	// so services can check for errors like gorm.ErrDuplicatedKey
+	// instead of each driver's own
+	gormConfig.TranslateError = true
+	db, err := gorm.Open(dialector, gormConfig)
+	if err != nil {
+		// a failed ping leaves the pool open
+		if sqlDb, dbErr := db.DB(); dbErr == nil {
+			sqlDb.Close()
+		}
+		return nil, err
+	}
+
+	sqlDb, err := db.DB()
 	if err != nil {
-		panic("Failed to connect to database!")
+		return nil, err
+	}
+	applyPoolConfig(sqlDb, config.Pool)
+
+	// gorm.Open only pings some drivers (and sqlite opens its file lazily)
+	if err := sqlDb.Ping(); err != nil {
+		sqlDb.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func applyPoolConfig(sqlDb *sql.DB, pool DbPoolConfig) {
+	if pool.MaxOpenConns > 0 {
+		sqlDb.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	// unlike the other settings, 0 here would mean no idle connections at all
+	if pool.MaxIdleConns > 0 {
+		sqlDb.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		sqlDb.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		sqlDb.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
 	}
-	instrumentation.InstrumentDbConnection(db)
-	return db
 }
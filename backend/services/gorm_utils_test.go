@@ -0,0 +1,127 @@
+package services
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func sqliteTestConfig(t *testing.T, path string) DbConfig {
+	dbStr, err := BuildDbString(SQLiteDriver, DbConnectionInfo{Name: path})
+	assert.NoError(t, err)
+	return DbConfig{
+		Driver:   SQLiteDriver,
+		DbString: dbStr,
+		Config: &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+		},
+		ConnectAttempts: 1,
+	}
+}
+
+func TestOpenDb(t *testing.T) {
+	t.Run("Applies pool config", func(t *testing.T) {
+		config := sqliteTestConfig(t, filepath.Join(t.TempDir(), "test.db"))
+		config.Pool = DbPoolConfig{MaxOpenConns: 3, MaxIdleConns: 2}
+
+		db, err := OpenDb(config)
+
+		assert.NoError(t, err)
+		sqlDb, _ := db.DB()
+		defer sqlDb.Close()
+		assert.Equal(t, 3, sqlDb.Stats().MaxOpenConnections)
+	})
+
+	t.Run("Gives up after the configured attempts", func(t *testing.T) {
+		// sqlite can't create a db file in a missing directory
+		config := sqliteTestConfig(t, filepath.Join(t.TempDir(), "missing", "test.db"))
+		config.ConnectAttempts = 3
+		config.InitialBackoff = 5 * time.Millisecond
+
+		start := time.Now()
+		db, err := OpenDb(config)
+
+		assert.Nil(t, db)
+		assert.ErrorContains(t, err, "after 3 attempt(s)")
+		// waited 5ms, then 10ms between attempts
+		assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+	})
+
+	t.Run("Retries until the db is reachable", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "late")
+		config := sqliteTestConfig(t, filepath.Join(dir, "test.db"))
+		config.ConnectAttempts = 20
+		config.InitialBackoff = 5 * time.Millisecond
+		config.MaxBackoff = 10 * time.Millisecond
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			os.Mkdir(dir, 0o755)
+		}()
+
+		db, err := OpenDb(config)
+
+		assert.NoError(t, err)
+		sqlDb, _ := db.DB()
+		defer sqlDb.Close()
+		assert.NoError(t, sqlDb.Ping())
+	})
+
+	t.Run("Translates driver errors", func(t *testing.T) {
+		config := sqliteTestConfig(t, filepath.Join(t.TempDir(), "test.db"))
+
+		db, err := OpenDb(config)
+
+		assert.NoError(t, err)
+		sqlDb, _ := db.DB()
+		defer sqlDb.Close()
+		assert.NoError(t, db.Exec("CREATE TABLE widgets (name TEXT UNIQUE)").Error)
+		assert.NoError(t, db.Exec("INSERT INTO widgets (name) VALUES ('gear')").Error)
+		err = db.Exec("INSERT INTO widgets (name) VALUES ('gear')").Error
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("Unsupported driver", func(t *testing.T) {
+		_, err := OpenDb(DbConfig{Driver: "oracle", ConnectAttempts: 1})
+
+		assert.ErrorContains(t, err, "unsupported database driver")
+	})
+}
+
+func TestBuildDbString(t *testing.T) {
+	t.Run("Postgres escapes credentials and requires ssl by default", func(t *testing.T) {
+		dbStr, err := BuildDbString(PostgresDriver, DbConnectionInfo{
+			User:     "tim",
+			Password: `p@ss word'\"`,
+			Host:     "db.example.com",
+			Name:     "netgo",
+		})
+
+		assert.NoError(t, err)
+		parsed, err := url.Parse(dbStr)
+		assert.NoError(t, err)
+		password, _ := parsed.User.Password()
+		assert.Equal(t, `p@ss word'\"`, password)
+		assert.Equal(t, "db.example.com:5432", parsed.Host)
+		assert.Equal(t, "/netgo", parsed.Path)
+		assert.Equal(t, "require", parsed.Query().Get("sslmode"))
+	})
+
+	t.Run("Postgres uses the configured sslmode", func(t *testing.T) {
+		dbStr, err := BuildDbString(PostgresDriver, DbConnectionInfo{
+			User:    "tim",
+			Host:    "localhost",
+			Port:    "6543",
+			Name:    "netgo",
+			SSLMode: "disable",
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "postgres://tim:@localhost:6543/netgo?sslmode=disable", dbStr)
+	})
+}
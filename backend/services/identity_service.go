@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/identity"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+/* interfaces */
+
+// methods the router handler layer interacts with
+type IIdentityService interface {
+	// the user the identity in `claims` is linked to; 404 error if it isn't linked yet
+	FindUser(ctx context.Context, claims *identity.Claims) (*model.User, error)
+	// new user `username` signing in with the identity in `claims`, and no password
+	Signup(ctx context.Context, username string, claims *identity.Claims) (*model.User, error)
+	// let user `userId` sign in with the identity in `claims` too
+	Link(ctx context.Context, userId uint, claims *identity.Claims) (*model.UserIdentity, error)
+	List(ctx context.Context, userId uint) ([]model.UserIdentity, error)
+	Unlink(ctx context.Context, userId uint, id uint) error
+}
+
+/* implementation */
+
+type IdentityService struct {
+	userRepository     IUserRepository
+	identityRepository IUserIdentityRepository
+	now                func() time.Time
+}
+
+// injectable deps
+type IdentityServiceDeps struct {
+	UserRepository     IUserRepository
+	IdentityRepository IUserIdentityRepository
+}
+
+func NewIdentityService(d IdentityServiceDeps) IIdentityService {
+	return &IdentityService{
+		userRepository:     d.UserRepository,
+		identityRepository: d.IdentityRepository,
+		now:                time.Now,
+	}
+}
+
+func (s *IdentityService) FindUser(ctx context.Context, claims *identity.Claims) (*model.User, error) {
+	linked, err := s.findIdentity(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if linked == nil {
+		return nil, apperrors.NewNotFound("Identity", claims.Subject)
+	}
+	user, err := s.userRepository.FindByID(ctx, linked.UserID)
+	if err != nil {
+		// linked to a deleted user
+		return nil, apperrors.NewNotFound("User", strconv.FormatUint(uint64(linked.UserID), 10))
+	}
+	return user, nil
+}
+
+/**
+ * The user gets the email from `claims` too, as a verified one, if the
+ * provider verified it and nobody else has it; otherwise they can add
+ * one themselves later.
+ */
+func (s *IdentityService) Signup(ctx context.Context, username string, claims *identity.Claims) (*model.User, error) {
+	if err := s.checkUnlinked(ctx, claims); err != nil {
+		return nil, err
+	}
+
+	user := &model.User{Username: username}
+	if email := model.NormalizeEmail(claims.Email); claims.EmailVerified && email != "" {
+		if _, err := s.userRepository.FindByEmail(ctx, email); errors.Is(err, gorm.ErrRecordNotFound) {
+			now := s.now()
+			user.Email = &email
+			user.EmailVerifiedAt = &now
+		}
+	}
+	// one transaction, so a failed link leaves no passwordless user behind
+	if err := s.identityRepository.CreateWithUser(ctx, user, s.newIdentity(0, claims)); err != nil {
+		logger.DebugCtx(ctx, "Identity signup creation error", zap.Error(err))
+		if linked, findErr := s.findIdentity(ctx, claims); findErr == nil && linked != nil {
+			// linked concurrently
+			return nil, apperrors.NewConflict("Identity", claims.Subject)
+		}
+		return nil, apperrors.NewConflict("Username", username)
+	}
+	logger.InfoCtx(ctx, "User signed up with identity provider",
+		zap.Uint("user_id", user.ID), zap.String("issuer", claims.Issuer))
+	return user, nil
+}
+
+func (s *IdentityService) Link(ctx context.Context, userId uint, claims *identity.Claims) (*model.UserIdentity, error) {
+	linked, err := s.findIdentity(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	if linked != nil {
+		if linked.UserID == userId {
+			return linked, nil
+		}
+		return nil, apperrors.NewConflict("Identity", claims.Subject)
+	}
+
+	created, err := s.createIdentity(ctx, userId, claims)
+	if err != nil {
+		return nil, err
+	}
+	logger.InfoCtx(ctx, "User linked identity", zap.Uint("user_id", userId), zap.String("issuer", claims.Issuer))
+	return created, nil
+}
+
+func (s *IdentityService) List(ctx context.Context, userId uint) ([]model.UserIdentity, error) {
+	identities, err := s.identityRepository.ListByUserID(ctx, userId)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to list user identities", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	return identities, nil
+}
+
+// 400 error if it's the only way left to sign in to the account
+func (s *IdentityService) Unlink(ctx context.Context, userId uint, id uint) error {
+	user, err := s.userRepository.FindByID(ctx, userId)
+	if err != nil {
+		return apperrors.NewNotFound("User", strconv.FormatUint(uint64(userId), 10))
+	}
+	if user.Password == "" {
+		identities, err := s.List(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return apperrors.NewBadRequest("Set a password before unlinking your only identity")
+		}
+	}
+
+	if err := s.identityRepository.Delete(ctx, userId, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apperrors.NewNotFound("Identity", strconv.FormatUint(uint64(id), 10))
+		}
+		logger.ErrorCtx(ctx, "Unable to delete user identity", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	logger.InfoCtx(ctx, "User unlinked identity", zap.Uint("user_id", userId))
+	return nil
+}
+
+// the identity in `claims`, or nil if it isn't linked to anyone
+func (s *IdentityService) findIdentity(ctx context.Context, claims *identity.Claims) (*model.UserIdentity, error) {
+	linked, err := s.identityRepository.FindByIssuerSubject(ctx, claims.Issuer, claims.Subject)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to look up user identity", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	return linked, nil
+}
+
+// 409 error if the identity in `claims` is linked to a user already
+func (s *IdentityService) checkUnlinked(ctx context.Context, claims *identity.Claims) error {
+	linked, err := s.findIdentity(ctx, claims)
+	if err != nil {
+		return err
+	}
+	if linked != nil {
+		return apperrors.NewConflict("Identity", claims.Subject)
+	}
+	return nil
+}
+
+func (s *IdentityService) newIdentity(userId uint, claims *identity.Claims) *model.UserIdentity {
+	return &model.UserIdentity{
+		UserID:    userId,
+		Issuer:    claims.Issuer,
+		Subject:   claims.Subject,
+		Email:     claims.Email,
+		CreatedAt: s.now(),
+	}
+}
+
+func (s *IdentityService) createIdentity(ctx context.Context, userId uint, claims *identity.Claims) (*model.UserIdentity, error) {
+	created := s.newIdentity(userId, claims)
+	if err := s.identityRepository.Create(ctx, created); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// linked concurrently
+			return nil, apperrors.NewConflict("Identity", claims.Subject)
+		}
+		logger.ErrorCtx(ctx, "Unable to save user identity", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	return created, nil
+}
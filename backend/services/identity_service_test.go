@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/identity"
+	"net-go/server/backend/model"
+)
+
+// identity service on in memory repos, with user "tim" (who has a password)
+func newTestIdentityService(t *testing.T) (*IdentityService, *MemoryUserRepository) {
+	t.Helper()
+	users := NewMemoryUserRepository()
+	s := NewIdentityService(IdentityServiceDeps{
+		UserRepository:     users,
+		IdentityRepository: NewMemoryUserIdentityRepository(&MemoryUserIdentityRepoDeps{UserRepository: users}),
+	}).(*IdentityService)
+	password, err := hashPassword("password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Create(context.TODO(), &model.User{Username: "tim", Password: password}); err != nil {
+		t.Fatal(err)
+	}
+	return s, users
+}
+
+func testClaims(subject string) *identity.Claims {
+	return &identity.Claims{
+		Issuer:        "https://idp.example.com",
+		Subject:       subject,
+		Email:         "Ann@Example.com",
+		EmailVerified: true,
+	}
+}
+
+func TestIdentityServiceSignup(t *testing.T) {
+	t.Run("Creates a passwordless user the identity signs in as", func(t *testing.T) {
+		s, _ := newTestIdentityService(t)
+
+		user, err := s.Signup(context.TODO(), "ann", testClaims("abc"))
+		assert.NoError(t, err)
+		assert.Equal(t, "ann", user.Username)
+		assert.Empty(t, user.Password)
+		assert.Equal(t, "ann@example.com", *user.Email)
+		assert.NotNil(t, user.EmailVerifiedAt)
+
+		found, err := s.FindUser(context.TODO(), testClaims("abc"))
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+	})
+
+	t.Run("Unverified emails aren't kept", func(t *testing.T) {
+		s, _ := newTestIdentityService(t)
+		claims := testClaims("abc")
+		claims.EmailVerified = false
+
+		user, err := s.Signup(context.TODO(), "ann", claims)
+		assert.NoError(t, err)
+		assert.Nil(t, user.Email)
+	})
+
+	t.Run("Taken usernames and linked identities conflict", func(t *testing.T) {
+		s, _ := newTestIdentityService(t)
+
+		_, err := s.Signup(context.TODO(), "tim", testClaims("abc"))
+		assert.Equal(t, http.StatusConflict, apperrors.Status(err))
+		// the failed signup linked nothing
+		_, err = s.FindUser(context.TODO(), testClaims("abc"))
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+
+		_, err = s.Signup(context.TODO(), "ann", testClaims("abc"))
+		assert.NoError(t, err)
+		_, err = s.Signup(context.TODO(), "ann2", testClaims("abc"))
+		assert.Equal(t, http.StatusConflict, apperrors.Status(err))
+	})
+
+	t.Run("Unlinked identities aren't anyone", func(t *testing.T) {
+		s, _ := newTestIdentityService(t)
+
+		_, err := s.FindUser(context.TODO(), testClaims("abc"))
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+}
+
+func TestIdentityServiceLink(t *testing.T) {
+	t.Run("Linked identities sign in as the user", func(t *testing.T) {
+		s, users := newTestIdentityService(t)
+		tim, _ := users.FindByUsername(context.TODO(), "tim")
+
+		_, err := s.Link(context.TODO(), tim.ID, testClaims("abc"))
+		assert.NoError(t, err)
+		found, err := s.FindUser(context.TODO(), testClaims("abc"))
+		assert.NoError(t, err)
+		assert.Equal(t, tim.ID, found.ID)
+
+		// again is a no-op
+		_, err = s.Link(context.TODO(), tim.ID, testClaims("abc"))
+		assert.NoError(t, err)
+		identities, _ := s.List(context.TODO(), tim.ID)
+		assert.Len(t, identities, 1)
+	})
+
+	t.Run("Identities of another user conflict", func(t *testing.T) {
+		s, users := newTestIdentityService(t)
+		tim, _ := users.FindByUsername(context.TODO(), "tim")
+		_, err := s.Signup(context.TODO(), "ann", testClaims("abc"))
+		assert.NoError(t, err)
+
+		_, err = s.Link(context.TODO(), tim.ID, testClaims("abc"))
+		assert.Equal(t, http.StatusConflict, apperrors.Status(err))
+	})
+}
+
+func TestIdentityServiceUnlink(t *testing.T) {
+	t.Run("Users with a password can unlink every identity", func(t *testing.T) {
+		s, users := newTestIdentityService(t)
+		tim, _ := users.FindByUsername(context.TODO(), "tim")
+		linked, _ := s.Link(context.TODO(), tim.ID, testClaims("abc"))
+
+		assert.NoError(t, s.Unlink(context.TODO(), tim.ID, linked.ID))
+		_, err := s.FindUser(context.TODO(), testClaims("abc"))
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+
+	t.Run("Passwordless users keep their last identity", func(t *testing.T) {
+		s, _ := newTestIdentityService(t)
+		ann, _ := s.Signup(context.TODO(), "ann", testClaims("abc"))
+		second, _ := s.Link(context.TODO(), ann.ID, testClaims("def"))
+
+		assert.NoError(t, s.Unlink(context.TODO(), ann.ID, second.ID))
+		identities, _ := s.List(context.TODO(), ann.ID)
+		err := s.Unlink(context.TODO(), ann.ID, identities[0].ID)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Other users' identities aren't found", func(t *testing.T) {
+		s, users := newTestIdentityService(t)
+		tim, _ := users.FindByUsername(context.TODO(), "tim")
+		ann, _ := s.Signup(context.TODO(), "ann", testClaims("abc"))
+		identities, _ := s.List(context.TODO(), ann.ID)
+
+		err := s.Unlink(context.TODO(), tim.ID, identities[0].ID)
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+	})
+}
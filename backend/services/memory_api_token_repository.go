@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * IAPITokenRepository implementation that keeps all tokens in memory.
+ * Mirrors the behavior of APITokenRepository (unique token hashes, gorm
+ * errors) so it can stand in for it in tests and demos.
+ */
+type MemoryAPITokenRepository struct {
+	mu     sync.RWMutex
+	tokens map[uint]model.APIToken
+	nextID uint
+}
+
+func NewMemoryAPITokenRepository() *MemoryAPITokenRepository {
+	return &MemoryAPITokenRepository{
+		tokens: make(map[uint]model.APIToken),
+		nextID: 1,
+	}
+}
+
+// copy of `token` that shares no memory with the stored one
+func cloneAPIToken(token model.APIToken) *model.APIToken {
+	if token.LastUsedAt != nil {
+		lastUsed := *token.LastUsedAt
+		token.LastUsedAt = &lastUsed
+	}
+	return &token
+}
+
+func (r *MemoryAPITokenRepository) Create(ctx context.Context, token *model.APIToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	token.ID = r.nextID
+	r.nextID++
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	r.tokens[token.ID] = *cloneAPIToken(*token)
+	return nil
+}
+
+func (r *MemoryAPITokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return cloneAPIToken(token), nil
+		}
+	}
+	return &model.APIToken{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryAPITokenRepository) ListByUserID(ctx context.Context, userId uint) ([]model.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tokens := []model.APIToken{}
+	for _, token := range r.tokens {
+		if token.UserID == userId {
+			tokens = append(tokens, *cloneAPIToken(token))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].ID < tokens[j].ID
+	})
+	return tokens, nil
+}
+
+func (r *MemoryAPITokenRepository) UpdateLastUsed(ctx context.Context, id uint, lastUsed time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[id]
+	if !ok {
+		return nil
+	}
+	token.LastUsedAt = &lastUsed
+	r.tokens[id] = token
+	return nil
+}
+
+func (r *MemoryAPITokenRepository) Delete(ctx context.Context, userId uint, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[id]
+	if !ok || token.UserID != userId {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.tokens, id)
+	return nil
+}
+
+func (r *MemoryAPITokenRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, token := range r.tokens {
+		if token.UserID == userId {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
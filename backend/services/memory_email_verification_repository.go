@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * IEmailVerificationRepository implementation that keeps all tokens in
+ * memory. Mirrors the behavior of EmailVerificationRepository (unique token
+ * hashes, gorm errors) so it can stand in for it in tests and demos.
+ */
+type MemoryEmailVerificationRepository struct {
+	mu     sync.RWMutex
+	tokens map[uint]model.EmailVerificationToken
+	nextID uint
+}
+
+func NewMemoryEmailVerificationRepository() *MemoryEmailVerificationRepository {
+	return &MemoryEmailVerificationRepository{
+		tokens: make(map[uint]model.EmailVerificationToken),
+		nextID: 1,
+	}
+}
+
+func (r *MemoryEmailVerificationRepository) Create(ctx context.Context, token *model.EmailVerificationToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	token.ID = r.nextID
+	r.nextID++
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *MemoryEmailVerificationRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.EmailVerificationToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return &model.EmailVerificationToken{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryEmailVerificationRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	token.UsedAt = &usedAt
+	r.tokens[id] = token
+	return nil
+}
+
+func (r *MemoryEmailVerificationRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, token := range r.tokens {
+		if token.UserID == userId {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
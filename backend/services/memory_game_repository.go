@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"net-go/server/backend/model/types"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * IGameRepository implementation that keeps all games in memory.
+ * Mirrors the behavior of GameRepository (soft delete, versioned
+ * updates, preloading of the player Users) so it can stand in for
+ * it in tests and demos.
+ */
+type MemoryGameRepository struct {
+	mu     sync.RWMutex
+	games  map[uint]model.Game
+	nextID uint
+	users  IUserRepository
+}
+
+type MemoryGameRepoDeps struct {
+	// source of the BlackPlayer/WhitePlayer associations
+	UserRepository IUserRepository
+}
+
+func NewMemoryGameRepository(deps *MemoryGameRepoDeps) *MemoryGameRepository {
+	return &MemoryGameRepository{
+		games:  make(map[uint]model.Game),
+		nextID: 1,
+		users:  deps.UserRepository,
+	}
+}
+
+// copy of `game` that shares no memory with the stored one
+func cloneGame(game model.Game) model.Game {
+	game.History = append(model.MoveSlice{}, game.History...)
+	playArea := make(types.PlayArea, len(game.Board.Map))
+	for i, row := range game.Board.Map {
+		playArea[i] = append([]types.Piece{}, row...)
+	}
+	game.Board.Map = playArea
+	if game.Score.ForfeitColor != nil {
+		color := *game.Score.ForfeitColor
+		game.Score.ForfeitColor = &color
+	}
+	return game
+}
+
+// fill the player associations, like gorm Preload does
+func (g *MemoryGameRepository) preload(ctx context.Context, game *model.Game) {
+	game.BlackPlayer = model.User{}
+	game.WhitePlayer = model.User{}
+	if user, err := g.users.FindByID(ctx, game.BlackPlayerId); err == nil {
+		game.BlackPlayer = *user
+	}
+	if user, err := g.users.FindByID(ctx, game.WhitePlayerId); err == nil {
+		game.WhitePlayer = *user
+	}
+}
+
+func (g *MemoryGameRepository) FindByID(ctx context.Context, id uint) (*model.Game, error) {
+	g.mu.RLock()
+	stored, ok := g.games[id]
+	g.mu.RUnlock()
+	if !ok || stored.DeletedAt.Valid {
+		return &model.Game{}, gorm.ErrRecordNotFound
+	}
+	game := cloneGame(stored)
+	g.preload(ctx, &game)
+	return &game, nil
+}
+
+// all non-deleted games user `userId` is a member of, in id order
+func (g *MemoryGameRepository) userGames(userId uint) []model.Game {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	games := make([]model.Game, 0)
+	for _, game := range g.games {
+		if game.DeletedAt.Valid {
+			continue
+		}
+		if game.BlackPlayerId == userId || game.WhitePlayerId == userId {
+			games = append(games, cloneGame(game))
+		}
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return games[i].ID < games[j].ID
+	})
+	return games
+}
+
+func (g *MemoryGameRepository) ListByUserID(ctx context.Context, userId uint) ([]model.Game, error) {
+	games := g.userGames(userId)
+	for i := range games {
+		g.preload(ctx, &games[i])
+	}
+	return games, nil
+}
+
+// whether `game` passes all the filters of `opts`
+func matchesListOptions(game model.Game, opts model.GameListOptions) bool {
+	switch opts.Status {
+	case model.ActiveGames:
+		if game.IsOver {
+			return false
+		}
+	case model.FinishedGames:
+		if !game.IsOver {
+			return false
+		}
+	}
+
+	switch opts.Turn {
+	case model.MyTurn:
+		if game.ActivePlayerId != opts.UserId {
+			return false
+		}
+	case model.OpponentTurn:
+		if game.ActivePlayerId == opts.UserId || game.ActivePlayerId == 0 {
+			return false
+		}
+	}
+
+	if opts.OpponentId != 0 && game.BlackPlayerId != opts.OpponentId && game.WhitePlayerId != opts.OpponentId {
+		return false
+	}
+	if opts.BoardSize != types.Undefined && game.Board.Size != opts.BoardSize {
+		return false
+	}
+	if !opts.CreatedAfter.IsZero() && game.CreatedAt.Before(opts.CreatedAfter) {
+		return false
+	}
+	if !opts.CreatedBefore.IsZero() && !game.CreatedAt.Before(opts.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func (g *MemoryGameRepository) ListSummaries(ctx context.Context, opts model.GameListOptions) ([]model.GameSummary, error) {
+	column, desc := opts.Sort.Column()
+	sortValue := func(game model.Game) time.Time {
+		if column == "updated_at" {
+			return game.UpdatedAt
+		}
+		return game.CreatedAt
+	}
+	// whether `a` comes before `b` in the requested order
+	precedes := func(aTime time.Time, aID uint, bTime time.Time, bID uint) bool {
+		if !aTime.Equal(bTime) {
+			return aTime.Before(bTime) != desc
+		}
+		return (aID < bID) != desc
+	}
+
+	games := make([]model.Game, 0)
+	for _, game := range g.userGames(opts.UserId) {
+		if !matchesListOptions(game, opts) {
+			continue
+		}
+		if opts.Cursor != nil && !precedes(opts.Cursor.SortValue, opts.Cursor.ID, sortValue(game), game.ID) {
+			continue
+		}
+		games = append(games, game)
+	}
+	sort.Slice(games, func(i, j int) bool {
+		return precedes(sortValue(games[i]), games[i].ID, sortValue(games[j]), games[j].ID)
+	})
+	if opts.Limit > 0 && len(games) > opts.Limit {
+		games = games[:opts.Limit]
+	}
+
+	summaries := make([]model.GameSummary, len(games))
+	for i, game := range games {
+		g.preload(ctx, &game)
+		summaries[i] = model.GameSummary{
+			Model:          game.Model,
+			BoardSize:      game.Board.Size,
+			IsOver:         game.IsOver,
+			Score:          game.Score,
+			MoveCount:      game.MoveCount,
+			ActivePlayerId: game.ActivePlayerId,
+			BlackPlayerId:  game.BlackPlayerId,
+			WhitePlayerId:  game.WhitePlayerId,
+			BlackPlayer:    game.BlackPlayer,
+			WhitePlayer:    game.WhitePlayer,
+		}
+	}
+	return summaries, nil
+}
+
+func (g *MemoryGameRepository) Create(ctx context.Context, game *model.Game) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if game.ID != 0 {
+		if _, exists := g.games[game.ID]; exists {
+			return gorm.ErrDuplicatedKey
+		}
+	} else {
+		game.ID = g.nextID
+	}
+	if game.ID >= g.nextID {
+		g.nextID = game.ID + 1
+	}
+
+	// run the same hooks gorm would
+	game.BeforeCreate(nil)
+	game.BeforeSave(nil)
+	now := time.Now()
+	game.CreatedAt = now
+	game.UpdatedAt = now
+	g.games[game.ID] = cloneGame(*game)
+	return nil
+}
+
+func (g *MemoryGameRepository) Update(ctx context.Context, game *model.Game) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	stored, ok := g.games[game.ID]
+	if !ok || stored.DeletedAt.Valid || stored.Version != game.Version {
+		return ErrVersionConflict
+	}
+
+	game.Version++
+	game.BeforeSave(nil)
+	game.CreatedAt = stored.CreatedAt
+	game.UpdatedAt = time.Now()
+	game.DeletedAt = stored.DeletedAt
+	g.games[game.ID] = cloneGame(*game)
+	return nil
+}
+
+func (g *MemoryGameRepository) Delete(ctx context.Context, gameID uint) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if game, ok := g.games[gameID]; ok && !game.DeletedAt.Valid {
+		game.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		g.games[gameID] = game
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+	"net-go/server/backend/model/types"
+)
+
+func newMemoryGameFixture(t *testing.T) (*MemoryGameRepository, *model.User, *model.User) {
+	users := NewMemoryUserRepository()
+	black := &model.User{Username: "tim"}
+	white := &model.User{Username: "tom"}
+	assert.NoError(t, users.Create(context.TODO(), black))
+	assert.NoError(t, users.Create(context.TODO(), white))
+	games := NewMemoryGameRepository(&MemoryGameRepoDeps{
+		UserRepository: users,
+	})
+	return games, black, white
+}
+
+func TestMemoryGameRepository(t *testing.T) {
+	t.Run("Find preloads players", func(t *testing.T) {
+		repo, black, white := newMemoryGameFixture(t)
+		game := &model.Game{BlackPlayerId: black.ID, WhitePlayerId: white.ID}
+		assert.NoError(t, repo.Create(context.TODO(), game))
+
+		found, err := repo.FindByID(context.TODO(), game.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "tim", found.BlackPlayer.Username)
+		assert.Equal(t, "tom", found.WhitePlayer.Username)
+		assert.Equal(t, uint(1), found.Version)
+		assert.Equal(t, black.ID, found.ActivePlayerId)
+	})
+
+	t.Run("Update requires current version", func(t *testing.T) {
+		repo, black, white := newMemoryGameFixture(t)
+		game := &model.Game{BlackPlayerId: black.ID, WhitePlayerId: white.ID}
+		assert.NoError(t, repo.Create(context.TODO(), game))
+		first, _ := repo.FindByID(context.TODO(), game.ID)
+		second, _ := repo.FindByID(context.TODO(), game.ID)
+
+		first.History = model.MoveSlice{{MoveType: types.Pass, Piece: types.BlackStone}}
+		assert.NoError(t, repo.Update(context.TODO(), first))
+		second.IsOver = true
+		err := repo.Update(context.TODO(), second)
+
+		assert.ErrorIs(t, err, ErrVersionConflict)
+		stored, _ := repo.FindByID(context.TODO(), game.ID)
+		assert.Equal(t, uint(2), stored.Version)
+		assert.Equal(t, white.ID, stored.ActivePlayerId)
+		assert.False(t, stored.IsOver)
+	})
+
+	t.Run("Deleted games are hidden", func(t *testing.T) {
+		repo, black, white := newMemoryGameFixture(t)
+		game := &model.Game{BlackPlayerId: black.ID, WhitePlayerId: white.ID}
+		assert.NoError(t, repo.Create(context.TODO(), game))
+
+		assert.NoError(t, repo.Delete(context.TODO(), game.ID))
+		_, err := repo.FindByID(context.TODO(), game.ID)
+		games, listErr := repo.ListByUserID(context.TODO(), black.ID)
+
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		assert.NoError(t, listErr)
+		assert.Empty(t, games)
+	})
+
+	t.Run("Summaries are filtered and paginated", func(t *testing.T) {
+		repo, black, white := newMemoryGameFixture(t)
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, repo.Create(context.TODO(), &model.Game{
+				Board:         types.Board{Size: types.Small},
+				BlackPlayerId: black.ID,
+				WhitePlayerId: white.ID,
+			}))
+		}
+		assert.NoError(t, repo.Create(context.TODO(), &model.Game{
+			Board:         types.Board{Size: types.Full},
+			IsOver:        true,
+			BlackPlayerId: black.ID,
+			WhitePlayerId: white.ID,
+		}))
+
+		opts := model.GameListOptions{
+			UserId: black.ID,
+			Status: model.ActiveGames,
+			Turn:   model.MyTurn,
+			Sort:   model.CreatedAsc,
+			Limit:  2,
+		}
+		page, err := repo.ListSummaries(context.TODO(), opts)
+		assert.NoError(t, err)
+		assert.Len(t, page, 2)
+		assert.Equal(t, uint(1), page[0].ID)
+		assert.Equal(t, "tom", page[0].WhitePlayer.Username)
+
+		cursor := model.NewGameCursor(page[1], opts.Sort)
+		opts.Cursor = &cursor
+		page, err = repo.ListSummaries(context.TODO(), opts)
+		assert.NoError(t, err)
+		assert.Len(t, page, 1)
+		assert.Equal(t, uint(3), page[0].ID)
+
+		finished, err := repo.ListSummaries(context.TODO(), model.GameListOptions{
+			UserId:    white.ID,
+			Status:    model.FinishedGames,
+			BoardSize: types.Full,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, finished, 1)
+	})
+}
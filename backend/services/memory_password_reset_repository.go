@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * IPasswordResetRepository implementation that keeps all tokens in
+ * memory. Mirrors the behavior of PasswordResetRepository (unique token
+ * hashes, gorm errors) so it can stand in for it in tests and demos.
+ */
+type MemoryPasswordResetRepository struct {
+	mu     sync.RWMutex
+	tokens map[uint]model.PasswordResetToken
+	nextID uint
+}
+
+func NewMemoryPasswordResetRepository() *MemoryPasswordResetRepository {
+	return &MemoryPasswordResetRepository{
+		tokens: make(map[uint]model.PasswordResetToken),
+		nextID: 1,
+	}
+}
+
+func (r *MemoryPasswordResetRepository) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	token.ID = r.nextID
+	r.nextID++
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	r.tokens[token.ID] = *token
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return &token, nil
+		}
+	}
+	return &model.PasswordResetToken{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryPasswordResetRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	token.UsedAt = &usedAt
+	r.tokens[id] = token
+	return nil
+}
+
+func (r *MemoryPasswordResetRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, token := range r.tokens {
+		if token.UserID == userId {
+			delete(r.tokens, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * IRecoveryCodeRepository implementation that keeps all codes in
+ * memory. Mirrors the behavior of RecoveryCodeRepository (gorm errors)
+ * so it can stand in for it in tests and demos.
+ */
+type MemoryRecoveryCodeRepository struct {
+	mu     sync.RWMutex
+	codes  map[uint]model.RecoveryCode
+	nextID uint
+}
+
+func NewMemoryRecoveryCodeRepository() *MemoryRecoveryCodeRepository {
+	return &MemoryRecoveryCodeRepository{
+		codes:  make(map[uint]model.RecoveryCode),
+		nextID: 1,
+	}
+}
+
+func (r *MemoryRecoveryCodeRepository) Replace(ctx context.Context, userId uint, codes []model.RecoveryCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleteByUserID(userId)
+	for i := range codes {
+		codes[i].ID = r.nextID
+		r.nextID++
+		codes[i].UserID = userId
+		if codes[i].CreatedAt.IsZero() {
+			codes[i].CreatedAt = time.Now()
+		}
+		r.codes[codes[i].ID] = codes[i]
+	}
+	return nil
+}
+
+func (r *MemoryRecoveryCodeRepository) MarkUsed(ctx context.Context, userId uint, codeHash string, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, code := range r.codes {
+		if code.UserID == userId && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = &usedAt
+			r.codes[id] = code
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *MemoryRecoveryCodeRepository) CountUnused(ctx context.Context, userId uint) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var count int64
+	for _, code := range r.codes {
+		if code.UserID == userId && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MemoryRecoveryCodeRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleteByUserID(userId)
+	return nil
+}
+
+// callers hold the write lock
+func (r *MemoryRecoveryCodeRepository) deleteByUserID(userId uint) {
+	for id, code := range r.codes {
+		if code.UserID == userId {
+			delete(r.codes, id)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * ISessionRepository implementation that keeps all sessions in memory.
+ * Mirrors the behavior of SessionRepository (unique token hashes, gorm
+ * errors) so it can stand in for it in tests and demos.
+ */
+type MemorySessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[uint]model.Session
+	nextID   uint
+}
+
+func NewMemorySessionRepository() *MemorySessionRepository {
+	return &MemorySessionRepository{
+		sessions: make(map[uint]model.Session),
+		nextID:   1,
+	}
+}
+
+func (r *MemorySessionRepository) Create(ctx context.Context, session *model.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.sessions {
+		if existing.TokenHash == session.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	session.ID = r.nextID
+	r.nextID++
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *MemorySessionRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, session := range r.sessions {
+		if session.TokenHash == tokenHash {
+			return &session, nil
+		}
+	}
+	return &model.Session{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemorySessionRepository) ListByUserID(ctx context.Context, userId uint) ([]model.Session, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sessions := []model.Session{}
+	for _, session := range r.sessions {
+		if session.UserID == userId {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastSeenAt.Equal(sessions[j].LastSeenAt) {
+			return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+		}
+		return sessions[i].ID > sessions[j].ID
+	})
+	return sessions, nil
+}
+
+func (r *MemorySessionRepository) UpdateLastSeen(ctx context.Context, id uint, lastSeen time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// like an UPDATE matching no rows, a missing session isn't an error
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = lastSeen
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) Delete(ctx context.Context, userId uint, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok || session.UserID != userId {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.sessions, id)
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, session := range r.sessions {
+		if session.UserID == userId {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (r *MemorySessionRepository) DeleteIdleSince(ctx context.Context, userId uint, lastSeen time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, session := range r.sessions {
+		if session.UserID == userId && session.LastSeenAt.Before(lastSeen) {
+			delete(r.sessions, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestMemorySessionRepository(t *testing.T) {
+	t.Run("Token hashes are unique", func(t *testing.T) {
+		repo := NewMemorySessionRepository()
+
+		assert.NoError(t, repo.Create(context.TODO(), &model.Session{UserID: 1, TokenHash: "a"}))
+		err := repo.Create(context.TODO(), &model.Session{UserID: 2, TokenHash: "a"})
+
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("Lists a user's sessions, most recently used first", func(t *testing.T) {
+		repo := NewMemorySessionRepository()
+		now := time.Now()
+		older := &model.Session{UserID: 1, TokenHash: "a", LastSeenAt: now.Add(-time.Hour)}
+		newer := &model.Session{UserID: 1, TokenHash: "b", LastSeenAt: now}
+		other := &model.Session{UserID: 2, TokenHash: "c", LastSeenAt: now}
+		for _, s := range []*model.Session{older, newer, other} {
+			assert.NoError(t, repo.Create(context.TODO(), s))
+		}
+
+		sessions, err := repo.ListByUserID(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+		assert.Equal(t, newer.ID, sessions[0].ID)
+		assert.Equal(t, older.ID, sessions[1].ID)
+	})
+
+	t.Run("Deletes only the user's own sessions", func(t *testing.T) {
+		repo := NewMemorySessionRepository()
+		theirs := &model.Session{UserID: 2, TokenHash: "b"}
+		assert.NoError(t, repo.Create(context.TODO(), theirs))
+
+		assert.ErrorIs(t, repo.Delete(context.TODO(), 1, theirs.ID), gorm.ErrRecordNotFound)
+		assert.NoError(t, repo.Delete(context.TODO(), 2, theirs.ID))
+		_, err := repo.FindByTokenHash(context.TODO(), "b")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
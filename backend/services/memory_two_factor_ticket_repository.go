@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * ITwoFactorTicketRepository implementation that keeps all tickets in
+ * memory. Mirrors the behavior of TwoFactorTicketRepository (unique
+ * token hashes, gorm errors) so it can stand in for it in tests and demos.
+ */
+type MemoryTwoFactorTicketRepository struct {
+	mu      sync.RWMutex
+	tickets map[uint]model.TwoFactorTicket
+	nextID  uint
+}
+
+func NewMemoryTwoFactorTicketRepository() *MemoryTwoFactorTicketRepository {
+	return &MemoryTwoFactorTicketRepository{
+		tickets: make(map[uint]model.TwoFactorTicket),
+		nextID:  1,
+	}
+}
+
+func (r *MemoryTwoFactorTicketRepository) Create(ctx context.Context, ticket *model.TwoFactorTicket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.tickets {
+		if existing.TokenHash == ticket.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	ticket.ID = r.nextID
+	r.nextID++
+	if ticket.CreatedAt.IsZero() {
+		ticket.CreatedAt = time.Now()
+	}
+	r.tickets[ticket.ID] = *ticket
+	return nil
+}
+
+func (r *MemoryTwoFactorTicketRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.TwoFactorTicket, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, ticket := range r.tickets {
+		if ticket.TokenHash == tokenHash {
+			return &ticket, nil
+		}
+	}
+	return &model.TwoFactorTicket{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryTwoFactorTicketRepository) RecordAttempt(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ticket, ok := r.tickets[id]; ok {
+		ticket.Attempts++
+		r.tickets[id] = ticket
+	}
+	return nil
+}
+
+func (r *MemoryTwoFactorTicketRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tickets[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.tickets, id)
+	return nil
+}
+
+func (r *MemoryTwoFactorTicketRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, ticket := range r.tickets {
+		if !now.Before(ticket.ExpiresAt) {
+			delete(r.tickets, id)
+		}
+	}
+	return nil
+}
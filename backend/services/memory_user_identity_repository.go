@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * IUserIdentityRepository implementation that keeps all identities in
+ * memory. Mirrors the behavior of UserIdentityRepository (unique
+ * issuer and subject, gorm errors) so it can stand in for it in tests and demos.
+ */
+type MemoryUserIdentityRepository struct {
+	mu         sync.RWMutex
+	identities map[uint]model.UserIdentity
+	nextID     uint
+	users      IUserRepository
+}
+
+type MemoryUserIdentityRepoDeps struct {
+	// where CreateWithUser creates users
+	UserRepository IUserRepository
+}
+
+func NewMemoryUserIdentityRepository(deps *MemoryUserIdentityRepoDeps) *MemoryUserIdentityRepository {
+	return &MemoryUserIdentityRepository{
+		identities: make(map[uint]model.UserIdentity),
+		nextID:     1,
+		users:      deps.UserRepository,
+	}
+}
+
+func (r *MemoryUserIdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insert(identity)
+}
+
+func (r *MemoryUserIdentityRepository) CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// check before creating the user, so a duplicate leaves no user behind
+	if r.isLinked(identity) {
+		return gorm.ErrDuplicatedKey
+	}
+	if err := r.users.Create(ctx, user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	return r.insert(identity)
+}
+
+// whether the issuer and subject of `identity` are linked already; callers hold the lock
+func (r *MemoryUserIdentityRepository) isLinked(identity *model.UserIdentity) bool {
+	for _, existing := range r.identities {
+		if existing.Issuer == identity.Issuer && existing.Subject == identity.Subject {
+			return true
+		}
+	}
+	return false
+}
+
+// callers hold the lock
+func (r *MemoryUserIdentityRepository) insert(identity *model.UserIdentity) error {
+	if r.isLinked(identity) {
+		return gorm.ErrDuplicatedKey
+	}
+
+	identity.ID = r.nextID
+	r.nextID++
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	r.identities[identity.ID] = *identity
+	return nil
+}
+
+func (r *MemoryUserIdentityRepository) FindByIssuerSubject(ctx context.Context, issuer string, subject string) (*model.UserIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, identity := range r.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return &model.UserIdentity{}, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryUserIdentityRepository) ListByUserID(ctx context.Context, userId uint) ([]model.UserIdentity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	identities := []model.UserIdentity{}
+	for _, identity := range r.identities {
+		if identity.UserID == userId {
+			identities = append(identities, identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].ID < identities[j].ID
+	})
+	return identities, nil
+}
+
+func (r *MemoryUserIdentityRepository) Delete(ctx context.Context, userId uint, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	identity, ok := r.identities[id]
+	if !ok || identity.UserID != userId {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.identities, id)
+	return nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * IUserRepository implementation that keeps all users in memory.
+ * Mirrors the behavior of UserRepository (unique usernames, soft
+ * delete, gorm errors) so it can stand in for it in tests and demos.
+ */
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[uint]model.User
+	nextID uint
+}
+
+// copy of `user` that shares no memory with the stored one
+func cloneUser(user model.User) *model.User {
+	if user.Email != nil {
+		email := *user.Email
+		user.Email = &email
+	}
+	if user.EmailVerifiedAt != nil {
+		verifiedAt := *user.EmailVerifiedAt
+		user.EmailVerifiedAt = &verifiedAt
+	}
+	return &user
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{
+		users:  make(map[uint]model.User),
+		nextID: 1,
+	}
+}
+
+func (u *MemoryUserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	user, ok := u.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return &model.User{}, gorm.ErrRecordNotFound
+	}
+	return cloneUser(user), nil
+}
+
+func (u *MemoryUserRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	for _, user := range u.users {
+		if user.Username == username && !user.DeletedAt.Valid {
+			return cloneUser(user), nil
+		}
+	}
+	return &model.User{}, gorm.ErrRecordNotFound
+}
+
+func (u *MemoryUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	for _, user := range u.users {
+		if user.Email != nil && *user.Email == email && !user.DeletedAt.Valid {
+			return cloneUser(user), nil
+		}
+	}
+	return &model.User{}, gorm.ErrRecordNotFound
+}
+
+func (u *MemoryUserRepository) Create(ctx context.Context, user *model.User) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.create(user)
+}
+
+// create a new user; caller must hold the write lock
+func (u *MemoryUserRepository) create(user *model.User) error {
+	if user.ID != 0 {
+		if _, exists := u.users[user.ID]; exists {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	if u.usernameTaken(user.Username, user.ID) || u.emailTaken(user.Email, user.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+
+	if user.ID == 0 {
+		user.ID = u.nextID
+	}
+	if user.ID >= u.nextID {
+		u.nextID = user.ID + 1
+	}
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	u.users[user.ID] = *cloneUser(*user)
+	return nil
+}
+
+// same semantics as gorm Save: create when new, otherwise overwrite
+func (u *MemoryUserRepository) Update(ctx context.Context, user *model.User) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, exists := u.users[user.ID]; !exists {
+		return u.create(user)
+	}
+	if u.usernameTaken(user.Username, user.ID) || u.emailTaken(user.Email, user.ID) {
+		return gorm.ErrDuplicatedKey
+	}
+	user.UpdatedAt = time.Now()
+	u.users[user.ID] = *cloneUser(*user)
+	return nil
+}
+
+func (u *MemoryUserRepository) AdvanceTOTPStep(ctx context.Context, userId uint, step int64) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	user, ok := u.users[userId]
+	if !ok || user.DeletedAt.Valid || user.TOTPLastStep >= step {
+		return gorm.ErrRecordNotFound
+	}
+	user.TOTPLastStep = step
+	user.UpdatedAt = time.Now()
+	u.users[userId] = user
+	return nil
+}
+
+/**
+ * Soft deletes the user, like gorm does for models with a DeletedAt.
+ * Not part of IUserRepository; used to set up test scenarios.
+ */
+func (u *MemoryUserRepository) Delete(ctx context.Context, id uint) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if user, ok := u.users[id]; ok && !user.DeletedAt.Valid {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		u.users[id] = user
+	}
+	return nil
+}
+
+// whether a user other than `exceptID` has `username`; soft deleted
+// users still count, since the unique index covers them too
+func (u *MemoryUserRepository) usernameTaken(username string, exceptID uint) bool {
+	for id, user := range u.users {
+		if id != exceptID && user.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+// like usernameTaken, for the (optional) email
+func (u *MemoryUserRepository) emailTaken(email *string, exceptID uint) bool {
+	if email == nil {
+		return false
+	}
+	for id, user := range u.users {
+		if id != exceptID && user.Email != nil && *user.Email == *email {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	t.Run("Create assigns ids and finds by username", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+		tim := &model.User{Username: "tim"}
+		tom := &model.User{Username: "tom"}
+
+		assert.NoError(t, repo.Create(context.TODO(), tim))
+		assert.NoError(t, repo.Create(context.TODO(), tom))
+		found, err := repo.FindByUsername(context.TODO(), "tom")
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), tim.ID)
+		assert.Equal(t, tom.ID, found.ID)
+		assert.False(t, found.CreatedAt.IsZero())
+	})
+
+	t.Run("Usernames are unique", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "tim"}))
+		err := repo.Create(context.TODO(), &model.User{Username: "tim"})
+
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("Emails are unique", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+		email := "tim@example.com"
+		tom := &model.User{Username: "tom"}
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "tim", Email: &email}))
+		assert.NoError(t, repo.Create(context.TODO(), tom))
+
+		tom.Email = &email
+		err := repo.Update(context.TODO(), tom)
+
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+		found, err := repo.FindByEmail(context.TODO(), email)
+		assert.NoError(t, err)
+		assert.Equal(t, "tim", found.Username)
+	})
+
+	t.Run("Soft deleted users are not found but keep their username", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+		user := &model.User{Username: "tim"}
+		assert.NoError(t, repo.Create(context.TODO(), user))
+
+		assert.NoError(t, repo.Delete(context.TODO(), user.ID))
+		_, errById := repo.FindByID(context.TODO(), user.ID)
+		_, errByName := repo.FindByUsername(context.TODO(), "tim")
+
+		assert.ErrorIs(t, errById, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, errByName, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.Create(context.TODO(), &model.User{Username: "tim"}), gorm.ErrDuplicatedKey)
+	})
+
+	t.Run("Returned users are copies", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+		user := &model.User{Username: "tim", Password: "abc"}
+		assert.NoError(t, repo.Create(context.TODO(), user))
+
+		user.Password = "changed without saving"
+		found, err := repo.FindByID(context.TODO(), user.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", found.Password)
+	})
+
+	t.Run("Update saves changes", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+		user := &model.User{Username: "tim"}
+		assert.NoError(t, repo.Create(context.TODO(), user))
+
+		user.Password = "abc"
+		assert.NoError(t, repo.Update(context.TODO(), user))
+		found, err := repo.FindByID(context.TODO(), user.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", found.Password)
+	})
+}
@@ -0,0 +1,83 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/model"
+)
+
+type MockAPITokenService struct {
+	mock.Mock
+}
+
+func (m *MockAPITokenService) Create(ctx context.Context, userId uint, name string, scopes []string) (string, *model.APIToken, error) {
+	ret := m.Called(ctx, userId, name, scopes)
+
+	r0 := ret.String(0)
+
+	var r1 *model.APIToken
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(*model.APIToken)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+func (m *MockAPITokenService) List(ctx context.Context, userId uint) ([]model.APIToken, error) {
+	ret := m.Called(ctx, userId)
+
+	var r0 []model.APIToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]model.APIToken)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockAPITokenService) Revoke(ctx context.Context, userId uint, id uint) error {
+	ret := m.Called(ctx, userId, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockAPITokenService) RevokeAll(ctx context.Context, userId uint) error {
+	ret := m.Called(ctx, userId)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockAPITokenService) Authenticate(ctx context.Context, token string) (*model.APIToken, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.APIToken
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.APIToken)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -49,6 +49,21 @@ func (m *MockGameRepository) ListByUserID(ctx context.Context, userId uint) ([]m
 	return r0, r1
 }
 
+func (m *MockGameRepository) ListSummaries(ctx context.Context, opts model.GameListOptions) ([]model.GameSummary, error) {
+	ret := m.Called(ctx, opts)
+
+	var r0 []model.GameSummary
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]model.GameSummary)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
 func (m *MockGameRepository) Create(ctx context.Context, game *model.Game) error {
 	ret := m.Called(ctx, game)
 
@@ -81,8 +96,3 @@ func (m *MockGameRepository) Delete(ctx context.Context, gameID uint) error {
 
 	return r0
 }
-
-func (m *MockGameRepository) MigrateAll() error {
-	// this will never need testing
-	return nil
-}
@@ -44,6 +44,52 @@ func (m *MockGameService) ListByUser(ctx context.Context, userId uint) ([]model.
 	return r0, r1
 }
 
+func (m *MockGameService) ListPending(ctx context.Context, userId uint) ([]model.GameSummary, error) {
+	ret := m.Called(ctx, userId)
+
+	var r0 []model.GameSummary
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]model.GameSummary)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
+func (m *MockGameService) GetUserStats(ctx context.Context, userId uint) (*model.UserStats, error) {
+	ret := m.Called(ctx, userId)
+
+	var r0 *model.UserStats
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.UserStats)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockGameService) ListSummaries(ctx context.Context, opts model.GameListOptions) (*model.GameSummaryPage, error) {
+	ret := m.Called(ctx, opts)
+
+	var r0 *model.GameSummaryPage
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.GameSummaryPage)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+	return r0, r1
+}
+
 func (m *MockGameService) Create(ctx context.Context, game *model.Game) error {
 	ret := m.Called(ctx, game)
 
@@ -76,8 +122,3 @@ func (m *MockGameService) Delete(ctx context.Context, gameID uint) error {
 
 	return r0
 }
-
-func (m *MockGameService) MigrateAll() error {
-	// no testing needed
-	return nil
-}
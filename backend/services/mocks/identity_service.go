@@ -0,0 +1,87 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/identity"
+	"net-go/server/backend/model"
+)
+
+type MockIdentityService struct {
+	mock.Mock
+}
+
+func (m *MockIdentityService) FindUser(ctx context.Context, claims *identity.Claims) (*model.User, error) {
+	ret := m.Called(ctx, claims)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockIdentityService) Signup(ctx context.Context, username string, claims *identity.Claims) (*model.User, error) {
+	ret := m.Called(ctx, username, claims)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockIdentityService) Link(ctx context.Context, userId uint, claims *identity.Claims) (*model.UserIdentity, error) {
+	ret := m.Called(ctx, userId, claims)
+
+	var r0 *model.UserIdentity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.UserIdentity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockIdentityService) List(ctx context.Context, userId uint) ([]model.UserIdentity, error) {
+	ret := m.Called(ctx, userId)
+
+	var r0 []model.UserIdentity
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]model.UserIdentity)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockIdentityService) Unlink(ctx context.Context, userId uint, id uint) error {
+	ret := m.Called(ctx, userId, id)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
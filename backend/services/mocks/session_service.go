@@ -0,0 +1,83 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/model"
+)
+
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) Create(ctx context.Context, userId uint, client model.SessionClient) (string, *model.Session, error) {
+	ret := m.Called(ctx, userId, client)
+
+	r0 := ret.String(0)
+
+	var r1 *model.Session
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(*model.Session)
+	}
+
+	var r2 error
+	if ret.Get(2) != nil {
+		r2 = ret.Get(2).(error)
+	}
+
+	return r0, r1, r2
+}
+
+func (m *MockSessionService) Authenticate(ctx context.Context, userId uint, token string) (*model.Session, error) {
+	ret := m.Called(ctx, userId, token)
+
+	var r0 *model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.Session)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockSessionService) List(ctx context.Context, userId uint) ([]model.Session, error) {
+	ret := m.Called(ctx, userId)
+
+	var r0 []model.Session
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]model.Session)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockSessionService) Revoke(ctx context.Context, userId uint, sessionId uint) error {
+	ret := m.Called(ctx, userId, sessionId)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockSessionService) RevokeAll(ctx context.Context, userId uint) error {
+	ret := m.Called(ctx, userId)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
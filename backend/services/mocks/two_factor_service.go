@@ -0,0 +1,115 @@
+package mocks
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"net-go/server/backend/model"
+)
+
+type MockTwoFactorService struct {
+	mock.Mock
+}
+
+func (m *MockTwoFactorService) Status(ctx context.Context, userId uint) (*model.TwoFactorStatus, error) {
+	ret := m.Called(ctx, userId)
+
+	var r0 *model.TwoFactorStatus
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TwoFactorStatus)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTwoFactorService) BeginEnrollment(ctx context.Context, userId uint) (*model.TwoFactorEnrollment, error) {
+	ret := m.Called(ctx, userId)
+
+	var r0 *model.TwoFactorEnrollment
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.TwoFactorEnrollment)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTwoFactorService) ConfirmEnrollment(ctx context.Context, userId uint, code string) ([]string, error) {
+	ret := m.Called(ctx, userId, code)
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTwoFactorService) Disable(ctx context.Context, userId uint, code string) error {
+	ret := m.Called(ctx, userId, code)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockTwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userId uint, code string) ([]string, error) {
+	ret := m.Called(ctx, userId, code)
+
+	var r0 []string
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).([]string)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTwoFactorService) StartChallenge(ctx context.Context, userId uint) (string, error) {
+	ret := m.Called(ctx, userId)
+
+	r0 := ret.String(0)
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockTwoFactorService) CompleteChallenge(ctx context.Context, ticket string, code string) (*model.User, error) {
+	ret := m.Called(ctx, ticket, code)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
@@ -61,6 +61,22 @@ func (m *MockUserRepository) FindByUsername(ctx context.Context, username string
 	return r0, r1
 }
 
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	ret := m.Called(ctx, email)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
 func (m *MockUserRepository) Update(ctx context.Context, user *model.User) error {
 	ret := m.Called(ctx, user)
 
@@ -72,7 +88,13 @@ func (m *MockUserRepository) Update(ctx context.Context, user *model.User) error
 	return r0
 }
 
-func (m *MockUserRepository) MigrateAll() error {
-	// this will never need testing
-	return nil
+func (m *MockUserRepository) AdvanceTOTPStep(ctx context.Context, userId uint, step int64) error {
+	ret := m.Called(ctx, userId, step)
+
+	var r0 error = nil
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
 }
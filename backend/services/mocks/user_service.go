@@ -76,7 +76,7 @@ func (m *MockUserService) Signin(ctx context.Context, username string, password
 	return r0, r1
 }
 
-func (m *MockUserService) UpdateSessionToken(ctx context.Context, user *model.User) error {
+func (m *MockUserService) Update(ctx context.Context, user *model.User) error {
 	ret := m.Called(ctx, user)
 
 	var r0 error
@@ -87,8 +87,8 @@ func (m *MockUserService) UpdateSessionToken(ctx context.Context, user *model.Us
 	return r0
 }
 
-func (m *MockUserService) Update(ctx context.Context, user *model.User) error {
-	ret := m.Called(ctx, user)
+func (m *MockUserService) ChangePassword(ctx context.Context, userId uint, currentPassword string, newPassword string) error {
+	ret := m.Called(ctx, userId, currentPassword, newPassword)
 
 	var r0 error
 	if ret.Get(0) != nil {
@@ -98,7 +98,67 @@ func (m *MockUserService) Update(ctx context.Context, user *model.User) error {
 	return r0
 }
 
-func (m *MockUserService) MigrateAll() error {
-	// no need to test
-	return nil
+func (m *MockUserService) RequestPasswordReset(ctx context.Context, username string) error {
+	ret := m.Called(ctx, username)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, token string, newPassword string) (*model.User, error) {
+	ret := m.Called(ctx, token, newPassword)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) RequestEmailVerification(ctx context.Context, userId uint, email string) error {
+	ret := m.Called(ctx, userId, email)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
+}
+
+func (m *MockUserService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	ret := m.Called(ctx, token)
+
+	var r0 *model.User
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(*model.User)
+	}
+
+	var r1 error
+	if ret.Get(1) != nil {
+		r1 = ret.Get(1).(error)
+	}
+
+	return r0, r1
+}
+
+func (m *MockUserService) RemoveEmail(ctx context.Context, userId uint) error {
+	ret := m.Called(ctx, userId)
+
+	var r0 error
+	if ret.Get(0) != nil {
+		r0 = ret.Get(0).(error)
+	}
+
+	return r0
 }
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* interface */
+
+type IPasswordResetRepository interface {
+	Create(ctx context.Context, t *model.PasswordResetToken) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error)
+	// gorm.ErrRecordNotFound if token `id` was already used; this is what
+	// keeps a token single use when it's redeemed twice at once
+	MarkUsed(ctx context.Context, id uint, usedAt time.Time) error
+	// drop all of a user's tokens, e.g. once their password has changed
+	DeleteByUserID(ctx context.Context, userId uint) error
+}
+
+/* implementation */
+
+type PasswordResetRepository struct {
+	BaseRepository
+}
+
+type PasswordResetRepoDeps struct {
+	BaseDeps *BaseRepoDeps
+}
+
+func NewPasswordResetRepository(deps *PasswordResetRepoDeps) IPasswordResetRepository {
+	return &PasswordResetRepository{
+		BaseRepository: NewBaseRepository(deps.BaseDeps),
+	}
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, token *model.PasswordResetToken) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "PasswordResetRepository.Create")
+	defer endSpan()
+	return r.Db.WithContext(ctx).Create(token).Error
+}
+
+func (r *PasswordResetRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.PasswordResetToken, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "PasswordResetRepository.FindByTokenHash")
+	defer endSpan()
+	var token model.PasswordResetToken
+	err := r.Db.WithContext(ctx).
+		First(&token, "token_hash = ?", tokenHash).
+		Error
+	return &token, err
+}
+
+func (r *PasswordResetRepository) MarkUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "PasswordResetRepository.MarkUsed")
+	defer endSpan()
+	result := r.Db.WithContext(ctx).
+		Model(&model.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PasswordResetRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "PasswordResetRepository.DeleteByUserID")
+	defer endSpan()
+	return r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.PasswordResetToken{}).
+		Error
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestPasswordResetRepository(t *testing.T) {
+	t.Run("Tokens can only be marked used once", func(t *testing.T) {
+		repo := &PasswordResetRepository{BaseRepository{Db: newTestDb(t)}}
+		token := &model.PasswordResetToken{UserID: 1, TokenHash: "abc", ExpiresAt: time.Now().Add(time.Hour)}
+		assert.NoError(t, repo.Create(context.TODO(), token))
+
+		assert.NoError(t, repo.MarkUsed(context.TODO(), token.ID, time.Now()))
+		assert.ErrorIs(t, repo.MarkUsed(context.TODO(), token.ID, time.Now()), gorm.ErrRecordNotFound)
+
+		found, err := repo.FindByTokenHash(context.TODO(), "abc")
+		assert.NoError(t, err)
+		assert.NotNil(t, found.UsedAt)
+		assert.False(t, found.IsUsable(time.Now()))
+	})
+
+	t.Run("Deletes a user's tokens", func(t *testing.T) {
+		repo := &PasswordResetRepository{BaseRepository{Db: newTestDb(t)}}
+		assert.NoError(t, repo.Create(context.TODO(), &model.PasswordResetToken{UserID: 1, TokenHash: "a"}))
+		assert.NoError(t, repo.Create(context.TODO(), &model.PasswordResetToken{UserID: 2, TokenHash: "b"}))
+
+		assert.NoError(t, repo.DeleteByUserID(context.TODO(), 1))
+
+		_, err := repo.FindByTokenHash(context.TODO(), "a")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = repo.FindByTokenHash(context.TODO(), "b")
+		assert.NoError(t, err)
+	})
+}
@@ -2,46 +2,175 @@ package services
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"golang.org/x/crypto/scrypt"
 	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
 )
 
+/*
+ * Passwords are stored in the PHC string format, which names the
+ * algorithm and its parameters next to the salt and hash:
+ *
+ *   $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>
+ *
+ * (salt and hash in unpadded base64), so the parameters can be raised
+ * later without breaking existing passwords. Those are rehashed with the
+ * current parameters the next time their user signs in.
+ *
+ * Passwords from before this format are scrypt hashes stored as
+ * "<hash>.<salt>" in hex; they still verify, and get rehashed the same way.
+ */
+
+type argon2Params struct {
+	// KiB
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+// OWASP's recommended argon2id parameters (as of 2024)
+var currentArgon2Params = argon2Params{
+	memory:      19 * 1024,
+	iterations:  2,
+	parallelism: 1,
+	saltLength:  16,
+	keyLength:   32,
+}
+
+// cost parameters of legacy scrypt hashes, from https://godoc.org/golang.org/x/crypto/scrypt
+const (
+	legacyScryptN      = 32768
+	legacyScryptR      = 8
+	legacyScryptP      = 1
+	legacyScryptKeyLen = 32
+)
+
+// upper bounds on the parameters of stored hashes, so a bad value can't tie up the server
+const (
+	maxArgon2Memory     = 1024 * 1024 // 1 GiB
+	maxArgon2Iterations = 16
+)
+
+var errMalformedPasswordHash = errors.New("malformed password hash")
+
+var phcEncoding = base64.RawStdEncoding
+
 // create a salted and hashed string from a plaintext password
 func hashPassword(password string) (string, error) {
-	// example for making salt - https://play.golang.org/p/_Aw6WeWC42I
-	salt := make([]byte, 32)
-	_, err := rand.Read(salt)
-	if err != nil {
+	params := currentArgon2Params
+	salt := make([]byte, params.saltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", err
 	}
 
-	// using recommended cost parameters from - https://godoc.org/golang.org/x/crypto/scrypt
-	saltHash, err := scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, params.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.memory, params.iterations, params.parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+/**
+ * Returns whether suppliedPassword matches storedPassword, once hashed
+ * the same way. Errors (rather than panics) on stored values it can't read.
+ */
+func comparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
+	if storedPassword == "" {
+		// users who only sign in through an identity provider have no password
+		return false, nil
+	}
+	if !strings.HasPrefix(storedPassword, "$") {
+		return compareLegacyScrypt(storedPassword, suppliedPassword)
+	}
+
+	params, salt, key, err := parseArgon2Hash(storedPassword)
 	if err != nil {
-		return "", err
+		return false, fmt.Errorf("Password verification failed: %w", err)
 	}
+	suppliedKey := argon2.IDKey([]byte(suppliedPassword), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(suppliedKey, key) == 1, nil
+}
 
-	// hex-encode string w/ random salt appended to pw
-	// (salt is appended so we can use it again in pw verification)
-	hashedPassword := fmt.Sprintf("%s.%s", hex.EncodeToString(saltHash), hex.EncodeToString(salt))
-	return hashedPassword, nil
+// whether storedPassword should be hashed again with the current algorithm and parameters
+func passwordNeedsRehash(storedPassword string) bool {
+	params, salt, key, err := parseArgon2Hash(storedPassword)
+	if err != nil {
+		// legacy scrypt, or unreadable
+		return true
+	}
+	return params.memory != currentArgon2Params.memory ||
+		params.iterations != currentArgon2Params.iterations ||
+		params.parallelism != currentArgon2Params.parallelism ||
+		uint32(len(salt)) != currentArgon2Params.saltLength ||
+		uint32(len(key)) != currentArgon2Params.keyLength
 }
 
-// returns whether the 2 passwords match, once suppliedPassword
-// is salted and hashed same way as storedPassword
-func comparePasswords(storedPassword string, suppliedPassword string) (bool, error) {
-	pwsalt := strings.Split(storedPassword, ".")
+// parameters, salt and key of an argon2id PHC string
+func parseArgon2Hash(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" {
+		return params, nil, nil, errMalformedPasswordHash
+	}
+	if parts[1] != "argon2id" {
+		return params, nil, nil, fmt.Errorf("unsupported password hash algorithm %q", parts[1])
+	}
 
-	// decode the random salt from stored pw
-	salt, err := hex.DecodeString(pwsalt[1])
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, errMalformedPasswordHash
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return params, nil, nil, errMalformedPasswordHash
+	}
+	if params.memory == 0 || params.memory > maxArgon2Memory ||
+		params.iterations == 0 || params.iterations > maxArgon2Iterations ||
+		params.parallelism == 0 {
+		return params, nil, nil, errMalformedPasswordHash
+	}
+
+	salt, err := phcEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return params, nil, nil, errMalformedPasswordHash
+	}
+	key, err := phcEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errMalformedPasswordHash
+	}
+	params.saltLength = uint32(len(salt))
+	params.keyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// compare against a legacy "<hash>.<salt>" hex scrypt hash
+func compareLegacyScrypt(storedPassword string, suppliedPassword string) (bool, error) {
+	hashHex, saltHex, found := strings.Cut(storedPassword, ".")
+	if !found {
+		return false, fmt.Errorf("Password verification failed: %w", errMalformedPasswordHash)
+	}
+	key, err := hex.DecodeString(hashHex)
+	if err != nil || len(key) == 0 {
+		return false, fmt.Errorf("Password verification failed: %w", errMalformedPasswordHash)
+	}
+	salt, err := hex.DecodeString(saltHex)
 	if err != nil {
 		return false, fmt.Errorf("Password verification failed: %v", err)
 	}
 
-	// hash supplied pw using salt obtained from stored pw
-	saltHash, err := scrypt.Key([]byte(suppliedPassword), salt, 32768, 8, 1, 32)
-
-	return hex.EncodeToString(saltHash) == pwsalt[0], err
+	suppliedKey, err := scrypt.Key([]byte(suppliedPassword), salt, legacyScryptN, legacyScryptR, legacyScryptP, legacyScryptKeyLen)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(suppliedKey, key) == 1, nil
 }
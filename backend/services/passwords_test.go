@@ -0,0 +1,88 @@
+package services
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/scrypt"
+)
+
+// a password hashed the way it was before the PHC format
+func legacyScryptHash(t *testing.T, password string) string {
+	t.Helper()
+	salt := []byte("0123456789abcdef0123456789abcdef")
+	key, err := scrypt.Key([]byte(password), salt, legacyScryptN, legacyScryptR, legacyScryptP, legacyScryptKeyLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(key) + "." + hex.EncodeToString(salt)
+}
+
+func TestPasswordHashing(t *testing.T) {
+	t.Run("Hashes to argon2id and verifies", func(t *testing.T) {
+		hashed, err := hashPassword("password1")
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=19456,t=2,p=1$"))
+
+		matching, err := comparePasswords(hashed, "password1")
+		assert.NoError(t, err)
+		assert.True(t, matching)
+		matching, err = comparePasswords(hashed, "password2")
+		assert.NoError(t, err)
+		assert.False(t, matching)
+	})
+
+	t.Run("Salts each hash", func(t *testing.T) {
+		first, _ := hashPassword("password1")
+		second, _ := hashPassword("password1")
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Verifies legacy scrypt hashes", func(t *testing.T) {
+		hashed := legacyScryptHash(t, "password1")
+
+		matching, err := comparePasswords(hashed, "password1")
+		assert.NoError(t, err)
+		assert.True(t, matching)
+		matching, err = comparePasswords(hashed, "password2")
+		assert.NoError(t, err)
+		assert.False(t, matching)
+	})
+
+	t.Run("Errors on malformed stored values instead of panicking", func(t *testing.T) {
+		for _, stored := range []string{
+			"no-dot",
+			"zz.zz",
+			"$argon2id$",
+			"$bcrypt$v=19$m=19456,t=2,p=1$c2FsdA$aGFzaA",
+			"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$aGFzaA",
+			"$argon2id$v=19$m=0,t=2,p=1$c2FsdA$aGFzaA",
+			"$argon2id$v=19$m=19456,t=1000,p=1$c2FsdA$aGFzaA",
+			"$argon2id$v=19$m=19456,t=2,p=1$not base64!$aGFzaA",
+			"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$",
+		} {
+			matching, err := comparePasswords(stored, "password1")
+			assert.Error(t, err, stored)
+			assert.False(t, matching, stored)
+		}
+	})
+
+	t.Run("Nothing matches an empty stored password", func(t *testing.T) {
+		// users signing in through an identity provider only
+		matching, err := comparePasswords("", "")
+		assert.NoError(t, err)
+		assert.False(t, matching)
+	})
+
+	t.Run("Outdated hashes need rehashing", func(t *testing.T) {
+		current, _ := hashPassword("password1")
+
+		assert.False(t, passwordNeedsRehash(current))
+		assert.True(t, passwordNeedsRehash(legacyScryptHash(t, "password1")))
+		assert.True(t, passwordNeedsRehash("$argon2id$v=19$m=4096,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaGhhc2hoYXNoaGFzaGhhc2g"))
+		assert.True(t, passwordNeedsRehash("junk"))
+	})
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* interface */
+
+type IRecoveryCodeRepository interface {
+	// swap all of user `userId`'s codes for `codes`
+	Replace(ctx context.Context, userId uint, codes []model.RecoveryCode) error
+	// gorm.ErrRecordNotFound unless `userId` has an unused code hashing to `codeHash`;
+	// like password reset tokens, this is what keeps codes single use
+	MarkUsed(ctx context.Context, userId uint, codeHash string, usedAt time.Time) error
+	CountUnused(ctx context.Context, userId uint) (int64, error)
+	DeleteByUserID(ctx context.Context, userId uint) error
+}
+
+/* implementation */
+
+type RecoveryCodeRepository struct {
+	BaseRepository
+}
+
+type RecoveryCodeRepoDeps struct {
+	BaseDeps *BaseRepoDeps
+}
+
+func NewRecoveryCodeRepository(deps *RecoveryCodeRepoDeps) IRecoveryCodeRepository {
+	return &RecoveryCodeRepository{
+		BaseRepository: NewBaseRepository(deps.BaseDeps),
+	}
+}
+
+func (r *RecoveryCodeRepository) Replace(ctx context.Context, userId uint, codes []model.RecoveryCode) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "RecoveryCodeRepository.Replace")
+	defer endSpan()
+	return r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		for i := range codes {
+			codes[i].UserID = userId
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *RecoveryCodeRepository) MarkUsed(ctx context.Context, userId uint, codeHash string, usedAt time.Time) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "RecoveryCodeRepository.MarkUsed")
+	defer endSpan()
+	result := r.Db.WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *RecoveryCodeRepository) CountUnused(ctx context.Context, userId uint) (int64, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "RecoveryCodeRepository.CountUnused")
+	defer endSpan()
+	var count int64
+	err := r.Db.WithContext(ctx).
+		Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userId).
+		Count(&count).
+		Error
+	return count, err
+}
+
+func (r *RecoveryCodeRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "RecoveryCodeRepository.DeleteByUserID")
+	defer endSpan()
+	return r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.RecoveryCode{}).
+		Error
+}
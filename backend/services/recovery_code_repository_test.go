@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestRecoveryCodeRepository(t *testing.T) {
+	t.Run("Replace swaps out a user's codes", func(t *testing.T) {
+		repo := &RecoveryCodeRepository{BaseRepository{Db: newTestDb(t)}}
+		assert.NoError(t, repo.Replace(context.TODO(), 1, []model.RecoveryCode{{CodeHash: "a"}, {CodeHash: "b"}}))
+		assert.NoError(t, repo.Replace(context.TODO(), 2, []model.RecoveryCode{{CodeHash: "a"}}))
+
+		assert.NoError(t, repo.Replace(context.TODO(), 1, []model.RecoveryCode{{CodeHash: "c"}}))
+
+		count, err := repo.CountUnused(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+		assert.ErrorIs(t, repo.MarkUsed(context.TODO(), 1, "a", time.Now()), gorm.ErrRecordNotFound)
+		count, _ = repo.CountUnused(context.TODO(), 2)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Codes can only be used once, by their own user", func(t *testing.T) {
+		repo := &RecoveryCodeRepository{BaseRepository{Db: newTestDb(t)}}
+		assert.NoError(t, repo.Replace(context.TODO(), 1, []model.RecoveryCode{{CodeHash: "a"}, {CodeHash: "b"}}))
+
+		assert.ErrorIs(t, repo.MarkUsed(context.TODO(), 2, "a", time.Now()), gorm.ErrRecordNotFound)
+		assert.NoError(t, repo.MarkUsed(context.TODO(), 1, "a", time.Now()))
+		assert.ErrorIs(t, repo.MarkUsed(context.TODO(), 1, "a", time.Now()), gorm.ErrRecordNotFound)
+
+		count, err := repo.CountUnused(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+}
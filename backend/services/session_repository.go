@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* interface */
+
+type ISessionRepository interface {
+	Create(ctx context.Context, s *model.Session) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error)
+	// most recently used first
+	ListByUserID(ctx context.Context, userId uint) ([]model.Session, error)
+	UpdateLastSeen(ctx context.Context, id uint, lastSeen time.Time) error
+	// gorm.ErrRecordNotFound if user `userId` has no session `id`
+	Delete(ctx context.Context, userId uint, id uint) error
+	DeleteByUserID(ctx context.Context, userId uint) error
+	// drop the sessions of `userId` last used before `lastSeen`
+	DeleteIdleSince(ctx context.Context, userId uint, lastSeen time.Time) error
+}
+
+/* implementation */
+
+type SessionRepository struct {
+	BaseRepository
+}
+
+type SessionRepoDeps struct {
+	BaseDeps *BaseRepoDeps
+}
+
+func NewSessionRepository(deps *SessionRepoDeps) ISessionRepository {
+	return &SessionRepository{
+		BaseRepository: NewBaseRepository(deps.BaseDeps),
+	}
+}
+
+func (s *SessionRepository) Create(ctx context.Context, session *model.Session) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "SessionRepository.Create")
+	defer endSpan()
+	return s.Db.WithContext(ctx).Create(session).Error
+}
+
+func (s *SessionRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.Session, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "SessionRepository.FindByTokenHash")
+	defer endSpan()
+	var session model.Session
+	err := s.Db.WithContext(ctx).
+		First(&session, "token_hash = ?", tokenHash).
+		Error
+	return &session, err
+}
+
+func (s *SessionRepository) ListByUserID(ctx context.Context, userId uint) ([]model.Session, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "SessionRepository.ListByUserID")
+	defer endSpan()
+	var sessions []model.Session
+	err := s.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("last_seen_at DESC").
+		Order("id DESC").
+		Find(&sessions).
+		Error
+	return sessions, err
+}
+
+func (s *SessionRepository) UpdateLastSeen(ctx context.Context, id uint, lastSeen time.Time) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "SessionRepository.UpdateLastSeen")
+	defer endSpan()
+	return s.Db.WithContext(ctx).
+		Model(&model.Session{ID: id}).
+		Update("last_seen_at", lastSeen).
+		Error
+}
+
+func (s *SessionRepository) Delete(ctx context.Context, userId uint, id uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "SessionRepository.Delete")
+	defer endSpan()
+	result := s.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.Session{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (s *SessionRepository) DeleteByUserID(ctx context.Context, userId uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "SessionRepository.DeleteByUserID")
+	defer endSpan()
+	return s.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.Session{}).
+		Error
+}
+
+func (s *SessionRepository) DeleteIdleSince(ctx context.Context, userId uint, lastSeen time.Time) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "SessionRepository.DeleteIdleSince")
+	defer endSpan()
+	return s.Db.WithContext(ctx).
+		Where("user_id = ? AND last_seen_at < ?", userId, lastSeen).
+		Delete(&model.Session{}).
+		Error
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func newTestSessionRepo(t *testing.T) *SessionRepository {
+	return &SessionRepository{BaseRepository{Db: newTestDb(t)}}
+}
+
+func TestSessionRepository(t *testing.T) {
+	t.Run("Finds sessions by token hash", func(t *testing.T) {
+		repo := newTestSessionRepo(t)
+		session := &model.Session{UserID: 1, TokenHash: "abc", UserAgent: "firefox", LastSeenAt: time.Now()}
+		assert.NoError(t, repo.Create(context.TODO(), session))
+
+		found, err := repo.FindByTokenHash(context.TODO(), "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, session.ID, found.ID)
+		assert.Equal(t, "firefox", found.UserAgent)
+
+		_, err = repo.FindByTokenHash(context.TODO(), "def")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Lists a user's sessions, most recently used first", func(t *testing.T) {
+		repo := newTestSessionRepo(t)
+		now := time.Now()
+		older := &model.Session{UserID: 1, TokenHash: "a", LastSeenAt: now.Add(-time.Hour)}
+		newer := &model.Session{UserID: 1, TokenHash: "b", LastSeenAt: now}
+		other := &model.Session{UserID: 2, TokenHash: "c", LastSeenAt: now}
+		for _, s := range []*model.Session{older, newer, other} {
+			assert.NoError(t, repo.Create(context.TODO(), s))
+		}
+
+		sessions, err := repo.ListByUserID(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+		assert.Equal(t, newer.ID, sessions[0].ID)
+		assert.Equal(t, older.ID, sessions[1].ID)
+	})
+
+	t.Run("Deletes only the user's own sessions", func(t *testing.T) {
+		repo := newTestSessionRepo(t)
+		mine := &model.Session{UserID: 1, TokenHash: "a"}
+		theirs := &model.Session{UserID: 2, TokenHash: "b"}
+		assert.NoError(t, repo.Create(context.TODO(), mine))
+		assert.NoError(t, repo.Create(context.TODO(), theirs))
+
+		assert.ErrorIs(t, repo.Delete(context.TODO(), 1, theirs.ID), gorm.ErrRecordNotFound)
+		assert.NoError(t, repo.Delete(context.TODO(), 1, mine.ID))
+
+		_, err := repo.FindByTokenHash(context.TODO(), "a")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = repo.FindByTokenHash(context.TODO(), "b")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Deletes all and idle sessions of a user", func(t *testing.T) {
+		repo := newTestSessionRepo(t)
+		now := time.Now()
+		idle := &model.Session{UserID: 1, TokenHash: "a", LastSeenAt: now.Add(-time.Hour)}
+		active := &model.Session{UserID: 1, TokenHash: "b", LastSeenAt: now}
+		other := &model.Session{UserID: 2, TokenHash: "c", LastSeenAt: now.Add(-time.Hour)}
+		for _, s := range []*model.Session{idle, active, other} {
+			assert.NoError(t, repo.Create(context.TODO(), s))
+		}
+
+		assert.NoError(t, repo.DeleteIdleSince(context.TODO(), 1, now.Add(-time.Minute)))
+		sessions, err := repo.ListByUserID(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+		assert.Equal(t, active.ID, sessions[0].ID)
+
+		assert.NoError(t, repo.DeleteByUserID(context.TODO(), 1))
+		sessions, err = repo.ListByUserID(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Empty(t, sessions)
+		sessions, err = repo.ListByUserID(context.TODO(), 2)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 1)
+	})
+
+	t.Run("Updates the last seen time", func(t *testing.T) {
+		repo := newTestSessionRepo(t)
+		session := &model.Session{UserID: 1, TokenHash: "a", LastSeenAt: time.Now().Add(-time.Hour)}
+		assert.NoError(t, repo.Create(context.TODO(), session))
+
+		seen := time.Now().Truncate(time.Second)
+		assert.NoError(t, repo.UpdateLastSeen(context.TODO(), session.ID, seen))
+		found, err := repo.FindByTokenHash(context.TODO(), "a")
+		assert.NoError(t, err)
+		assert.True(t, seen.Equal(found.LastSeenAt))
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// how stale a session's LastSeenAt may get before a request refreshes it;
+// saves writing to the db on every authenticated request
+const sessionTouchInterval = time.Minute
+
+/* interfaces */
+
+// methods the router handler layer interacts with
+type ISessionService interface {
+	// start a new session for `userId`, returning the token to give the client
+	Create(ctx context.Context, userId uint, client model.SessionClient) (string, *model.Session, error)
+	// the session of `userId` that `token` belongs to, if it's still valid
+	Authenticate(ctx context.Context, userId uint, token string) (*model.Session, error)
+	List(ctx context.Context, userId uint) ([]model.Session, error)
+	Revoke(ctx context.Context, userId uint, sessionId uint) error
+	RevokeAll(ctx context.Context, userId uint) error
+}
+
+/* implementation */
+
+type SessionService struct {
+	sessionRepository ISessionRepository
+	now               func() time.Time
+}
+
+// injectable deps
+type SessionServiceDeps struct {
+	SessionRepository ISessionRepository
+}
+
+func NewSessionService(d SessionServiceDeps) ISessionService {
+	return &SessionService{
+		sessionRepository: d.SessionRepository,
+		now:               time.Now,
+	}
+}
+
+func (s *SessionService) Create(ctx context.Context, userId uint, client model.SessionClient) (string, *model.Session, error) {
+	now := s.now()
+	// clear out the user's expired sessions while we're here
+	if err := s.sessionRepository.DeleteIdleSince(ctx, userId, now.Add(-model.SessionIdleTimeout)); err != nil {
+		logger.WarnCtx(ctx, "Unable to delete expired sessions", zap.Error(err))
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to generate session token", zap.Error(err))
+		return "", nil, apperrors.NewInternal()
+	}
+	session := &model.Session{
+		UserID:     userId,
+		TokenHash:  model.HashToken(token),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		UserAgent:  truncate(client.UserAgent, 512),
+		IP:         truncate(client.IP, 64),
+	}
+	if err := s.sessionRepository.Create(ctx, session); err != nil {
+		logger.ErrorCtx(ctx, "Unable to create session", zap.Error(err))
+		return "", nil, apperrors.NewInternal()
+	}
+	return token, session, nil
+}
+
+// 401 error for unknown, expired or someone else's tokens
+func (s *SessionService) Authenticate(ctx context.Context, userId uint, token string) (*model.Session, error) {
+	session, err := s.sessionRepository.FindByTokenHash(ctx, model.HashToken(token))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to look up session", zap.Error(err))
+		}
+		return nil, apperrors.NewUnauthorized()
+	}
+	if session.UserID != userId {
+		return nil, apperrors.NewUnauthorized()
+	}
+
+	now := s.now()
+	if session.IsExpired(now) {
+		if err := s.sessionRepository.Delete(ctx, userId, session.ID); err != nil {
+			logger.WarnCtx(ctx, "Unable to delete expired session", zap.Error(err))
+		}
+		return nil, apperrors.NewUnauthorized()
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		if err := s.sessionRepository.UpdateLastSeen(ctx, session.ID, now); err != nil {
+			// only the device list goes stale; not worth failing the request over
+			logger.WarnCtx(ctx, "Unable to update session last seen time", zap.Error(err))
+		} else {
+			session.LastSeenAt = now
+		}
+	}
+	return session, nil
+}
+
+// the unexpired sessions of `userId`, most recently used first
+func (s *SessionService) List(ctx context.Context, userId uint) ([]model.Session, error) {
+	sessions, err := s.sessionRepository.ListByUserID(ctx, userId)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to list sessions", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+
+	now := s.now()
+	active := make([]model.Session, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.IsExpired(now) {
+			active = append(active, session)
+		}
+	}
+	return active, nil
+}
+
+func (s *SessionService) Revoke(ctx context.Context, userId uint, sessionId uint) error {
+	err := s.sessionRepository.Delete(ctx, userId, sessionId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperrors.NewNotFound("Session", strconv.FormatUint(uint64(sessionId), 10))
+	}
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to revoke session", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+func (s *SessionService) RevokeAll(ctx context.Context, userId uint) error {
+	if err := s.sessionRepository.DeleteByUserID(ctx, userId); err != nil {
+		logger.ErrorCtx(ctx, "Unable to revoke sessions", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// `s` cut down to at most `max` bytes, to fit its db column
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
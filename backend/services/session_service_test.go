@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/model"
+)
+
+// session service on an in memory repo, with a clock the test controls
+func newTestSessionService() (*SessionService, *MemorySessionRepository, *time.Time) {
+	repo := NewMemorySessionRepository()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSessionService(SessionServiceDeps{SessionRepository: repo}).(*SessionService)
+	s.now = func() time.Time { return now }
+	return s, repo, &now
+}
+
+func TestSessionServiceCreate(t *testing.T) {
+	t.Run("Stores only the token's hash", func(t *testing.T) {
+		s, repo, _ := newTestSessionService()
+
+		token, session, err := s.Create(context.TODO(), 1, model.SessionClient{UserAgent: "firefox", IP: "10.0.0.1"})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+		assert.NotContains(t, token, "::")
+		assert.Equal(t, model.HashToken(token), session.TokenHash)
+		stored, err := repo.FindByTokenHash(context.TODO(), session.TokenHash)
+		assert.NoError(t, err)
+		assert.Equal(t, "firefox", stored.UserAgent)
+		assert.Equal(t, "10.0.0.1", stored.IP)
+	})
+
+	t.Run("Each signin gets its own session", func(t *testing.T) {
+		s, _, _ := newTestSessionService()
+
+		first, _, err := s.Create(context.TODO(), 1, model.SessionClient{})
+		assert.NoError(t, err)
+		second, _, err := s.Create(context.TODO(), 1, model.SessionClient{})
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, first, second)
+		sessions, err := s.List(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Len(t, sessions, 2)
+	})
+
+	t.Run("Clears out the user's expired sessions", func(t *testing.T) {
+		s, repo, now := newTestSessionService()
+		_, stale, err := s.Create(context.TODO(), 1, model.SessionClient{})
+		assert.NoError(t, err)
+
+		*now = now.Add(model.SessionIdleTimeout + time.Minute)
+		_, _, err = s.Create(context.TODO(), 1, model.SessionClient{})
+		assert.NoError(t, err)
+
+		_, err = repo.FindByTokenHash(context.TODO(), stale.TokenHash)
+		assert.Error(t, err)
+	})
+}
+
+func TestSessionServiceAuthenticate(t *testing.T) {
+	t.Run("Accepts the token of a live session", func(t *testing.T) {
+		s, _, _ := newTestSessionService()
+		token, created, _ := s.Create(context.TODO(), 1, model.SessionClient{})
+
+		session, err := s.Authenticate(context.TODO(), 1, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, created.ID, session.ID)
+	})
+
+	t.Run("Rejects unknown tokens and other users' tokens", func(t *testing.T) {
+		s, _, _ := newTestSessionService()
+		token, _, _ := s.Create(context.TODO(), 1, model.SessionClient{})
+
+		_, err := s.Authenticate(context.TODO(), 1, "not-a-token")
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		_, err = s.Authenticate(context.TODO(), 2, token)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Rejects revoked sessions", func(t *testing.T) {
+		s, _, _ := newTestSessionService()
+		token, session, _ := s.Create(context.TODO(), 1, model.SessionClient{})
+		other, _, _ := s.Create(context.TODO(), 1, model.SessionClient{})
+
+		assert.NoError(t, s.Revoke(context.TODO(), 1, session.ID))
+
+		_, err := s.Authenticate(context.TODO(), 1, token)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+		_, err = s.Authenticate(context.TODO(), 1, other)
+		assert.NoError(t, err)
+
+		assert.NoError(t, s.RevokeAll(context.TODO(), 1))
+		_, err = s.Authenticate(context.TODO(), 1, other)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Expires idle sessions", func(t *testing.T) {
+		s, _, now := newTestSessionService()
+		token, _, _ := s.Create(context.TODO(), 1, model.SessionClient{})
+
+		*now = now.Add(model.SessionIdleTimeout)
+
+		_, err := s.Authenticate(context.TODO(), 1, token)
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Use keeps a session alive, refreshing last seen at most once a minute", func(t *testing.T) {
+		s, repo, now := newTestSessionService()
+		token, created, _ := s.Create(context.TODO(), 1, model.SessionClient{})
+		start := *now
+
+		*now = start.Add(30 * time.Second)
+		_, err := s.Authenticate(context.TODO(), 1, token)
+		assert.NoError(t, err)
+		stored, _ := repo.FindByTokenHash(context.TODO(), created.TokenHash)
+		assert.True(t, start.Equal(stored.LastSeenAt))
+
+		*now = start.Add(model.SessionIdleTimeout - time.Minute)
+		session, err := s.Authenticate(context.TODO(), 1, token)
+		assert.NoError(t, err)
+		assert.True(t, now.Equal(session.LastSeenAt))
+
+		*now = start.Add(model.SessionIdleTimeout + time.Minute)
+		_, err = s.Authenticate(context.TODO(), 1, token)
+		assert.NoError(t, err)
+	})
+}
+
+func TestSessionServiceRevoke(t *testing.T) {
+	t.Run("Can't revoke another user's session", func(t *testing.T) {
+		s, _, _ := newTestSessionService()
+		token, session, _ := s.Create(context.TODO(), 1, model.SessionClient{})
+
+		err := s.Revoke(context.TODO(), 2, session.ID)
+
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(err))
+		_, err = s.Authenticate(context.TODO(), 1, token)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net-go/server/backend/migrations"
+	"net-go/server/backend/model"
+)
+
+/**
+ * Opens a fresh, fully migrated sqlite database in a temp dir
+ * for repository tests to run against.
+ */
+func newTestDb(t *testing.T) *gorm.DB {
+	t.Helper()
+	dbStr, err := BuildDbString(SQLiteDriver, DbConnectionInfo{
+		Name: filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialector, err := dialectorFor(SQLiteDriver, dbStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// translated errors, like connectDb
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migrations.NewMigrator(db, migrations.All).Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDb, err := db.DB(); err == nil {
+			sqlDb.Close()
+		}
+	})
+	return db
+}
+
+// repositories backed by the same fresh test db
+func newTestRepos(t *testing.T) (*UserRepository, *GameRepository) {
+	base := BaseRepository{Db: newTestDb(t)}
+	return &UserRepository{base}, &GameRepository{base}
+}
+
+// creates a user for each of `usernames`, in order
+func createTestUsers(t *testing.T, repo IUserRepository, usernames ...string) []*model.User {
+	t.Helper()
+	users := make([]*model.User, 0, len(usernames))
+	for _, username := range usernames {
+		user := &model.User{Username: username}
+		if err := repo.Create(context.TODO(), user); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	return users
+}
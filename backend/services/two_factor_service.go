@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/logger"
+	"net-go/server/backend/model"
+	"net-go/server/backend/totp"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+/* interfaces */
+
+// methods the router handler layer interacts with
+type ITwoFactorService interface {
+	Status(ctx context.Context, userId uint) (*model.TwoFactorStatus, error)
+	// new TOTP secret for `userId`, replacing any unconfirmed one; 2FA is only on once it's confirmed
+	BeginEnrollment(ctx context.Context, userId uint) (*model.TwoFactorEnrollment, error)
+	// turn 2FA on, if `code` is right for the new secret; returns the
+	// user's recovery codes, which are never shown again
+	ConfirmEnrollment(ctx context.Context, userId uint, code string) ([]string, error)
+	// turn 2FA off; `code` is a TOTP code or a recovery code, like at signin
+	Disable(ctx context.Context, userId uint, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId uint, code string) ([]string, error)
+	// ticket for a signin of `userId` that passed the password check and still needs a code
+	StartChallenge(ctx context.Context, userId uint) (string, error)
+	// the user `ticket` is for, if `code` is right for them; tickets are single use
+	CompleteChallenge(ctx context.Context, ticket string, code string) (*model.User, error)
+}
+
+/* implementation */
+
+type TwoFactorService struct {
+	userRepository         IUserRepository
+	recoveryCodeRepository IRecoveryCodeRepository
+	ticketRepository       ITwoFactorTicketRepository
+	issuer                 string
+	now                    func() time.Time
+}
+
+// injectable deps
+type TwoFactorServiceDeps struct {
+	UserRepository         IUserRepository
+	RecoveryCodeRepository IRecoveryCodeRepository
+	TicketRepository       ITwoFactorTicketRepository
+	// name authenticator apps list the account under
+	Issuer string
+}
+
+func NewTwoFactorService(d TwoFactorServiceDeps) ITwoFactorService {
+	return &TwoFactorService{
+		userRepository:         d.UserRepository,
+		recoveryCodeRepository: d.RecoveryCodeRepository,
+		ticketRepository:       d.TicketRepository,
+		issuer:                 d.Issuer,
+		now:                    time.Now,
+	}
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userId uint) (*model.TwoFactorStatus, error) {
+	user, err := s.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return &model.TwoFactorStatus{}, nil
+	}
+	left, err := s.recoveryCodeRepository.CountUnused(ctx, userId)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to count recovery codes", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	return &model.TwoFactorStatus{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, userId uint) (*model.TwoFactorEnrollment, error) {
+	user, err := s.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, apperrors.NewBadRequest("Two-factor authentication is already on")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to generate TOTP secret", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	user.TOTPSecret = &secret
+	user.TOTPLastStep = 0
+	if err := s.updateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return &model.TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Username, secret),
+	}, nil
+}
+
+func (s *TwoFactorService) ConfirmEnrollment(ctx context.Context, userId uint, code string) ([]string, error) {
+	user, err := s.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, apperrors.NewBadRequest("Two-factor authentication is already on")
+	}
+	if user.TOTPSecret == nil {
+		return nil, apperrors.NewBadRequest("Start two-factor enrollment first")
+	}
+	step, ok := totp.Validate(*user.TOTPSecret, code, s.now())
+	if !ok {
+		return nil, apperrors.NewBadRequest("Invalid two-factor code")
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	user.TOTPEnabledAt = &now
+	user.TOTPLastStep = step
+	if err := s.updateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	logger.InfoCtx(ctx, "User turned on two-factor authentication", zap.Uint("user_id", user.ID))
+	return codes, nil
+}
+
+func (s *TwoFactorService) Disable(ctx context.Context, userId uint, code string) error {
+	user, err := s.getEnabledUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCode(ctx, user, code); err != nil {
+		return err
+	}
+
+	user.TOTPSecret = nil
+	user.TOTPEnabledAt = nil
+	user.TOTPLastStep = 0
+	if err := s.updateUser(ctx, user); err != nil {
+		return err
+	}
+	if err := s.recoveryCodeRepository.DeleteByUserID(ctx, user.ID); err != nil {
+		logger.WarnCtx(ctx, "Unable to delete recovery codes", zap.Error(err))
+	}
+	logger.InfoCtx(ctx, "User turned off two-factor authentication", zap.Uint("user_id", user.ID))
+	return nil
+}
+
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userId uint, code string) ([]string, error) {
+	user, err := s.getEnabledUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkCode(ctx, user, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, user.ID)
+}
+
+func (s *TwoFactorService) StartChallenge(ctx context.Context, userId uint) (string, error) {
+	now := s.now()
+	// clear out abandoned signins while we're here
+	if err := s.ticketRepository.DeleteExpired(ctx, now); err != nil {
+		logger.WarnCtx(ctx, "Unable to delete expired 2FA tickets", zap.Error(err))
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to generate 2FA ticket", zap.Error(err))
+		return "", apperrors.NewInternal()
+	}
+	ticket := &model.TwoFactorTicket{
+		UserID:    userId,
+		TokenHash: model.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(model.TwoFactorTicketTTL),
+	}
+	if err := s.ticketRepository.Create(ctx, ticket); err != nil {
+		logger.ErrorCtx(ctx, "Unable to save 2FA ticket", zap.Error(err))
+		return "", apperrors.NewInternal()
+	}
+	return token, nil
+}
+
+/**
+ * A wrong code is a 400 and counts against the ticket; an unknown,
+ * expired or used up ticket is a 401, and the signin has to start over.
+ */
+func (s *TwoFactorService) CompleteChallenge(ctx context.Context, token string, code string) (*model.User, error) {
+	ticket, err := s.ticketRepository.FindByTokenHash(ctx, model.HashToken(token))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to look up 2FA ticket", zap.Error(err))
+			return nil, apperrors.NewInternal()
+		}
+		return nil, apperrors.NewUnauthorized()
+	}
+	if !ticket.IsUsable(s.now()) {
+		return nil, apperrors.NewUnauthorized()
+	}
+	user, err := s.getEnabledUser(ctx, ticket.UserID)
+	if err != nil {
+		return nil, apperrors.NewUnauthorized()
+	}
+
+	if err := s.checkCode(ctx, user, code); err != nil {
+		if apperrors.Status(err) == http.StatusBadRequest {
+			if err := s.ticketRepository.RecordAttempt(ctx, ticket.ID); err != nil {
+				logger.WarnCtx(ctx, "Unable to count 2FA attempt", zap.Error(err))
+			}
+		}
+		return nil, err
+	}
+	if err := s.ticketRepository.Delete(ctx, ticket.ID); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to delete 2FA ticket", zap.Error(err))
+			return nil, apperrors.NewInternal()
+		}
+		// completed concurrently
+		return nil, apperrors.NewUnauthorized()
+	}
+	return user, nil
+}
+
+// 400 error unless `code` is a TOTP code for `user` not used before, or one of their recovery codes
+func (s *TwoFactorService) checkCode(ctx context.Context, user *model.User, code string) error {
+	now := s.now()
+	if step, ok := totp.Validate(*user.TOTPSecret, code, now); ok {
+		// `user` may be stale, so the stored step decides if the code was used
+		err := s.userRepository.AdvanceTOTPStep(ctx, user.ID, step)
+		if err == nil {
+			user.TOTPLastStep = step
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to record TOTP step", zap.Error(err))
+			return apperrors.NewInternal()
+		}
+	}
+
+	err := s.recoveryCodeRepository.MarkUsed(ctx, user.ID, model.HashRecoveryCode(code), now)
+	if err == nil {
+		logger.InfoCtx(ctx, "User used a recovery code", zap.Uint("user_id", user.ID))
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.ErrorCtx(ctx, "Unable to use recovery code", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	return apperrors.NewBadRequest("Invalid two-factor code")
+}
+
+// new set of recovery codes for `userId`, as shown to the user
+func (s *TwoFactorService) replaceRecoveryCodes(ctx context.Context, userId uint) ([]string, error) {
+	codes := make([]string, model.RecoveryCodeCount)
+	stored := make([]model.RecoveryCode, model.RecoveryCodeCount)
+	now := s.now()
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			logger.ErrorCtx(ctx, "Unable to generate recovery code", zap.Error(err))
+			return nil, apperrors.NewInternal()
+		}
+		codes[i] = code
+		stored[i] = model.RecoveryCode{CodeHash: model.HashRecoveryCode(code), CreatedAt: now}
+	}
+	if err := s.recoveryCodeRepository.Replace(ctx, userId, stored); err != nil {
+		logger.ErrorCtx(ctx, "Unable to save recovery codes", zap.Error(err))
+		return nil, apperrors.NewInternal()
+	}
+	return codes, nil
+}
+
+func (s *TwoFactorService) getUser(ctx context.Context, userId uint) (*model.User, error) {
+	user, err := s.userRepository.FindByID(ctx, userId)
+	if err != nil {
+		return nil, apperrors.NewNotFound("User", strconv.FormatUint(uint64(userId), 10))
+	}
+	return user, nil
+}
+
+// like getUser, but a 400 error unless the user has 2FA on
+func (s *TwoFactorService) getEnabledUser(ctx context.Context, userId uint) (*model.User, error) {
+	user, err := s.getUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, apperrors.NewBadRequest("Two-factor authentication is off")
+	}
+	return user, nil
+}
+
+func (s *TwoFactorService) updateUser(ctx context.Context, user *model.User) error {
+	if err := s.userRepository.Update(ctx, user); err != nil {
+		logger.ErrorCtx(ctx, "Unable to update user 2FA settings", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+// lowercase base32, so codes are easy to read back and type
+const recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// 60 random bits, as "xxxx-xxxx-xxxx"
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	var code strings.Builder
+	for i, v := range b {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+		code.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+	return code.String(), nil
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"net-go/server/backend/apperrors"
+	"net-go/server/backend/model"
+	"net-go/server/backend/totp"
+)
+
+// 2FA service on in memory repos, with user "tim", at a clock tests can move
+func newTestTwoFactorService(t *testing.T) (*TwoFactorService, *model.User, *time.Time) {
+	t.Helper()
+	users := NewMemoryUserRepository()
+	s := NewTwoFactorService(TwoFactorServiceDeps{
+		UserRepository:         users,
+		RecoveryCodeRepository: NewMemoryRecoveryCodeRepository(),
+		TicketRepository:       NewMemoryTwoFactorTicketRepository(),
+		Issuer:                 "net-go",
+	}).(*TwoFactorService)
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+	return s, createTestUsers(t, users, "tim")[0], &now
+}
+
+// the TOTP code for `secret` at `now`
+func totpCode(t *testing.T, secret string, now time.Time) string {
+	t.Helper()
+	code, err := totp.Code(secret, totp.Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// turns 2FA on for `userId`, returning the secret and recovery codes
+func enrollTwoFactor(t *testing.T, s *TwoFactorService, userId uint, now *time.Time) (string, []string) {
+	t.Helper()
+	enrollment, err := s.BeginEnrollment(context.TODO(), userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := s.ConfirmEnrollment(context.TODO(), userId, totpCode(t, enrollment.Secret, *now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a new period, so the next code isn't the one just used
+	*now = now.Add(totp.Period)
+	return enrollment.Secret, codes
+}
+
+func TestTwoFactorServiceEnrollment(t *testing.T) {
+	t.Run("Confirming with a code turns 2FA on", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+
+		enrollment, err := s.BeginEnrollment(context.TODO(), user.ID)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/net-go:tim?"))
+		assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+		status, _ := s.Status(context.TODO(), user.ID)
+		assert.False(t, status.Enabled)
+
+		codes, err := s.ConfirmEnrollment(context.TODO(), user.ID, totpCode(t, enrollment.Secret, *now))
+
+		assert.NoError(t, err)
+		assert.Len(t, codes, model.RecoveryCodeCount)
+		status, err = s.Status(context.TODO(), user.ID)
+		assert.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, int64(model.RecoveryCodeCount), status.RecoveryCodesLeft)
+	})
+
+	t.Run("Wrong code leaves 2FA off", func(t *testing.T) {
+		s, user, _ := newTestTwoFactorService(t)
+		_, err := s.BeginEnrollment(context.TODO(), user.ID)
+		assert.NoError(t, err)
+
+		_, err = s.ConfirmEnrollment(context.TODO(), user.ID, "000000")
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		status, _ := s.Status(context.TODO(), user.ID)
+		assert.False(t, status.Enabled)
+	})
+
+	t.Run("Can't confirm without enrolling or enroll twice", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+
+		_, err := s.ConfirmEnrollment(context.TODO(), user.ID, "000000")
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		enrollTwoFactor(t, s, user.ID, now)
+		_, err = s.BeginEnrollment(context.TODO(), user.ID)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Disabling takes a code", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		secret, _ := enrollTwoFactor(t, s, user.ID, now)
+
+		err := s.Disable(context.TODO(), user.ID, "000000")
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		assert.NoError(t, s.Disable(context.TODO(), user.ID, totpCode(t, secret, *now)))
+		status, _ := s.Status(context.TODO(), user.ID)
+		assert.False(t, status.Enabled)
+		assert.Equal(t, int64(0), status.RecoveryCodesLeft)
+	})
+}
+
+func TestTwoFactorServiceChallenge(t *testing.T) {
+	t.Run("A TOTP code completes the signin once", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		secret, _ := enrollTwoFactor(t, s, user.ID, now)
+		ticket, err := s.StartChallenge(context.TODO(), user.ID)
+		assert.NoError(t, err)
+
+		signedIn, err := s.CompleteChallenge(context.TODO(), ticket, totpCode(t, secret, *now))
+
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, signedIn.ID)
+		_, err = s.CompleteChallenge(context.TODO(), ticket, totpCode(t, secret, *now))
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("TOTP codes can't be replayed", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		secret, _ := enrollTwoFactor(t, s, user.ID, now)
+		code := totpCode(t, secret, *now)
+		first, _ := s.StartChallenge(context.TODO(), user.ID)
+		second, _ := s.StartChallenge(context.TODO(), user.ID)
+
+		_, err := s.CompleteChallenge(context.TODO(), first, code)
+		assert.NoError(t, err)
+		_, err = s.CompleteChallenge(context.TODO(), second, code)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Concurrent uses of a TOTP code only let one through", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		secret, _ := enrollTwoFactor(t, s, user.ID, now)
+		code := totpCode(t, secret, *now)
+		// both requests loaded the user before either used the code
+		first, _ := s.userRepository.FindByID(context.TODO(), user.ID)
+		second, _ := s.userRepository.FindByID(context.TODO(), user.ID)
+
+		assert.NoError(t, s.checkCode(context.TODO(), first, code))
+		err := s.checkCode(context.TODO(), second, code)
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Recovery codes work once each", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		_, codes := enrollTwoFactor(t, s, user.ID, now)
+		first, _ := s.StartChallenge(context.TODO(), user.ID)
+		second, _ := s.StartChallenge(context.TODO(), user.ID)
+
+		// typed in capitals and without the dashes
+		_, err := s.CompleteChallenge(context.TODO(), first, strings.ToUpper(strings.ReplaceAll(codes[0], "-", "")))
+		assert.NoError(t, err)
+		_, err = s.CompleteChallenge(context.TODO(), second, codes[0])
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+
+		status, _ := s.Status(context.TODO(), user.ID)
+		assert.Equal(t, int64(model.RecoveryCodeCount-1), status.RecoveryCodesLeft)
+	})
+
+	t.Run("Regenerating voids the old recovery codes", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		secret, codes := enrollTwoFactor(t, s, user.ID, now)
+
+		fresh, err := s.RegenerateRecoveryCodes(context.TODO(), user.ID, totpCode(t, secret, *now))
+		assert.NoError(t, err)
+		ticket, _ := s.StartChallenge(context.TODO(), user.ID)
+
+		_, err = s.CompleteChallenge(context.TODO(), ticket, codes[0])
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		_, err = s.CompleteChallenge(context.TODO(), ticket, fresh[0])
+		assert.NoError(t, err)
+	})
+
+	t.Run("Tickets expire", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		secret, _ := enrollTwoFactor(t, s, user.ID, now)
+		ticket, _ := s.StartChallenge(context.TODO(), user.ID)
+
+		*now = now.Add(model.TwoFactorTicketTTL)
+		_, err := s.CompleteChallenge(context.TODO(), ticket, totpCode(t, secret, *now))
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Tickets take a few wrong codes", func(t *testing.T) {
+		s, user, now := newTestTwoFactorService(t)
+		secret, _ := enrollTwoFactor(t, s, user.ID, now)
+		ticket, _ := s.StartChallenge(context.TODO(), user.ID)
+
+		for i := 0; i < model.TwoFactorTicketMaxAttempts; i++ {
+			_, err := s.CompleteChallenge(context.TODO(), ticket, "000000")
+			assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		}
+		_, err := s.CompleteChallenge(context.TODO(), ticket, totpCode(t, secret, *now))
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+
+	t.Run("Unknown tickets", func(t *testing.T) {
+		s, _, _ := newTestTwoFactorService(t)
+
+		_, err := s.CompleteChallenge(context.TODO(), "made-up", "000000")
+
+		assert.Equal(t, http.StatusUnauthorized, apperrors.Status(err))
+	})
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/* interface */
+
+type ITwoFactorTicketRepository interface {
+	Create(ctx context.Context, t *model.TwoFactorTicket) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*model.TwoFactorTicket, error)
+	// count a wrong code against ticket `id`
+	RecordAttempt(ctx context.Context, id uint) error
+	// gorm.ErrRecordNotFound if ticket `id` is already gone; this is what
+	// keeps a ticket single use when it's completed twice at once
+	Delete(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context, now time.Time) error
+}
+
+/* implementation */
+
+type TwoFactorTicketRepository struct {
+	BaseRepository
+}
+
+type TwoFactorTicketRepoDeps struct {
+	BaseDeps *BaseRepoDeps
+}
+
+func NewTwoFactorTicketRepository(deps *TwoFactorTicketRepoDeps) ITwoFactorTicketRepository {
+	return &TwoFactorTicketRepository{
+		BaseRepository: NewBaseRepository(deps.BaseDeps),
+	}
+}
+
+func (r *TwoFactorTicketRepository) Create(ctx context.Context, ticket *model.TwoFactorTicket) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "TwoFactorTicketRepository.Create")
+	defer endSpan()
+	return r.Db.WithContext(ctx).Create(ticket).Error
+}
+
+func (r *TwoFactorTicketRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*model.TwoFactorTicket, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "TwoFactorTicketRepository.FindByTokenHash")
+	defer endSpan()
+	var ticket model.TwoFactorTicket
+	err := r.Db.WithContext(ctx).
+		First(&ticket, "token_hash = ?", tokenHash).
+		Error
+	return &ticket, err
+}
+
+func (r *TwoFactorTicketRepository) RecordAttempt(ctx context.Context, id uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "TwoFactorTicketRepository.RecordAttempt")
+	defer endSpan()
+	return r.Db.WithContext(ctx).
+		Model(&model.TwoFactorTicket{}).
+		Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).
+		Error
+}
+
+func (r *TwoFactorTicketRepository) Delete(ctx context.Context, id uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "TwoFactorTicketRepository.Delete")
+	defer endSpan()
+	result := r.Db.WithContext(ctx).Delete(&model.TwoFactorTicket{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *TwoFactorTicketRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "TwoFactorTicketRepository.DeleteExpired")
+	defer endSpan()
+	return r.Db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Delete(&model.TwoFactorTicket{}).
+		Error
+}
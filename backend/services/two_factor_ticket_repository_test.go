@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestTwoFactorTicketRepository(t *testing.T) {
+	t.Run("Counts attempts and deletes once", func(t *testing.T) {
+		repo := &TwoFactorTicketRepository{BaseRepository{Db: newTestDb(t)}}
+		ticket := &model.TwoFactorTicket{UserID: 1, TokenHash: "abc", ExpiresAt: time.Now().Add(time.Minute)}
+		assert.NoError(t, repo.Create(context.TODO(), ticket))
+
+		assert.NoError(t, repo.RecordAttempt(context.TODO(), ticket.ID))
+		assert.NoError(t, repo.RecordAttempt(context.TODO(), ticket.ID))
+		found, err := repo.FindByTokenHash(context.TODO(), "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, found.Attempts)
+
+		assert.NoError(t, repo.Delete(context.TODO(), ticket.ID))
+		assert.ErrorIs(t, repo.Delete(context.TODO(), ticket.ID), gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Deletes expired tickets", func(t *testing.T) {
+		repo := &TwoFactorTicketRepository{BaseRepository{Db: newTestDb(t)}}
+		now := time.Now()
+		assert.NoError(t, repo.Create(context.TODO(), &model.TwoFactorTicket{UserID: 1, TokenHash: "old", ExpiresAt: now.Add(-time.Second)}))
+		assert.NoError(t, repo.Create(context.TODO(), &model.TwoFactorTicket{UserID: 1, TokenHash: "new", ExpiresAt: now.Add(time.Minute)}))
+
+		assert.NoError(t, repo.DeleteExpired(context.TODO(), now))
+
+		_, err := repo.FindByTokenHash(context.TODO(), "old")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		_, err = repo.FindByTokenHash(context.TODO(), "new")
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"net-go/server/backend/instrumentation"
+	"net-go/server/backend/model"
+
+	"gorm.io/gorm"
+)
+
+/* interface */
+
+type IUserIdentityRepository interface {
+	// errors if the identity (issuer and subject) is linked already
+	Create(ctx context.Context, identity *model.UserIdentity) error
+	// creates `user` and links `identity` to it; neither is saved if either fails
+	CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error
+	FindByIssuerSubject(ctx context.Context, issuer string, subject string) (*model.UserIdentity, error)
+	ListByUserID(ctx context.Context, userId uint) ([]model.UserIdentity, error)
+	// gorm.ErrRecordNotFound if user `userId` has no identity `id`
+	Delete(ctx context.Context, userId uint, id uint) error
+}
+
+/* implementation */
+
+type UserIdentityRepository struct {
+	BaseRepository
+}
+
+type UserIdentityRepoDeps struct {
+	BaseDeps *BaseRepoDeps
+}
+
+func NewUserIdentityRepository(deps *UserIdentityRepoDeps) IUserIdentityRepository {
+	return &UserIdentityRepository{
+		BaseRepository: NewBaseRepository(deps.BaseDeps),
+	}
+}
+
+func (r *UserIdentityRepository) Create(ctx context.Context, identity *model.UserIdentity) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserIdentityRepository.Create")
+	defer endSpan()
+	return r.Db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *UserIdentityRepository) CreateWithUser(ctx context.Context, user *model.User, identity *model.UserIdentity) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserIdentityRepository.CreateWithUser")
+	defer endSpan()
+	return r.Db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+func (r *UserIdentityRepository) FindByIssuerSubject(ctx context.Context, issuer string, subject string) (*model.UserIdentity, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserIdentityRepository.FindByIssuerSubject")
+	defer endSpan()
+	var identity model.UserIdentity
+	err := r.Db.WithContext(ctx).
+		First(&identity, "issuer = ? AND subject = ?", issuer, subject).
+		Error
+	return &identity, err
+}
+
+func (r *UserIdentityRepository) ListByUserID(ctx context.Context, userId uint) ([]model.UserIdentity, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserIdentityRepository.ListByUserID")
+	defer endSpan()
+	identities := []model.UserIdentity{}
+	err := r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("id").
+		Find(&identities).
+		Error
+	return identities, err
+}
+
+func (r *UserIdentityRepository) Delete(ctx context.Context, userId uint, id uint) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserIdentityRepository.Delete")
+	defer endSpan()
+	result := r.Db.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Delete(&model.UserIdentity{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestUserIdentityRepository(t *testing.T) {
+	t.Run("Finds identities by issuer and subject", func(t *testing.T) {
+		repo := &UserIdentityRepository{BaseRepository{Db: newTestDb(t)}}
+		identity := &model.UserIdentity{UserID: 1, Issuer: "https://idp.example.com", Subject: "abc"}
+		assert.NoError(t, repo.Create(context.TODO(), identity))
+
+		found, err := repo.FindByIssuerSubject(context.TODO(), "https://idp.example.com", "abc")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), found.UserID)
+		_, err = repo.FindByIssuerSubject(context.TODO(), "https://other.example.com", "abc")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	})
+
+	t.Run("Linking an issuer and subject twice is a duplicate", func(t *testing.T) {
+		repo := &UserIdentityRepository{BaseRepository{Db: newTestDb(t)}}
+		identity := &model.UserIdentity{UserID: 1, Issuer: "https://idp.example.com", Subject: "abc"}
+		assert.NoError(t, repo.Create(context.TODO(), identity))
+
+		err := repo.Create(context.TODO(), &model.UserIdentity{UserID: 2, Issuer: "https://idp.example.com", Subject: "abc"})
+
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+		// the same subject at another issuer is someone else
+		assert.NoError(t, repo.Create(context.TODO(), &model.UserIdentity{UserID: 2, Issuer: "https://other.example.com", Subject: "abc"}))
+	})
+
+	t.Run("CreateWithUser saves both or neither", func(t *testing.T) {
+		db := newTestDb(t)
+		repo := &UserIdentityRepository{BaseRepository{Db: db}}
+		userRepo := &UserRepository{BaseRepository{Db: db}}
+		createTestUsers(t, userRepo, "tim")
+
+		ann := &model.User{Username: "ann"}
+		identity := &model.UserIdentity{Issuer: "https://idp.example.com", Subject: "abc"}
+		assert.NoError(t, repo.CreateWithUser(context.TODO(), ann, identity))
+		assert.Equal(t, ann.ID, identity.UserID)
+
+		// identity linked already
+		err := repo.CreateWithUser(context.TODO(), &model.User{Username: "bob"},
+			&model.UserIdentity{Issuer: "https://idp.example.com", Subject: "abc"})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+		_, err = userRepo.FindByUsername(context.TODO(), "bob")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		// username taken
+		err = repo.CreateWithUser(context.TODO(), &model.User{Username: "tim"},
+			&model.UserIdentity{Issuer: "https://idp.example.com", Subject: "def"})
+		assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+		_, err = repo.FindByIssuerSubject(context.TODO(), "https://idp.example.com", "def")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Users can only delete their own identities", func(t *testing.T) {
+		repo := &UserIdentityRepository{BaseRepository{Db: newTestDb(t)}}
+		identity := &model.UserIdentity{UserID: 1, Issuer: "https://idp.example.com", Subject: "abc"}
+		assert.NoError(t, repo.Create(context.TODO(), identity))
+
+		assert.ErrorIs(t, repo.Delete(context.TODO(), 2, identity.ID), gorm.ErrRecordNotFound)
+		assert.NoError(t, repo.Delete(context.TODO(), 1, identity.ID))
+
+		identities, err := repo.ListByUserID(context.TODO(), 1)
+		assert.NoError(t, err)
+		assert.Empty(t, identities)
+	})
+}
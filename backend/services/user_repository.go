@@ -5,6 +5,7 @@ import (
 	"net-go/server/backend/instrumentation"
 	"net-go/server/backend/model"
 
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -12,12 +13,16 @@ import (
 
 // methods for interacting with the data layer
 type IUserRepository interface {
-	IMigratable
 	// db functionality abstractions
 	FindByID(ctx context.Context, id uint) (*model.User, error)
 	Create(ctx context.Context, u *model.User) error
 	FindByUsername(ctx context.Context, username string) (*model.User, error)
+	// `email` must already be normalized (see model.NormalizeEmail)
+	FindByEmail(ctx context.Context, email string) (*model.User, error)
 	Update(ctx context.Context, user *model.User) error
+	// records `step` as the user's last used TOTP step if it's later than
+	// the stored one; gorm.ErrRecordNotFound if it isn't
+	AdvanceTOTPStep(ctx context.Context, userId uint, step int64) error
 }
 
 /* implementation */
@@ -37,7 +42,7 @@ func NewUserRepository(deps *UserRepoDeps) IUserRepository {
 }
 
 func (u *UserRepository) FindByID(ctx context.Context, id uint) (*model.User, error) {
-	ctx, endSpan := instrumentation.StartDbTrace("UserRepository.FindByID")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserRepository.FindByID")
 	defer endSpan()
 	var user model.User
 	err := u.Db.WithContext(ctx).
@@ -48,7 +53,7 @@ func (u *UserRepository) FindByID(ctx context.Context, id uint) (*model.User, er
 }
 
 func (u *UserRepository) FindByUsername(ctx context.Context, username string) (*model.User, error) {
-	ctx, endSpan := instrumentation.StartDbTrace("UserRepository.FindByUsername")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserRepository.FindByUsername")
 	defer endSpan()
 	var user model.User
 	err := u.Db.WithContext(ctx).
@@ -59,26 +64,44 @@ func (u *UserRepository) FindByUsername(ctx context.Context, username string) (*
 	return &user, err
 }
 
+func (u *UserRepository) FindByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserRepository.FindByEmail")
+	defer endSpan()
+	var user model.User
+	err := u.Db.WithContext(ctx).
+		First(&user, "email = ?", email).
+		Error
+
+	return &user, err
+}
+
 func (u *UserRepository) Create(ctx context.Context, user *model.User) error {
-	ctx, endSpan := instrumentation.StartDbTrace("UserRepository.Create")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserRepository.Create")
 	defer endSpan()
 	err := u.Db.WithContext(ctx).Create(user).Error
 	return err
 }
 
 func (u *UserRepository) Update(ctx context.Context, user *model.User) error {
-	ctx, endSpan := instrumentation.StartDbTrace("UserRepository.Update")
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserRepository.Update")
 	defer endSpan()
 	err := u.Db.WithContext(ctx).Save(user).Error
 	return err
 }
 
-/*
- * Performs necessary migrations on tables correlating to gorm models.
- * Does not delete unused columns; only creates missing columns etc.
- * https://gorm.io/docs/migration.html
- */
-func (u *UserRepository) MigrateAll() error {
-	err := u.Db.AutoMigrate(&model.User{})
-	return err
+func (u *UserRepository) AdvanceTOTPStep(ctx context.Context, userId uint, step int64) error {
+	ctx, endSpan := instrumentation.StartDbTrace(ctx, "UserRepository.AdvanceTOTPStep")
+	defer endSpan()
+	// conditional, so of two requests with the same code only one gets through
+	result := u.Db.WithContext(ctx).
+		Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", userId, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"net-go/server/backend/model"
+)
+
+func TestUserRepositoryIntegration(t *testing.T) {
+	t.Run("Create and find by username", func(t *testing.T) {
+		repo, _ := newTestRepos(t)
+		user := &model.User{Username: "tim", Password: "hashed"}
+
+		assert.NoError(t, repo.Create(context.TODO(), user))
+		found, err := repo.FindByUsername(context.TODO(), "tim")
+
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+		assert.Equal(t, "hashed", found.Password)
+	})
+
+	t.Run("Find by id", func(t *testing.T) {
+		repo, _ := newTestRepos(t)
+		user := &model.User{Username: "tim"}
+		assert.NoError(t, repo.Create(context.TODO(), user))
+
+		found, err := repo.FindByID(context.TODO(), user.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "tim", found.Username)
+		assert.False(t, found.CreatedAt.IsZero())
+	})
+
+	t.Run("Missing users are not found", func(t *testing.T) {
+		repo, _ := newTestRepos(t)
+
+		_, errById := repo.FindByID(context.TODO(), 42)
+		_, errByName := repo.FindByUsername(context.TODO(), "nobody")
+
+		assert.ErrorIs(t, errById, gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, errByName, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Usernames are unique", func(t *testing.T) {
+		repo, _ := newTestRepos(t)
+
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "tim"}))
+		assert.Error(t, repo.Create(context.TODO(), &model.User{Username: "tim"}))
+	})
+
+	t.Run("Update saves changes", func(t *testing.T) {
+		repo, _ := newTestRepos(t)
+		user := &model.User{Username: "tim"}
+		assert.NoError(t, repo.Create(context.TODO(), user))
+
+		user.Password = "abc"
+		assert.NoError(t, repo.Update(context.TODO(), user))
+		found, err := repo.FindByID(context.TODO(), user.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc", found.Password)
+	})
+	t.Run("TOTP steps only advance", func(t *testing.T) {
+		repo, _ := newTestRepos(t)
+		user := &model.User{Username: "tim"}
+		assert.NoError(t, repo.Create(context.TODO(), user))
+
+		assert.NoError(t, repo.AdvanceTOTPStep(context.TODO(), user.ID, 5))
+		assert.ErrorIs(t, repo.AdvanceTOTPStep(context.TODO(), user.ID, 5), gorm.ErrRecordNotFound)
+		assert.ErrorIs(t, repo.AdvanceTOTPStep(context.TODO(), user.ID, 4), gorm.ErrRecordNotFound)
+		assert.NoError(t, repo.AdvanceTOTPStep(context.TODO(), user.ID, 6))
+
+		found, err := repo.FindByID(context.TODO(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(6), found.TOTPLastStep)
+	})
+
+	t.Run("Emails are unique but optional", func(t *testing.T) {
+		repo, _ := newTestRepos(t)
+		email := "tim@example.com"
+
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "tim", Email: &email}))
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "tom"}))
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "bob"}))
+		assert.Error(t, repo.Create(context.TODO(), &model.User{Username: "ann", Email: &email}))
+
+		found, err := repo.FindByEmail(context.TODO(), email)
+		assert.NoError(t, err)
+		assert.Equal(t, "tim", found.Username)
+	})
+}
@@ -2,40 +2,76 @@ package services
 
 import (
 	"context"
-	"github.com/google/uuid"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 	"net-go/server/backend/apperrors"
 	"net-go/server/backend/logger"
+	"net-go/server/backend/mailer"
 	"net-go/server/backend/model"
+	"net-go/server/backend/ratelimit"
 	"strconv"
+	"strings"
+	"time"
 )
 
 /* interfaces */
 
 // methods the router handler layer interacts with
 type IUserService interface {
-	IMigratable
 	Get(ctx context.Context, id uint) (*model.User, error)
 	FindByUsername(ctx context.Context, username string) (*model.User, error)
 	Signup(ctx context.Context, username string, password string) (*model.User, error)
 	Signin(ctx context.Context, username string, password string) (*model.User, error)
-	UpdateSessionToken(ctx context.Context, user *model.User) error
 	Update(ctx context.Context, user *model.User) error
+	// set a new password for user `userId` after checking their current one
+	ChangePassword(ctx context.Context, userId uint, currentPassword string, newPassword string) error
+	// email `username` a link to reset their password, if they exist
+	RequestPasswordReset(ctx context.Context, username string) error
+	// set a new password using a token from RequestPasswordReset
+	ResetPassword(ctx context.Context, token string, newPassword string) (*model.User, error)
+	// email `email` a link to make it the address of user `userId`
+	RequestEmailVerification(ctx context.Context, userId uint, email string) error
+	// set the address a token from RequestEmailVerification was sent to as its user's
+	VerifyEmail(ctx context.Context, token string) (*model.User, error)
+	RemoveEmail(ctx context.Context, userId uint) error
 }
 
 /* implementation */
 
 type UserService struct {
-	userRepository IUserRepository
+	userRepository              IUserRepository
+	signinLockout               ratelimit.Lockout
+	passwordResetRepository     IPasswordResetRepository
+	emailVerificationRepository IEmailVerificationRepository
+	mailer                      mailer.Mailer
+	publicURL                   string
+	now                         func() time.Time
 }
 
 // injectable deps
 type UserServiceDeps struct {
 	UserRepository IUserRepository
+	// locks usernames out of signin after repeated failures; optional
+	SigninLockout               ratelimit.Lockout
+	PasswordResetRepository     IPasswordResetRepository
+	EmailVerificationRepository IEmailVerificationRepository
+	// delivers password reset and email verification links
+	Mailer mailer.Mailer
+	// where the app is served, for links in emails, e.g. https://playonlinego.xyz
+	PublicURL string
 }
 
 func NewUserService(d UserServiceDeps) IUserService {
 	return &UserService{
-		userRepository: d.UserRepository,
+		userRepository:              d.UserRepository,
+		signinLockout:               d.SigninLockout,
+		passwordResetRepository:     d.PasswordResetRepository,
+		emailVerificationRepository: d.EmailVerificationRepository,
+		mailer:                      d.Mailer,
+		publicURL:                   strings.TrimSuffix(d.PublicURL, "/"),
+		now:                         time.Now,
 	}
 }
 
@@ -52,7 +88,7 @@ func (s *UserService) Get(ctx context.Context, id uint) (*model.User, error) {
 func (s *UserService) Signup(ctx context.Context, username string, password string) (*model.User, error) {
 	hashedPassword, err := hashPassword(password)
 	if err != nil {
-		logger.Debug("Unable to signup user for username: %v", username)
+		logger.DebugCtx(ctx, "Unable to hash password for signup", zap.String("username", username), zap.Error(err))
 		return nil, apperrors.NewInternal()
 	}
 
@@ -62,7 +98,7 @@ func (s *UserService) Signup(ctx context.Context, username string, password stri
 		Password: hashedPassword,
 	}
 	if err := s.userRepository.Create(ctx, u); err != nil {
-		logger.Debug("User signup creation error: %v", err)
+		logger.DebugCtx(ctx, "User signup creation error", zap.Error(err))
 		return nil, apperrors.NewConflict("Username", username)
 	}
 
@@ -70,26 +106,104 @@ func (s *UserService) Signup(ctx context.Context, username string, password stri
 }
 
 // fetch user from db, if present
-// always returns 404 err on any failure for secrecy
+// always returns 404 err on any failure for secrecy,
+// or 429 while the username is locked out after repeated failures
 func (s *UserService) Signin(ctx context.Context, username string, password string) (*model.User, error) {
+	if err := s.checkSigninLockout(ctx, username); err != nil {
+		return nil, err
+	}
+
 	user, err := s.FindByUsername(ctx, username)
 	if err != nil {
-		return user, err
+		return user, s.failSignin(ctx, username, err)
 	}
 
 	matching, err := comparePasswords(user.Password, password)
 	if err != nil {
-		logger.Warn("Error comparing passwords: %v", err)
+		logger.WarnCtx(ctx, "Error comparing passwords", zap.Error(err))
 	}
 	if !matching || err != nil {
 		// wrong password, but return 404 for security
-		return user, apperrors.NewNotFound("User", username)
+		return user, s.failSignin(ctx, username, apperrors.NewNotFound("User", username))
 	}
 
 	// successful signin
+	if s.signinLockout != nil {
+		if err := s.signinLockout.Reset(ctx, signinLockoutKey(username)); err != nil {
+			logger.WarnCtx(ctx, "Unable to reset signin lockout", zap.Error(err))
+		}
+	}
+	if passwordNeedsRehash(user.Password) {
+		s.rehashPassword(ctx, user, password)
+	}
 	return user, nil
 }
 
+/**
+ * Stores `password` (just checked against the stored hash) hashed with
+ * the current algorithm and parameters. Best effort: on failure, the old
+ * hash keeps working and this is tried again next signin.
+ */
+func (s *UserService) rehashPassword(ctx context.Context, user *model.User, password string) {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		logger.WarnCtx(ctx, "Unable to rehash password", zap.Error(err))
+		return
+	}
+	previous := user.Password
+	user.Password = hashedPassword
+	if err := s.userRepository.Update(ctx, user); err != nil {
+		logger.WarnCtx(ctx, "Unable to save rehashed password", zap.Error(err))
+		user.Password = previous
+		return
+	}
+	logger.InfoCtx(ctx, "Rehashed user password", zap.Uint("user_id", user.ID))
+}
+
+// case insensitive, since mysql matches usernames regardless of case
+func signinLockoutKey(username string) string {
+	return strings.ToLower(username)
+}
+
+// 429 error if `username` is locked out of signin
+func (s *UserService) checkSigninLockout(ctx context.Context, username string) error {
+	if s.signinLockout == nil {
+		return nil
+	}
+	lockedFor, err := s.signinLockout.Check(ctx, signinLockoutKey(username))
+	if err != nil {
+		// fail open; a broken lockout store shouldn't stop everyone signing in
+		logger.WarnCtx(ctx, "Unable to check signin lockout", zap.Error(err))
+		return nil
+	}
+	if lockedFor > 0 {
+		logger.DebugCtx(ctx, "Rejected signin of locked out user", zap.String("username", username))
+		return apperrors.NewTooManyRequests(lockedFor)
+	}
+	return nil
+}
+
+/**
+ * Records a failed signin of `username`, returning `err`, or a 429
+ * error instead if this failure got the username locked out.
+ */
+func (s *UserService) failSignin(ctx context.Context, username string, err error) error {
+	if s.signinLockout == nil {
+		return err
+	}
+	lockedFor, lockoutErr := s.signinLockout.RecordFailure(ctx, signinLockoutKey(username))
+	if lockoutErr != nil {
+		logger.WarnCtx(ctx, "Unable to record failed signin", zap.Error(lockoutErr))
+		return err
+	}
+	if lockedFor > 0 {
+		logger.InfoCtx(ctx, "Locked out user after repeated failed signins",
+			zap.String("username", username), zap.Duration("locked_for", lockedFor))
+		return apperrors.NewTooManyRequests(lockedFor)
+	}
+	return err
+}
+
 func (s *UserService) FindByUsername(ctx context.Context, username string) (*model.User, error) {
 	user, err := s.userRepository.FindByUsername(ctx, username)
 	if err != nil {
@@ -98,20 +212,265 @@ func (s *UserService) FindByUsername(ctx context.Context, username string) (*mod
 	return user, nil
 }
 
-func (s *UserService) UpdateSessionToken(ctx context.Context, user *model.User) error {
-	sessToken := uuid.New().String()
-	user.SessionToken = sessToken
-	return s.Update(ctx, user)
-}
-
 func (s *UserService) Update(ctx context.Context, user *model.User) error {
 	if err := s.userRepository.Update(ctx, user); err != nil {
-		logger.Error("Unable to update user: %v", err)
+		logger.ErrorCtx(ctx, "Unable to update user", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	return nil
+}
+
+func (s *UserService) ChangePassword(ctx context.Context, userId uint, currentPassword string, newPassword string) error {
+	user, err := s.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user.Password == "" {
+		return apperrors.NewBadRequest("This account has no password to change")
+	}
+
+	matching, err := comparePasswords(user.Password, currentPassword)
+	if err != nil {
+		logger.WarnCtx(ctx, "Error comparing passwords", zap.Error(err))
 		return apperrors.NewInternal()
 	}
+	if !matching {
+		return apperrors.NewBadRequest("Current password is incorrect")
+	}
+
+	return s.setPassword(ctx, user, newPassword)
+}
+
+/**
+ * The link goes to the user's verified email; users without one can't
+ * reset their password. Always succeeds for unknown usernames too, so
+ * this can't be used to find out who has an account.
+ */
+func (s *UserService) RequestPasswordReset(ctx context.Context, username string) error {
+	user, err := s.userRepository.FindByUsername(ctx, username)
+	if err != nil {
+		logger.DebugCtx(ctx, "Password reset requested for unknown user", zap.String("username", username))
+		return nil
+	}
+	if user.Email == nil || user.EmailVerifiedAt == nil {
+		logger.DebugCtx(ctx, "Password reset requested for user without a verified email", zap.Uint("user_id", user.ID))
+		return nil
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to generate password reset token", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	now := s.now()
+	reset := &model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: model.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(model.PasswordResetTokenTTL),
+	}
+	if err := s.passwordResetRepository.Create(ctx, reset); err != nil {
+		logger.ErrorCtx(ctx, "Unable to save password reset token", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+
+	msg := mailer.Message{
+		To:      *user.Email,
+		Subject: "Reset your net-go password",
+		Body: fmt.Sprintf(
+			"Someone asked to reset the password of your net-go account, %s.\n\n"+
+				"To choose a new password, go to %s/reset-password?token=%s\n\n"+
+				"The link works once, for the next %s. If you didn't ask for this, you can ignore this email.",
+			user.Username, s.publicURL, token, model.PasswordResetTokenTTL),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		// the response mustn't differ from that for unknown users
+		logger.ErrorCtx(ctx, "Unable to send password reset email", zap.Uint("user_id", user.ID), zap.Error(err))
+		return nil
+	}
+	logger.InfoCtx(ctx, "Sent password reset email", zap.Uint("user_id", user.ID))
 	return nil
 }
 
-func (s *UserService) MigrateAll() error {
-	return s.userRepository.MigrateAll()
+func (s *UserService) ResetPassword(ctx context.Context, token string, newPassword string) (*model.User, error) {
+	invalidErr := apperrors.NewBadRequest("Password reset link is invalid or has expired")
+
+	reset, err := s.passwordResetRepository.FindByTokenHash(ctx, model.HashToken(token))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to look up password reset token", zap.Error(err))
+			return nil, apperrors.NewInternal()
+		}
+		return nil, invalidErr
+	}
+	now := s.now()
+	if !reset.IsUsable(now) {
+		return nil, invalidErr
+	}
+	// claim the token before using it, so it only ever works once
+	if err := s.passwordResetRepository.MarkUsed(ctx, reset.ID, now); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to use password reset token", zap.Error(err))
+			return nil, apperrors.NewInternal()
+		}
+		return nil, invalidErr
+	}
+
+	user, err := s.Get(ctx, reset.UserID)
+	if err != nil {
+		return nil, invalidErr
+	}
+	if err := s.setPassword(ctx, user, newPassword); err != nil {
+		return nil, err
+	}
+	logger.InfoCtx(ctx, "Reset user password", zap.Uint("user_id", user.ID))
+	return user, nil
+}
+
+// save `password` as the password of `user`, voiding any outstanding reset links
+func (s *UserService) setPassword(ctx context.Context, user *model.User, password string) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to hash password", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	user.Password = hashedPassword
+	if err := s.Update(ctx, user); err != nil {
+		return err
+	}
+
+	if err := s.passwordResetRepository.DeleteByUserID(ctx, user.ID); err != nil {
+		logger.WarnCtx(ctx, "Unable to delete password reset tokens", zap.Error(err))
+	}
+	return nil
+}
+
+func (s *UserService) RequestEmailVerification(ctx context.Context, userId uint, email string) error {
+	email = model.NormalizeEmail(email)
+	user, err := s.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user.Email != nil && *user.Email == email {
+		return apperrors.NewBadRequest("That email is already verified")
+	}
+	if err := s.checkEmailAvailable(ctx, user.ID, email); err != nil {
+		return err
+	}
+
+	token, err := newRandomToken()
+	if err != nil {
+		logger.ErrorCtx(ctx, "Unable to generate email verification token", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	now := s.now()
+	verification := &model.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: model.HashToken(token),
+		CreatedAt: now,
+		ExpiresAt: now.Add(model.EmailVerificationTokenTTL),
+	}
+	if err := s.emailVerificationRepository.Create(ctx, verification); err != nil {
+		logger.ErrorCtx(ctx, "Unable to save email verification token", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Verify your net-go email",
+		Body: fmt.Sprintf(
+			"To use this address for your net-go account, %s, go to %s/verify-email?token=%s\n\n"+
+				"The link works for the next %s. If you didn't ask for this, you can ignore this email.",
+			user.Username, s.publicURL, token, model.EmailVerificationTokenTTL),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		logger.ErrorCtx(ctx, "Unable to send email verification", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+
+	// a heads up at the current address, in case someone else is changing it
+	if user.Email != nil {
+		notice := mailer.Message{
+			To:      *user.Email,
+			Subject: "Your net-go email is being changed",
+			Body: fmt.Sprintf(
+				"Someone asked to change the email of your net-go account, %s, to %s. "+
+					"It changes once the new address is verified. If this wasn't you, change your password.",
+				user.Username, email),
+		}
+		if err := s.mailer.Send(ctx, notice); err != nil {
+			logger.WarnCtx(ctx, "Unable to send email change notice", zap.Error(err))
+		}
+	}
+	logger.InfoCtx(ctx, "Sent email verification", zap.Uint("user_id", user.ID))
+	return nil
+}
+
+func (s *UserService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	invalidErr := apperrors.NewBadRequest("Email verification link is invalid or has expired")
+
+	verification, err := s.emailVerificationRepository.FindByTokenHash(ctx, model.HashToken(token))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to look up email verification token", zap.Error(err))
+			return nil, apperrors.NewInternal()
+		}
+		return nil, invalidErr
+	}
+	now := s.now()
+	if !verification.IsUsable(now) {
+		return nil, invalidErr
+	}
+
+	user, err := s.Get(ctx, verification.UserID)
+	if err != nil {
+		return nil, invalidErr
+	}
+	// someone else may have verified it since the link was sent
+	if err := s.checkEmailAvailable(ctx, user.ID, verification.Email); err != nil {
+		return nil, err
+	}
+	if err := s.emailVerificationRepository.MarkUsed(ctx, verification.ID, now); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.ErrorCtx(ctx, "Unable to use email verification token", zap.Error(err))
+			return nil, apperrors.NewInternal()
+		}
+		return nil, invalidErr
+	}
+
+	user.Email = &verification.Email
+	user.EmailVerifiedAt = &now
+	if err := s.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	// links to any other addresses the user tried are void now
+	if err := s.emailVerificationRepository.DeleteByUserID(ctx, user.ID); err != nil {
+		logger.WarnCtx(ctx, "Unable to delete email verification tokens", zap.Error(err))
+	}
+	logger.InfoCtx(ctx, "Verified user email", zap.Uint("user_id", user.ID))
+	return user, nil
+}
+
+func (s *UserService) RemoveEmail(ctx context.Context, userId uint) error {
+	user, err := s.Get(ctx, userId)
+	if err != nil {
+		return err
+	}
+	user.Email = nil
+	user.EmailVerifiedAt = nil
+	return s.Update(ctx, user)
+}
+
+// 409 error if a user other than `userId` has `email`
+func (s *UserService) checkEmailAvailable(ctx context.Context, userId uint, email string) error {
+	other, err := s.userRepository.FindByEmail(ctx, email)
+	if err == nil && other.ID != userId {
+		return apperrors.NewConflict("Email", email)
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.ErrorCtx(ctx, "Unable to look up user by email", zap.Error(err))
+		return apperrors.NewInternal()
+	}
+	return nil
 }
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"net-go/server/backend/apperrors"
+	"net-go/server/backend/mailer"
 	"net-go/server/backend/model"
+	"net-go/server/backend/ratelimit"
 	"net-go/server/backend/services/mocks"
 )
 
@@ -134,6 +139,10 @@ func TestUserServiceSignin(t *testing.T) {
 		mockUserRepository.
 			On("FindByUsername", mock.AnythingOfType("context.todoCtx"), mockUser.Username).
 			Return(mockUser, nil)
+		// the password is a legacy scrypt hash, so it's upgraded
+		mockUserRepository.
+			On("Update", mock.AnythingOfType("context.todoCtx"), mock.AnythingOfType("*model.User")).
+			Return(nil)
 
 		ctx := context.TODO()
 		actualUser, err := userService.Signin(ctx, mockUser.Username, rawPassword)
@@ -141,6 +150,7 @@ func TestUserServiceSignin(t *testing.T) {
 		assert.NoError(t, err)
 
 		assert.Equal(t, uid, actualUser.ID)
+		assert.True(t, strings.HasPrefix(actualUser.Password, "$argon2id$"))
 
 		mockUserRepository.AssertExpectations(t)
 	})
@@ -195,4 +205,362 @@ func TestUserServiceSignin(t *testing.T) {
 
 		mockUserRepository.AssertExpectations(t)
 	})
+
+	t.Run("repeated failures lock the username out", func(t *testing.T) {
+		mockUser := &model.User{
+			Username: "tim",
+			Password: "87bf38a508832455cd6aea07a1f57e787b30c90f716212a483cca7d7f414d596.4b299672c289b4e05ecf9e8bb96e870c1615807fcbc446d84f9fb73c8f82f8a3",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := NewUserService(UserServiceDeps{
+			UserRepository: mockUserRepository,
+			SigninLockout: ratelimit.NewMemoryLockout(ratelimit.MemoryLockoutConfig{
+				Threshold:     2,
+				BaseLockout:   time.Minute,
+				MaxLockout:    time.Hour,
+				FailureWindow: time.Hour,
+			}),
+		})
+
+		mockUserRepository.
+			On("FindByUsername", mock.AnythingOfType("context.todoCtx"), mockUser.Username).
+			Return(mockUser, nil)
+
+		ctx := context.TODO()
+		_, firstErr := userService.Signin(ctx, mockUser.Username, "incorrect_password")
+		_, secondErr := userService.Signin(ctx, mockUser.Username, "incorrect_password")
+		// even the right password is rejected while locked out
+		_, lockedErr := userService.Signin(ctx, mockUser.Username, "password")
+
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(firstErr))
+		assert.Equal(t, http.StatusTooManyRequests, apperrors.Status(secondErr))
+		assert.Equal(t, http.StatusTooManyRequests, apperrors.Status(lockedErr))
+		assert.Equal(t, "60", apperrors.RetryAfterHeader(lockedErr))
+		// the locked out attempt doesn't reach the db
+		mockUserRepository.AssertNumberOfCalls(t, "FindByUsername", 2)
+	})
+
+	t.Run("successful signin resets failures", func(t *testing.T) {
+		mockUser := &model.User{
+			Username: "tim",
+			Password: "87bf38a508832455cd6aea07a1f57e787b30c90f716212a483cca7d7f414d596.4b299672c289b4e05ecf9e8bb96e870c1615807fcbc446d84f9fb73c8f82f8a3",
+		}
+
+		mockUserRepository := new(mocks.MockUserRepository)
+		userService := NewUserService(UserServiceDeps{
+			UserRepository: mockUserRepository,
+			SigninLockout: ratelimit.NewMemoryLockout(ratelimit.MemoryLockoutConfig{
+				Threshold:     2,
+				BaseLockout:   time.Minute,
+				MaxLockout:    time.Hour,
+				FailureWindow: time.Hour,
+			}),
+		})
+
+		mockUserRepository.
+			On("FindByUsername", mock.AnythingOfType("context.todoCtx"), mockUser.Username).
+			Return(mockUser, nil)
+		// the legacy scrypt hash is upgraded on success
+		mockUserRepository.
+			On("Update", mock.AnythingOfType("context.todoCtx"), mock.AnythingOfType("*model.User")).
+			Return(nil)
+
+		ctx := context.TODO()
+		_, firstErr := userService.Signin(ctx, mockUser.Username, "incorrect_password")
+		_, successErr := userService.Signin(ctx, mockUser.Username, "password")
+		_, secondErr := userService.Signin(ctx, mockUser.Username, "incorrect_password")
+
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(firstErr))
+		assert.NoError(t, successErr)
+		assert.Equal(t, http.StatusNotFound, apperrors.Status(secondErr))
+	})
+}
+
+func TestUserServiceSigninRehash(t *testing.T) {
+	t.Run("Legacy password hashes are upgraded on signin", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+		s := NewUserService(UserServiceDeps{UserRepository: repo})
+		legacy := legacyScryptHash(t, "password1")
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "tim", Password: legacy}))
+
+		_, err := s.Signin(context.TODO(), "tim", "password1")
+		assert.NoError(t, err)
+
+		stored, _ := repo.FindByUsername(context.TODO(), "tim")
+		assert.True(t, strings.HasPrefix(stored.Password, "$argon2id$"))
+		_, err = s.Signin(context.TODO(), "tim", "password1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Failed signins leave the hash alone", func(t *testing.T) {
+		repo := NewMemoryUserRepository()
+		s := NewUserService(UserServiceDeps{UserRepository: repo})
+		legacy := legacyScryptHash(t, "password1")
+		assert.NoError(t, repo.Create(context.TODO(), &model.User{Username: "tim", Password: legacy}))
+
+		_, err := s.Signin(context.TODO(), "tim", "password2")
+		assert.Error(t, err)
+
+		stored, _ := repo.FindByUsername(context.TODO(), "tim")
+		assert.Equal(t, legacy, stored.Password)
+	})
+}
+
+// mailer keeping what it sent, for tests to read back
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// the token in the link of password reset or email verification email `msg`
+func tokenFromEmail(t *testing.T, msg mailer.Message) string {
+	t.Helper()
+	_, token, found := strings.Cut(msg.Body, "token=")
+	if !found {
+		t.Fatalf("no token link in %q", msg.Body)
+	}
+	return strings.Fields(token)[0]
+}
+
+// user service on in memory repos, with user "tim" (password "password1")
+// whose verified email is tim@example.com
+func newPasswordTestUserService(t *testing.T) (*UserService, *recordingMailer, *model.User) {
+	t.Helper()
+	m := &recordingMailer{}
+	s := NewUserService(UserServiceDeps{
+		UserRepository:              NewMemoryUserRepository(),
+		PasswordResetRepository:     NewMemoryPasswordResetRepository(),
+		EmailVerificationRepository: NewMemoryEmailVerificationRepository(),
+		Mailer:                      m,
+		PublicURL:                   "https://example.com/",
+	}).(*UserService)
+	user, err := s.Signup(context.TODO(), "tim", "password1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, verifiedAt := "tim@example.com", time.Now()
+	user.Email, user.EmailVerifiedAt = &email, &verifiedAt
+	if err := s.Update(context.TODO(), user); err != nil {
+		t.Fatal(err)
+	}
+	return s, m, user
+}
+
+func TestUserServiceChangePassword(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		s, _, user := newPasswordTestUserService(t)
+
+		err := s.ChangePassword(context.TODO(), user.ID, "password1", "password2")
+
+		assert.NoError(t, err)
+		_, err = s.Signin(context.TODO(), "tim", "password1")
+		assert.Error(t, err)
+		_, err = s.Signin(context.TODO(), "tim", "password2")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		s, _, user := newPasswordTestUserService(t)
+
+		err := s.ChangePassword(context.TODO(), user.ID, "not-my-password", "password2")
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		_, err = s.Signin(context.TODO(), "tim", "password1")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Users signing in through an identity provider have no password to change", func(t *testing.T) {
+		s, _, _ := newPasswordTestUserService(t)
+		user := &model.User{Username: "oidc-tim"}
+		assert.NoError(t, s.userRepository.Create(context.TODO(), user))
+
+		err := s.ChangePassword(context.TODO(), user.ID, "", "password2")
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		_, err = s.Signin(context.TODO(), "oidc-tim", "")
+		assert.Error(t, err)
+	})
+
+	t.Run("Voids outstanding reset links", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+		assert.NoError(t, s.RequestPasswordReset(context.TODO(), "tim"))
+
+		assert.NoError(t, s.ChangePassword(context.TODO(), user.ID, "password1", "password2"))
+
+		_, err := s.ResetPassword(context.TODO(), tokenFromEmail(t, m.sent[0]), "password3")
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+}
+
+func TestUserServicePasswordReset(t *testing.T) {
+	t.Run("Emails a link that resets the password once", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+
+		assert.NoError(t, s.RequestPasswordReset(context.TODO(), "tim"))
+		assert.Len(t, m.sent, 1)
+		assert.Equal(t, "tim@example.com", m.sent[0].To)
+		assert.Contains(t, m.sent[0].Body, "https://example.com/reset-password?token=")
+		token := tokenFromEmail(t, m.sent[0])
+
+		reset, err := s.ResetPassword(context.TODO(), token, "password2")
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, reset.ID)
+		_, err = s.Signin(context.TODO(), "tim", "password2")
+		assert.NoError(t, err)
+
+		_, err = s.ResetPassword(context.TODO(), token, "password3")
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Unknown users get no email, but the same response", func(t *testing.T) {
+		s, m, _ := newPasswordTestUserService(t)
+
+		err := s.RequestPasswordReset(context.TODO(), "nobody")
+
+		assert.NoError(t, err)
+		assert.Empty(t, m.sent)
+	})
+
+	t.Run("Users without an email get no email, but the same response", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+		assert.NoError(t, s.RemoveEmail(context.TODO(), user.ID))
+
+		err := s.RequestPasswordReset(context.TODO(), "tim")
+
+		assert.NoError(t, err)
+		assert.Empty(t, m.sent)
+	})
+
+	t.Run("Goes to the verified email, never the username", func(t *testing.T) {
+		s, m, _ := newPasswordTestUserService(t)
+		user, err := s.Signup(context.TODO(), "tom@example.org", "password1")
+		assert.NoError(t, err)
+		email, verifiedAt := "tom@example.com", time.Now()
+		user.Email, user.EmailVerifiedAt = &email, &verifiedAt
+		assert.NoError(t, s.Update(context.TODO(), user))
+
+		assert.NoError(t, s.RequestPasswordReset(context.TODO(), "tom@example.org"))
+
+		assert.Len(t, m.sent, 1)
+		assert.Equal(t, "tom@example.com", m.sent[0].To)
+	})
+
+	t.Run("Users with an unverified email get no email", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+		user.EmailVerifiedAt = nil
+		assert.NoError(t, s.Update(context.TODO(), user))
+
+		err := s.RequestPasswordReset(context.TODO(), "tim")
+
+		assert.NoError(t, err)
+		assert.Empty(t, m.sent)
+	})
+
+	t.Run("Links expire", func(t *testing.T) {
+		s, m, _ := newPasswordTestUserService(t)
+		assert.NoError(t, s.RequestPasswordReset(context.TODO(), "tim"))
+
+		later := time.Now().Add(model.PasswordResetTokenTTL + time.Minute)
+		s.now = func() time.Time { return later }
+		_, err := s.ResetPassword(context.TODO(), tokenFromEmail(t, m.sent[0]), "password2")
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Unknown tokens are rejected", func(t *testing.T) {
+		s, _, _ := newPasswordTestUserService(t)
+
+		_, err := s.ResetPassword(context.TODO(), "made-up", "password2")
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+}
+
+func TestUserServiceEmailVerification(t *testing.T) {
+	t.Run("Emails a link that sets the email once", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+		assert.NoError(t, s.RemoveEmail(context.TODO(), user.ID))
+
+		err := s.RequestEmailVerification(context.TODO(), user.ID, " Tim@Example.org ")
+
+		assert.NoError(t, err)
+		assert.Len(t, m.sent, 1)
+		assert.Equal(t, "tim@example.org", m.sent[0].To)
+		assert.Contains(t, m.sent[0].Body, "https://example.com/verify-email?token=")
+		// not set until verified
+		got, _ := s.Get(context.TODO(), user.ID)
+		assert.Nil(t, got.Email)
+
+		token := tokenFromEmail(t, m.sent[0])
+		verified, err := s.VerifyEmail(context.TODO(), token)
+		assert.NoError(t, err)
+		assert.Equal(t, "tim@example.org", *verified.Email)
+		assert.NotNil(t, verified.EmailVerifiedAt)
+
+		_, err = s.VerifyEmail(context.TODO(), token)
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
+
+	t.Run("Changing the email notifies the current one", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+
+		assert.NoError(t, s.RequestEmailVerification(context.TODO(), user.ID, "tim@example.org"))
+
+		assert.Len(t, m.sent, 2)
+		assert.Equal(t, "tim@example.org", m.sent[0].To)
+		assert.Equal(t, "tim@example.com", m.sent[1].To)
+		got, _ := s.Get(context.TODO(), user.ID)
+		assert.Equal(t, "tim@example.com", *got.Email)
+	})
+
+	t.Run("Already verified", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+
+		err := s.RequestEmailVerification(context.TODO(), user.ID, "TIM@example.com")
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+		assert.Empty(t, m.sent)
+	})
+
+	t.Run("Email used by another user", func(t *testing.T) {
+		s, m, _ := newPasswordTestUserService(t)
+		other, err := s.Signup(context.TODO(), "tom", "password1")
+		assert.NoError(t, err)
+
+		err = s.RequestEmailVerification(context.TODO(), other.ID, "tim@example.com")
+
+		assert.Equal(t, http.StatusConflict, apperrors.Status(err))
+		assert.Empty(t, m.sent)
+	})
+
+	t.Run("Email taken before the link is used", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+		assert.NoError(t, s.RemoveEmail(context.TODO(), user.ID))
+		other, err := s.Signup(context.TODO(), "tom", "password1")
+		assert.NoError(t, err)
+		assert.NoError(t, s.RequestEmailVerification(context.TODO(), user.ID, "shared@example.com"))
+		assert.NoError(t, s.RequestEmailVerification(context.TODO(), other.ID, "shared@example.com"))
+
+		_, err = s.VerifyEmail(context.TODO(), tokenFromEmail(t, m.sent[1]))
+		assert.NoError(t, err)
+		_, err = s.VerifyEmail(context.TODO(), tokenFromEmail(t, m.sent[0]))
+
+		assert.Equal(t, http.StatusConflict, apperrors.Status(err))
+	})
+
+	t.Run("Links expire", func(t *testing.T) {
+		s, m, user := newPasswordTestUserService(t)
+		assert.NoError(t, s.RequestEmailVerification(context.TODO(), user.ID, "tim@example.org"))
+
+		later := time.Now().Add(model.EmailVerificationTokenTTL + time.Minute)
+		s.now = func() time.Time { return later }
+		_, err := s.VerifyEmail(context.TODO(), tokenFromEmail(t, m.sent[0]))
+
+		assert.Equal(t, http.StatusBadRequest, apperrors.Status(err))
+	})
 }
@@ -2,7 +2,107 @@ package subscriptions
 
 import (
 	"net-go/server/backend/model"
+	"sync"
 )
 
-type GameListener = chan model.Game
-type GameSubscriptions = map[uint]GameListener
+type GameListener = <-chan model.Game
+
+/**
+ * Hands game updates to the long-poll requests waiting on them.
+ * Safe for concurrent use.
+ */
+type Hub struct {
+	mu        sync.Mutex
+	listeners map[uint]map[chan model.Game]struct{} // by game id
+	closed    bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		listeners: make(map[uint]map[chan model.Game]struct{}),
+	}
+}
+
+/**
+ * Listens for the next update to game `gameId`. The listener receives
+ * at most one game, and is closed without one if the hub shuts down.
+ * Call `unsubscribe` once done waiting.
+ */
+func (h *Hub) Subscribe(gameId uint) (listener GameListener, unsubscribe func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// buffered so Publish never blocks on a slow or departed listener
+	ch := make(chan model.Game, 1)
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if h.listeners[gameId] == nil {
+		h.listeners[gameId] = make(map[chan model.Game]struct{})
+	}
+	h.listeners[gameId][ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(gameId, ch)
+	}
+}
+
+// Sends `game` to everyone listening for updates to game `gameId`.
+func (h *Hub) Publish(gameId uint, game model.Game) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.listeners[gameId] {
+		ch <- game
+		h.remove(gameId, ch)
+	}
+}
+
+// number of requests currently waiting on game updates
+func (h *Hub) ListenerCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	count := 0
+	for _, gameListeners := range h.listeners {
+		count += len(gameListeners)
+	}
+	return count
+}
+
+// whether the hub is accepting subscriptions (i.e. Close hasn't been called)
+func (h *Hub) Running() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.closed
+}
+
+/**
+ * Closes all listeners so waiting long-poll requests can finish,
+ * e.g. on server shutdown. Later subscriptions are closed immediately.
+ */
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for gameId, gameListeners := range h.listeners {
+		for ch := range gameListeners {
+			h.remove(gameId, ch)
+		}
+	}
+}
+
+// closes and forgets `ch`; callers must hold h.mu
+func (h *Hub) remove(gameId uint, ch chan model.Game) {
+	gameListeners := h.listeners[gameId]
+	if _, ok := gameListeners[ch]; !ok {
+		// already removed by Publish or Close
+		return
+	}
+	close(ch)
+	delete(gameListeners, ch)
+	if len(gameListeners) == 0 {
+		delete(h.listeners, gameId)
+	}
+}
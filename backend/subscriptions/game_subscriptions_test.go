@@ -0,0 +1,68 @@
+package subscriptions
+
+import (
+	"net-go/server/backend/model"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	t.Run("publish reaches listeners of that game only", func(t *testing.T) {
+		hub := NewHub()
+		game := model.Game{IsOver: true}
+		first, unsubscribeFirst := hub.Subscribe(1)
+		defer unsubscribeFirst()
+		second, unsubscribeSecond := hub.Subscribe(1)
+		defer unsubscribeSecond()
+		other, unsubscribeOther := hub.Subscribe(2)
+		defer unsubscribeOther()
+		assert.Equal(t, 3, hub.ListenerCount())
+
+		hub.Publish(1, game)
+
+		assert.Equal(t, game, <-first)
+		assert.Equal(t, game, <-second)
+		select {
+		case <-other:
+			t.Fatal("listener of another game was notified")
+		default:
+		}
+		// listeners only receive one update
+		assert.Equal(t, 1, hub.ListenerCount())
+	})
+	t.Run("publish without listeners", func(t *testing.T) {
+		hub := NewHub()
+		hub.Publish(1, model.Game{})
+		assert.Equal(t, 0, hub.ListenerCount())
+	})
+	t.Run("unsubscribe", func(t *testing.T) {
+		hub := NewHub()
+		listener, unsubscribe := hub.Subscribe(1)
+
+		unsubscribe()
+		// safe to call again, e.g. after a publish
+		unsubscribe()
+
+		_, ok := <-listener
+		assert.False(t, ok)
+		assert.Equal(t, 0, hub.ListenerCount())
+	})
+	t.Run("close", func(t *testing.T) {
+		hub := NewHub()
+		listener, unsubscribe := hub.Subscribe(1)
+		defer unsubscribe()
+		assert.True(t, hub.Running())
+
+		hub.Close()
+
+		_, ok := <-listener
+		assert.False(t, ok)
+		assert.False(t, hub.Running())
+		assert.Equal(t, 0, hub.ListenerCount())
+
+		late, _ := hub.Subscribe(1)
+		_, ok = <-late
+		assert.False(t, ok, "subscriptions after close should be closed")
+	})
+}
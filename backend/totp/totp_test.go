@@ -0,0 +1,89 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the SHA1 secret of RFC 6238's test vectors
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	t.Run("Matches the RFC 6238 test vectors", func(t *testing.T) {
+		// the RFC's 8 digit codes, cut to their last 6 digits
+		vectors := map[int64]string{
+			59:          "287082",
+			1111111109:  "081804",
+			1111111111:  "050471",
+			1234567890:  "005924",
+			2000000000:  "279037",
+			20000000000: "353130",
+		}
+		for unix, expected := range vectors {
+			code, err := Code(rfcSecret, Step(time.Unix(unix, 0)))
+			assert.NoError(t, err)
+			assert.Equal(t, expected, code, "at %d", unix)
+		}
+	})
+
+	t.Run("Bad secret", func(t *testing.T) {
+		_, err := Code("not base32!", 1)
+		assert.Error(t, err)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("Accepts the current code and neighbouring periods", func(t *testing.T) {
+		for _, offset := range []time.Duration{-Period, 0, Period} {
+			code, _ := Code(rfcSecret, Step(now.Add(offset)))
+
+			step, ok := Validate(rfcSecret, code, now)
+
+			assert.True(t, ok)
+			assert.Equal(t, Step(now.Add(offset)), step)
+		}
+	})
+
+	t.Run("Rejects older codes", func(t *testing.T) {
+		code, _ := Code(rfcSecret, Step(now.Add(-3*Period)))
+
+		_, ok := Validate(rfcSecret, code, now)
+
+		assert.False(t, ok)
+	})
+
+	t.Run("Ignores spaces but not other junk", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, "050 471", now)
+		assert.True(t, ok)
+		_, ok = Validate(rfcSecret, "50471", now)
+		assert.False(t, ok)
+	})
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.NoError(t, err)
+	other, _ := GenerateSecret()
+
+	assert.Len(t, secret, 32)
+	assert.NotEqual(t, secret, other)
+	_, err = Code(secret, 1)
+	assert.NoError(t, err)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("net-go", "tim smith", "ABC"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/net-go:tim smith", uri.Path)
+	assert.Equal(t, "ABC", uri.Query().Get("secret"))
+	assert.Equal(t, "net-go", uri.Query().Get("issuer"))
+}
@@ -17,8 +17,9 @@ msgType - the Msg type to trigger on completion via Cmd
 -}
 doLogout : (Result Http.Error () -> msg) -> Cmd msg
 doLogout msgType =
-    Http.get
+    Http.post
         { url = prefix ++ "/signout"
+        , body = Http.emptyBody
         , expect = Http.expectWhatever msgType
         }
 
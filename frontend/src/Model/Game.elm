@@ -22,6 +22,9 @@ type alias Game =
     , whitePlayerName : String
     , blackPlayerName : String
     , id : Maybe String
+
+    -- version of the backend game this state is based on; 0 until created
+    , version : Int
     }
 
 
@@ -36,6 +39,7 @@ newGame size color komi blackName whiteName =
     , blackPlayerName = blackName
     , whitePlayerName = whiteName
     , id = Nothing
+    , version = 0
     }
 
 
@@ -199,6 +203,7 @@ gameDecoder =
         |> required "whitePlayerName" string
         |> required "blackPlayerName" string
         |> required "id" (nullable string)
+        |> optional "version" int 0
 
 
 gameEncoder : Game -> Encode.Value
@@ -213,4 +218,5 @@ gameEncoder game =
         , ( "blackPlayerName", Encode.string game.blackPlayerName )
         , ( "whitePlayerName", Encode.string game.whitePlayerName )
         , ( "id", (Maybe.map Encode.string >> Maybe.withDefault Encode.null) game.id )
+        , ( "version", Encode.int game.version )
         ]
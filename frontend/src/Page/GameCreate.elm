@@ -85,6 +85,7 @@ formDataToGame formData userData =
     , whitePlayerName = whitePlayerName
     , blackPlayerName = blackPlayerName
     , id = Nothing -- no db id yet; that will gen on backend
+    , version = 0
     }
 
 
@@ -54,6 +54,7 @@ blackGame =
     , whitePlayerName = "ted"
     , blackPlayerName = "bill"
     , id = Nothing
+    , version = 0
     }
 
 
@@ -71,6 +72,7 @@ whiteGame =
     , whitePlayerName = "ted"
     , blackPlayerName = "bill"
     , id = Nothing
+    , version = 0
     }
 
 
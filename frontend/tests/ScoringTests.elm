@@ -162,6 +162,7 @@ game =
     , whitePlayerName = "ted"
     , blackPlayerName = "bill"
     , id = Nothing
+    , version = 0
     }
 
 suite : Test
@@ -2,16 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net-go/server/backend/constants"
 	"net-go/server/backend/handler/provider"
 	"net-go/server/backend/handler/router"
+	"net-go/server/backend/health"
+	"net-go/server/backend/identity"
+	"net-go/server/backend/instrumentation"
+	applogger "net-go/server/backend/logger"
+	"net-go/server/backend/mailer"
+	"net-go/server/backend/migrations"
+	"net-go/server/backend/ratelimit"
 	"net-go/server/backend/services"
 	"net-go/server/backend/subscriptions"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -20,7 +30,9 @@ import (
 	"github.com/hyperdxio/opentelemetry-logs-go/exporters/otlp/otlplogs"
 	sdk "github.com/hyperdxio/opentelemetry-logs-go/sdk/logs"
 	"github.com/hyperdxio/otel-config-go/otelconfig"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"gorm.io/gorm"
 )
 
@@ -43,12 +55,25 @@ func buildLogger() func() {
 	)
 
 	// create new logger with opentelemetry zap core and set it globally
-	var logger *zap.Logger
+	var core zapcore.Core
+	options := []zap.Option{zap.AddCallerSkip(1)}
 	if constants.GetDevMode() {
-		logger = zap.Must(zap.NewDevelopment(zap.Development(), zap.AddCallerSkip(1)))
+		encoder := zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+		core = zapcore.NewCore(encoder, zapcore.Lock(os.Stderr), zapcore.DebugLevel)
+		options = append(options, zap.Development(), zap.AddCaller(), zap.AddStacktrace(zapcore.WarnLevel))
 	} else {
-		logger = zap.New(otelzap.NewOtelCore(loggerProvider), zap.AddCallerSkip(1))
+		core = otelzap.NewOtelCore(loggerProvider)
 	}
+	level, err := zapcore.ParseLevel(constants.GetLogLevel())
+	if err != nil {
+		log.Fatalf("Invalid LOG_LEVEL: %v", err)
+	}
+	applogger.SetLevel(level)
+	logger := applogger.New(core, applogger.Sampling{
+		Initial:    constants.GetLogSamplingInitial(),
+		Thereafter: constants.GetLogSamplingThereafter(),
+		Tick:       time.Second,
+	}, options...)
 	zap.ReplaceGlobals(logger)
 
 	// gracefully shutdown logger to flush accumulated signals before program finish
@@ -58,59 +83,480 @@ func buildLogger() func() {
 	}
 }
 
-func buildProvider() provider.Provider {
-	dbStr := fmt.Sprintf(
-		"%s:%s@tcp(%s:3306)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		constants.GetDatabaseUserUsername(),
-		constants.GetDatabaseUserPassword(),
-		constants.GetDatabaseHost(),
-		constants.GetDatabaseName(),
-	)
-	baseRepoDeps := &services.BaseRepoDeps{
-		DbString: dbStr,
+// builds the db connection string for the configured DB_DRIVER
+func buildDbString(driver string) string {
+	var info services.DbConnectionInfo
+	if driver == services.SQLiteDriver {
+		info.Name = constants.GetDatabasePath()
+	} else {
+		info = services.DbConnectionInfo{
+			User:     constants.GetDatabaseUserUsername(),
+			Password: constants.GetDatabaseUserPassword(),
+			Host:     constants.GetDatabaseHost(),
+			Port:     constants.GetDatabasePort(),
+			Name:     constants.GetDatabaseName(),
+			SSLMode:  constants.GetDatabaseSSLMode(),
+		}
+	}
+
+	dbStr, err := services.BuildDbString(driver, info)
+	if err != nil {
+		log.Fatalf("Invalid database config: %v", err)
+	}
+	return dbStr
+}
+
+/**
+ * Opens the db configured by the env, waiting for it to come up if
+ * it isn't reachable yet. Exits if it can't be reached at all.
+ */
+func openDb() *gorm.DB {
+	driver := constants.GetDatabaseDriver()
+	db, err := services.OpenDb(services.DbConfig{
+		Driver:   driver,
+		DbString: buildDbString(driver),
 		Config:   &gorm.Config{},
+		Pool: services.DbPoolConfig{
+			MaxOpenConns:    constants.GetDatabaseMaxOpenConns(),
+			MaxIdleConns:    constants.GetDatabaseMaxIdleConns(),
+			ConnMaxLifetime: constants.GetDatabaseConnMaxLifetime(),
+			ConnMaxIdleTime: constants.GetDatabaseConnMaxIdleTime(),
+		},
+		ConnectAttempts: constants.GetDatabaseConnectAttempts(),
+		InitialBackoff:  time.Second,
+		MaxBackoff:      30 * time.Second,
+	})
+	if err != nil {
+		log.Fatalf("Unable to open database: %v", err)
 	}
-	userDeps := services.UserServiceDeps{
-		UserRepository: services.NewUserRepository(
+	return db
+}
+
+/**
+ * Exits if the db schema doesn't match this build's migrations.
+ * Migrations are never applied implicitly on startup; run the
+ * `migrate` command first.
+ */
+func checkSchemaVersion(db *gorm.DB) {
+	err := migrations.NewMigrator(db, migrations.All).CheckCurrent()
+	if err != nil {
+		log.Fatalf("Database schema check failed: %v (apply migrations with the `migrate up` command)", err)
+	}
+}
+
+/**
+ * Handles the `migrate` command:
+ *   migrate up         apply all pending migrations (the default)
+ *   migrate down [n]   roll back the n (default 1) most recent migrations
+ *   migrate status     list all migrations and whether they are applied
+ */
+func runMigrate(args []string) error {
+	migrator := migrations.NewMigrator(openDb(), migrations.All)
+
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			log.Printf("Applied migration %d (%s)\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Schema is already up to date")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations to roll back: %q", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := migrator.Down(steps)
+		for _, migration := range rolledBack {
+			log.Printf("Rolled back migration %d (%s)\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			log.Println("No migrations to roll back")
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.IsApplied() {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-30s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q (expected up, down or status)", command)
+	}
+	return nil
+}
+
+// the data layer the services are built on
+type repositories struct {
+	users              services.IUserRepository
+	sessions           services.ISessionRepository
+	passwordResets     services.IPasswordResetRepository
+	emailVerifications services.IEmailVerificationRepository
+	recoveryCodes      services.IRecoveryCodeRepository
+	twoFactorTickets   services.ITwoFactorTicketRepository
+	identities         services.IUserIdentityRepository
+	apiTokens          services.IAPITokenRepository
+	games              services.IGameRepository
+}
+
+/**
+ * Builds the repositories on `db`. Without a db (demo mode), they are
+ * kept in memory so the app can run without a database (all data is
+ * lost on shutdown).
+ */
+func buildRepositories(db *gorm.DB) repositories {
+	if db == nil {
+		userRepository := services.NewMemoryUserRepository()
+		gameRepository := services.NewMemoryGameRepository(
+			&services.MemoryGameRepoDeps{
+				UserRepository: userRepository,
+			},
+		)
+		identityRepository := services.NewMemoryUserIdentityRepository(
+			&services.MemoryUserIdentityRepoDeps{
+				UserRepository: userRepository,
+			},
+		)
+		return repositories{
+			users:              userRepository,
+			sessions:           services.NewMemorySessionRepository(),
+			passwordResets:     services.NewMemoryPasswordResetRepository(),
+			emailVerifications: services.NewMemoryEmailVerificationRepository(),
+			recoveryCodes:      services.NewMemoryRecoveryCodeRepository(),
+			twoFactorTickets:   services.NewMemoryTwoFactorTicketRepository(),
+			identities:         identityRepository,
+			apiTokens:          services.NewMemoryAPITokenRepository(),
+			games:              gameRepository,
+		}
+	}
+
+	baseRepoDeps := &services.BaseRepoDeps{
+		Db: db,
+	}
+	return repositories{
+		users: services.NewUserRepository(
 			&services.UserRepoDeps{
 				BaseDeps: baseRepoDeps,
 			},
 		),
-	}
-	gameDeps := services.GameServiceDeps{
-		GameRepository: services.NewGameRepository(
+		sessions: services.NewSessionRepository(
+			&services.SessionRepoDeps{
+				BaseDeps: baseRepoDeps,
+			},
+		),
+		passwordResets: services.NewPasswordResetRepository(
+			&services.PasswordResetRepoDeps{
+				BaseDeps: baseRepoDeps,
+			},
+		),
+		emailVerifications: services.NewEmailVerificationRepository(
+			&services.EmailVerificationRepoDeps{
+				BaseDeps: baseRepoDeps,
+			},
+		),
+		recoveryCodes: services.NewRecoveryCodeRepository(
+			&services.RecoveryCodeRepoDeps{
+				BaseDeps: baseRepoDeps,
+			},
+		),
+		twoFactorTickets: services.NewTwoFactorTicketRepository(
+			&services.TwoFactorTicketRepoDeps{
+				BaseDeps: baseRepoDeps,
+			},
+		),
+		identities: services.NewUserIdentityRepository(
+			&services.UserIdentityRepoDeps{
+				BaseDeps: baseRepoDeps,
+			},
+		),
+		apiTokens: services.NewAPITokenRepository(
+			&services.APITokenRepoDeps{
+				BaseDeps: baseRepoDeps,
+			},
+		),
+		games: services.NewGameRepository(
 			&services.GameRepoDeps{
 				BaseDeps: baseRepoDeps,
 			},
 		),
 	}
-	p := provider.Provider{
-		R:             gin.Default(),
-		UserService:   services.NewUserService(userDeps),
-		GameService:   services.NewGameService(gameDeps),
-		Subscriptions: make(subscriptions.GameSubscriptions),
+}
+
+// limiter allowing `perMinute` auth requests a minute, or nil (no limit) when it's 0
+func newAuthLimiter(perMinute int) ratelimit.Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return ratelimit.NewMemoryLimiter(ratelimit.MemoryLimiterConfig{
+		Limit: perMinute,
+		Per:   time.Minute,
+	})
+}
+
+// lockout of usernames after repeated failed signins, or nil when the threshold is 0
+func newSigninLockout() ratelimit.Lockout {
+	threshold := constants.GetSigninLockoutThreshold()
+	if threshold <= 0 {
+		return nil
+	}
+	return ratelimit.NewMemoryLockout(ratelimit.MemoryLockoutConfig{
+		Threshold:   threshold,
+		BaseLockout: constants.GetSigninLockoutBase(),
+		MaxLockout:  constants.GetSigninLockoutMax(),
+		// a failure is remembered at least as long as the lockout it can lead to
+		FailureWindow: constants.GetSigninLockoutMax(),
+	})
+}
+
+// sends the app's emails, per MAILER
+func buildMailer() mailer.Mailer {
+	switch constants.GetMailer() {
+	case "log":
+		return mailer.NewLogMailer()
+	case "file":
+		m, err := mailer.NewFileMailer(constants.GetMailerDir())
+		if err != nil {
+			log.Fatalf("Unable to set up file mailer: %v", err)
+		}
+		return m
+	case "smtp":
+		m, err := mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Addr:     constants.GetSMTPAddr(),
+			Username: constants.GetSMTPUsername(),
+			Password: constants.GetSMTPPassword(),
+			From:     constants.GetMailFrom(),
+		})
+		if err != nil {
+			log.Fatalf("Unable to set up SMTP mailer: %v", err)
+		}
+		return m
 	}
+	log.Fatalf("Unknown MAILER %q (expected log, file or smtp)", constants.GetMailer())
+	return nil
+}
 
-	// TODO: do this separately...?
-	// migrate db
-	if err := p.MigrateAll(); err != nil {
-		log.Printf("Failed to auto migrate db: %v\n", err)
-		panic("Migration failure!")
+// the OpenID Connect provider users can sign in with, or nil if OIDC_ISSUER isn't set
+func buildIdentityProvider() identity.Provider {
+	if constants.GetOIDCIssuer() == "" {
+		return nil
+	}
+	p, err := identity.NewOIDCProvider(identity.OIDCConfig{
+		Issuer:       constants.GetOIDCIssuer(),
+		ClientID:     constants.GetOIDCClientID(),
+		ClientSecret: constants.GetOIDCClientSecret(),
+		RedirectURL:  constants.GetOIDCRedirectURL(),
+		Scopes:       constants.GetOIDCScopes(),
+	})
+	if err != nil {
+		log.Fatalf("Invalid OIDC config: %v", err)
 	}
 	return p
 }
 
+func buildProvider(db *gorm.DB) provider.Provider {
+	repos := buildRepositories(db)
+	userDeps := services.UserServiceDeps{
+		UserRepository:              repos.users,
+		SigninLockout:               newSigninLockout(),
+		PasswordResetRepository:     repos.passwordResets,
+		EmailVerificationRepository: repos.emailVerifications,
+		Mailer:                      buildMailer(),
+		PublicURL:                   constants.GetPublicURL(),
+	}
+	sessionDeps := services.SessionServiceDeps{
+		SessionRepository: repos.sessions,
+	}
+	twoFactorDeps := services.TwoFactorServiceDeps{
+		UserRepository:         repos.users,
+		RecoveryCodeRepository: repos.recoveryCodes,
+		TicketRepository:       repos.twoFactorTickets,
+		Issuer:                 "net-go",
+	}
+	identityDeps := services.IdentityServiceDeps{
+		UserRepository:     repos.users,
+		IdentityRepository: repos.identities,
+	}
+	apiTokenDeps := services.APITokenServiceDeps{
+		TokenRepository: repos.apiTokens,
+	}
+	gameDeps := services.GameServiceDeps{
+		GameRepository: repos.games,
+	}
+	return provider.Provider{
+		R:                   gin.Default(),
+		UserService:         services.NewUserService(userDeps),
+		SessionService:      services.NewSessionService(sessionDeps),
+		TwoFactorService:    services.NewTwoFactorService(twoFactorDeps),
+		IdentityService:     services.NewIdentityService(identityDeps),
+		IdentityProvider:    buildIdentityProvider(),
+		APITokenService:     services.NewAPITokenService(apiTokenDeps),
+		GameService:         services.NewGameService(gameDeps),
+		Subscriptions:       subscriptions.NewHub(),
+		AuthIPLimiter:       newAuthLimiter(constants.GetAuthRateLimitPerIP()),
+		AuthUsernameLimiter: newAuthLimiter(constants.GetAuthRateLimitPerUsername()),
+		TrustedProxies:      constants.GetTrustedProxies(),
+	}
+}
+
+/**
+ * Builds the /readyz checks: the db is reachable, its schema matches
+ * this build and the realtime hub is accepting long-poll subscribers.
+ * Without a db (demo mode), only the hub is checked.
+ */
+func buildReadiness(db *gorm.DB, dbHealth *services.DbHealthMonitor, hub *subscriptions.Hub) *health.Readiness {
+	checks := []health.Check{
+		{
+			Name: "realtime",
+			Run: func(ctx context.Context) error {
+				if !hub.Running() {
+					return errors.New("realtime hub is closed")
+				}
+				return nil
+			},
+		},
+	}
+	if db != nil {
+		migrator := migrations.NewMigrator(db, migrations.All)
+		checks = append(checks,
+			health.Check{Name: "database", Run: dbHealth.Check},
+			health.Check{
+				Name: "migrations",
+				Run: func(ctx context.Context) error {
+					return migrator.CheckCurrent()
+				},
+			},
+		)
+	}
+	return health.NewReadiness(checks...)
+}
+
+/**
+ * Exits unless the auth cookies can be trusted: signed with real keys
+ * (the built in dev key is only allowed in dev and demo mode) and only
+ * sent cross site over https.
+ */
+func checkAuthCookieConfig(demo bool) {
+	keys := constants.GetAuthCookieKeys()
+	if len(keys) == 0 {
+		if !constants.GetDevMode() && !demo {
+			log.Fatal("AUTH_COOKIE_KEYS must be set outside of dev mode")
+		}
+		log.Println("AUTH_COOKIE_KEYS is not set; signing auth cookies with an insecure dev key")
+	}
+	for i, key := range keys {
+		if len(key) < 32 {
+			log.Fatalf("AUTH_COOKIE_KEYS key %d is too short; keys must be at least 32 characters", i+1)
+		}
+	}
+	if constants.GetAuthCookieSameSite() == http.SameSiteNoneMode && !constants.GetAuthCookieSecure() {
+		log.Fatal("AUTH_COOKIE_SAMESITE=none requires AUTH_COOKIE_SECURE=true")
+	}
+}
+
+/**
+ * Exits unless emails are really sent: outside of dev and demo mode,
+ * mail holding password reset and email verification tokens mustn't
+ * end up in logs or local files.
+ */
+func checkMailerConfig(demo bool) {
+	if constants.GetDevMode() || demo {
+		return
+	}
+	switch constants.GetMailer() {
+	case "log", "file":
+		log.Fatal("MAILER must be set to smtp outside of dev mode")
+	}
+}
+
 func main() {
+	demo := flag.Bool("demo", false, "run with in-memory storage instead of a database")
+	flag.Parse()
+
 	constants.LoadEnv()
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(flag.Args()[1:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	port := ":" + constants.GetPort()
 	log.Println("Starting server...")
-
-	p := buildProvider()
+	if *demo {
+		log.Println("Running in demo mode; data will not be persisted")
+	}
+	checkAuthCookieConfig(*demo)
+	checkMailerConfig(*demo)
 
 	// setup logging
 	shutdownLogger := buildLogger()
 	defer shutdownLogger()
 
+	// lets the log level/sampling be changed without a restart, e.g.
+	// curl -X PUT -H 'Content-Type: application/json' $LOG_ADMIN_ADDR/log/level -d '{"level":"debug"}'
+	if addr := constants.GetLogAdminAddr(); addr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/log/", http.StripPrefix("/log", applogger.AdminHandler()))
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				log.Printf("Log admin listener stopped: %v\n", err)
+			}
+		}()
+	}
+
+	// prometheus scrapes are kept off the public listener, since the
+	// metrics reveal traffic, signin failures and db timings
+	if addr := constants.GetMetricsAddr(); addr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.Handler())
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				log.Printf("Metrics listener stopped: %v\n", err)
+			}
+		}()
+	}
+
+	var db *gorm.DB
+	var dbHealth *services.DbHealthMonitor
+	if !*demo {
+		db = openDb()
+		checkSchemaVersion(db)
+
+		// report db outages (e.g. a mariadb restart) while running
+		dbHealth = services.NewDbHealthMonitor(&services.DbHealthMonitorDeps{
+			Db:       db,
+			Interval: 15 * time.Second,
+			Timeout:  2 * time.Second,
+		})
+		dbHealthCtx, stopDbHealth := context.WithCancel(context.Background())
+		defer stopDbHealth()
+		go dbHealth.Run(dbHealthCtx)
+	}
+
+	p := buildProvider(db)
+	p.Readiness = buildReadiness(db, dbHealth, p.Subscriptions)
+	instrumentation.RegisterLongPollSubscribers(p.Subscriptions.ListenerCount)
+
 	// set routing and server config
 	router.SetRouter(p)
 	srv := &http.Server{
@@ -135,14 +581,21 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
 	// this blocks until `quit` channel receives a kill signal
-	<-quit
+	sig := <-quit
 	log.Println("Shutting down server...")
 
-	// close subscriptions to allow long-poll requests to finish
-	for _, subscription_chan := range p.Subscriptions {
-		close(subscription_chan)
+	// fail readiness first, and on SIGTERM (i.e. from the orchestrator)
+	// keep serving for a bit so load balancers can drain this instance
+	p.Readiness.StartDraining()
+	if sig == syscall.SIGTERM {
+		drainDelay := constants.GetShutdownDrainDelay()
+		log.Println("Draining for", drainDelay)
+		time.Sleep(drainDelay)
 	}
 
+	// close subscriptions to allow long-poll requests to finish
+	p.Subscriptions.Close()
+
 	// The context is used to inform the server it has 5 seconds to finish
 	// the request it is currently handling
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -151,5 +604,11 @@ func main() {
 		log.Fatal("Server forced to shutdown: ", err)
 	}
 
+	if db != nil {
+		if sqlDb, err := db.DB(); err == nil {
+			sqlDb.Close()
+		}
+	}
+
 	log.Println("Server exiting")
 }